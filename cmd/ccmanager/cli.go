@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/valentindosimont/ccmanager/internal/api"
+	"github.com/valentindosimont/ccmanager/internal/app"
+	"github.com/valentindosimont/ccmanager/internal/control"
+)
+
+// errUsage marks errors caused by bad arguments (exit status 2)
+var errUsage = errors.New("usage")
+
+// backend performs session actions, either through the control API of a
+// running daemon/dashboard or directly against tmux
+type backend interface {
+	Sessions() ([]api.Session, error)
+	SendPrompt(name, text string) error
+	CreateSession(req api.CreateRequest) (api.CreateResponse, error)
+	KillSession(name string) error
+	Focus(name string) error
+}
+
+// localBackend drives tmux in-process when no server is listening
+type localBackend struct {
+	ctrl *control.Controller
+}
+
+func (b localBackend) Sessions() ([]api.Session, error) {
+	snapshot := b.ctrl.Sessions()
+	sessions := make([]api.Session, 0, len(snapshot))
+	for _, s := range snapshot {
+		sessions = append(sessions, api.NewSession(s))
+	}
+	return sessions, nil
+}
+
+func (b localBackend) SendPrompt(name, text string) error {
+	return b.ctrl.SendPrompt(name, text)
+}
+
+func (b localBackend) CreateSession(req api.CreateRequest) (api.CreateResponse, error) {
+	path, err := b.ctrl.CreateSession(control.CreateOptions{
+		Name:      req.Name,
+		Path:      req.Path,
+		Workspace: req.Workspace,
+	})
+	resp := api.CreateResponse{Name: req.Name, Path: path}
+	var warning *control.CreateWarning
+	if errors.As(err, &warning) {
+		resp.Warning, err = warning.Error(), nil
+	}
+	return resp, err
+}
+
+func (b localBackend) KillSession(name string) error {
+	return b.ctrl.KillSession(name)
+}
+
+func (b localBackend) Focus(name string) error {
+	return b.ctrl.Focus(name)
+}
+
+// newBackend prefers the control socket so actions are seen (and scored) by
+// the process owning the monitor
+func newBackend(application *app.App, socketPath string) backend {
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		_ = conn.Close()
+		return api.NewClient(socketPath)
+	}
+	application.Refresh()
+	return localBackend{ctrl: application.Controller()}
+}
+
+// runCommand runs a non-interactive subcommand
+func runCommand(application *app.App, cfg app.Config, name string, args []string) error {
+	b := func() backend { return newBackend(application, cfg.SocketPath) }
+
+	switch name {
+	case "ls":
+		return cmdList(b, args, os.Stdout)
+	case "send":
+		return cmdSend(b, args, os.Stdin)
+	case "new":
+		return cmdNew(b, args, os.Stdout)
+	case "kill":
+		return cmdKill(b, args)
+	case "focus":
+		return cmdFocus(b, application, args)
+	case "usage":
+		return cmdUsage(application.Usage(), args, os.Stdout)
+	}
+	return fmt.Errorf("%w: unknown command %s", errUsage, name)
+}
+
+func cmdList(b func() backend, args []string, out io.Writer) error {
+	fs := newFlagSet("ls")
+	asJSON := fs.Bool("json", false, "print sessions as JSON")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	sessions, err := b().Sessions()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sessions)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tAGENT\tSTATE\tDIR\tTOKENS\tCOST")
+	for _, s := range sessions {
+		cost := "-"
+		if s.Usage != nil {
+			cost = fmt.Sprintf("$%.2f", s.Usage.EstimatedCost)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", s.Name, s.Agent, s.State, s.WorkingDir, s.Tokens, cost)
+	}
+	return tw.Flush()
+}
+
+func cmdSend(b func() backend, args []string, in io.Reader) error {
+	fs := newFlagSet("send")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	session := rest[0]
+	var text string
+	if len(rest) > 1 {
+		text = strings.Join(rest[1:], " ")
+	} else {
+		// No prompt argument: read it from stdin
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return fmt.Errorf("read prompt: %w", err)
+		}
+		text = strings.TrimRight(string(data), "\n")
+	}
+	if text == "" {
+		return fmt.Errorf("%w: empty prompt", errUsage)
+	}
+	return b().SendPrompt(session, text)
+}
+
+func cmdNew(b func() backend, args []string, out io.Writer) error {
+	fs := newFlagSet("new")
+	worktree := fs.String("worktree", "", "create an isolated worktree/workspace with this name")
+	name := fs.String("name", "", "session name (default: <dir>-N, or the worktree name)")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 1 {
+		return fmt.Errorf("%w: new takes a single path", errUsage)
+	}
+
+	path, err := filepath.Abs(rest[0])
+	if err != nil {
+		return fmt.Errorf("resolve path: %w", err)
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", path)
+	}
+
+	be := b()
+	req := api.CreateRequest{Name: *name, Path: path, Workspace: *worktree != ""}
+	if req.Name == "" {
+		req.Name = *worktree
+	}
+	if req.Name == "" {
+		sessions, err := be.Sessions()
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(sessions))
+		for _, s := range sessions {
+			names = append(names, s.Name)
+		}
+		req.Name = control.NextSessionName(path, names)
+	}
+
+	resp, err := be.CreateSession(req)
+	if err != nil {
+		return err
+	}
+	if resp.Warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", resp.Warning)
+	}
+	fmt.Fprintf(out, "%s\t%s\n", resp.Name, resp.Path)
+	return nil
+}
+
+func cmdKill(b func() backend, args []string) error {
+	fs := newFlagSet("kill")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 1 {
+		return fmt.Errorf("%w: kill takes a single session", errUsage)
+	}
+	return b().KillSession(rest[0])
+}
+
+func cmdFocus(b func() backend, application *app.App, args []string) error {
+	fs := newFlagSet("focus")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 1 {
+		return fmt.Errorf("%w: focus takes a single session or group", errUsage)
+	}
+
+	target := rest[0]
+	// A single digit selects a control group, like the dashboard hotkeys
+	if groupNum, err := strconv.Atoi(target); err == nil && len(target) == 1 {
+		session := application.ControlGroup(groupNum)
+		if session == "" {
+			return fmt.Errorf("control group %d is empty", groupNum)
+		}
+		target = session
+	}
+	return b().Focus(target)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseArgs parses flags anywhere in args (flag stops at the first
+// positional argument) and requires at least minArgs positionals
+func parseArgs(fs *flag.FlagSet, args []string, minArgs int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		remaining := fs.Args()
+		// Parse swallows a "--" terminator: everything after it is positional
+		if consumed := len(args) - len(remaining); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, remaining...)
+			break
+		}
+		if len(remaining) == 0 {
+			break
+		}
+		positional = append(positional, remaining[0])
+		args = remaining[1:]
+	}
+
+	if len(positional) < minArgs {
+		return nil, fmt.Errorf("%w: %s needs %d argument(s)", errUsage, fs.Name(), minArgs)
+	}
+	return positional, nil
+}
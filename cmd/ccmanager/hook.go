@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/api"
+	"github.com/valentindosimont/ccmanager/internal/app"
+	"github.com/valentindosimont/ccmanager/internal/hooks"
+	"github.com/valentindosimont/ccmanager/internal/tmux"
+)
+
+// hookTimeout bounds how long a hook can delay Claude Code
+const hookTimeout = 2 * time.Second
+
+// runHook handles `ccmanager hook install|uninstall|<Event>`
+func runHook(cfg app.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: hook needs an event or install/uninstall", errUsage)
+	}
+
+	switch args[0] {
+	case "install", "uninstall":
+		return cmdHookInstall(args[0], args[1:])
+	}
+
+	// Exit 0 even here: Claude Code treats exit status 2 as "block the tool"
+	if !hooks.IsEvent(args[0]) {
+		fmt.Fprintf(os.Stderr, "ccmanager: ignoring unknown hook event %s\n", args[0])
+		return nil
+	}
+	forwardHook(cfg.SocketPath, args[0], os.Stdin)
+	return nil
+}
+
+func cmdHookInstall(action string, args []string) error {
+	fs := newFlagSet("hook " + action)
+	settings := fs.String("settings", hooks.DefaultSettingsPath(), "Claude Code settings file")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate ccmanager binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	if action == "uninstall" {
+		if err := hooks.Uninstall(*settings, executable); err != nil {
+			return err
+		}
+		fmt.Printf("Removed ccmanager hooks from %s\n", *settings)
+		return nil
+	}
+
+	if err := hooks.Install(*settings, executable); err != nil {
+		return err
+	}
+	fmt.Printf("Installed ccmanager hooks in %s\n", *settings)
+	return nil
+}
+
+// forwardHook sends the hook payload to the running daemon or dashboard.
+// It never fails: a hook error would surface in Claude Code, and with no
+// monitor running there is nobody to tell.
+func forwardHook(socketPath, event string, stdin io.Reader) {
+	h := hooks.Hook{Event: event}
+	if data, err := io.ReadAll(io.LimitReader(stdin, 1<<20)); err == nil {
+		_ = json.Unmarshal(data, &h.Payload)
+	}
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		h.Session, _ = tmux.NewClient().PaneSession(pane)
+	}
+
+	client := api.NewClient(socketPath)
+	client.SetTimeout(hookTimeout)
+	if err := client.SendHook(h); err != nil && os.Getenv("CCMANAGER_DEBUG") == "1" {
+		fmt.Fprintf(os.Stderr, "ccmanager hook %s: %v\n", event, err)
+	}
+}
@@ -1,14 +1,47 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/valentindosimont/ccmanager/internal/app"
 )
 
+const usageText = `Usage: ccmanager [command]
+
+Commands:
+  (none)                            Open the dashboard (attaches to a running daemon if any)
+  daemon                            Track sessions, score and cost in the background without the TUI
+  ls [--json]                       List sessions with state, directory, tokens and cost
+  send <session> [prompt]           Send a prompt (read from stdin if omitted)
+  new <path> [--worktree name] [--name session]
+                                    Start a Claude session, optionally in a new worktree
+  kill <session>                    Kill a session and clean up its workspace
+  focus <session|group>             Switch tmux to a session or control group (0-9)
+  usage [--since date] [--until date] [--by day|project|model|session] [--format table|json|csv]
+                                    Report token usage and cost from all transcripts
+  hook install|uninstall [--settings path]
+                                    Add or remove ccmanager hooks in ~/.claude/settings.json
+  hook <Event>                      Called by Claude Code hooks; forwards the event to the monitor
+`
+
 func main() {
-	cfg, fileCfg := app.LoadConfig()
+	cfg, fileCfg, cfgErr := app.LoadConfig()
+
+	// Hooks run on every tool call, so they skip opening the store. They
+	// only need the socket path, so a broken config doesn't stop them.
+	if len(os.Args) > 1 && os.Args[1] == "hook" {
+		if err := runHook(cfg, os.Args[2:]); err != nil {
+			exitError(err)
+		}
+		return
+	}
+
+	if cfgErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", cfgErr)
+		os.Exit(1)
+	}
 
 	application, err := app.New(cfg, fileCfg)
 	if err != nil {
@@ -17,8 +50,34 @@ func main() {
 	}
 	defer func() { _ = application.Close() }()
 
-	if err := application.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	run := application.Run
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "daemon":
+			run = application.RunDaemon
+		case "ls", "send", "new", "kill", "focus", "usage":
+			cmd, args := os.Args[1], os.Args[2:]
+			run = func() error { return runCommand(application, cfg, cmd, args) }
+		case "help", "-h", "--help":
+			fmt.Print(usageText)
+			return
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n%s", os.Args[1], usageText)
+			os.Exit(2)
+		}
+	}
+
+	if err := run(); err != nil {
+		_ = application.Close()
+		exitError(err)
+	}
+}
+
+func exitError(err error) {
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n%s", err, usageText)
+		os.Exit(2)
 	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/store"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+// usageRow is one line of a usage report
+type usageRow struct {
+	Key                      string  `json:"key"`
+	Messages                 int     `json:"messages"`
+	InputTokens              int64   `json:"input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	Cost                     float64 `json:"cost"`
+	UncachedCost             float64 `json:"uncached_cost"`
+	CacheSavings             float64 `json:"cache_savings"`
+	CacheHitRatio            float64 `json:"cache_hit_ratio"`
+	CacheUnreadTokens        int64   `json:"cache_unread_tokens"`
+}
+
+func (r *usageRow) add(o usageRow) {
+	r.Messages += o.Messages
+	r.InputTokens += o.InputTokens
+	r.OutputTokens += o.OutputTokens
+	r.CacheCreationInputTokens += o.CacheCreationInputTokens
+	r.CacheReadInputTokens += o.CacheReadInputTokens
+	r.Cost += o.Cost
+	r.UncachedCost += o.UncachedCost
+	r.CacheSavings += o.CacheSavings
+	r.CacheUnreadTokens += o.CacheUnreadTokens
+	r.CacheHitRatio = store.UsageTotal{
+		InputTokens:              r.InputTokens,
+		CacheCreationInputTokens: r.CacheCreationInputTokens,
+		CacheReadInputTokens:     r.CacheReadInputTokens,
+	}.CacheHitRatio()
+}
+
+// usageReport is the JSON form of `ccmanager usage`
+type usageReport struct {
+	By    usage.Grouping `json:"by"`
+	Since *time.Time     `json:"since,omitempty"`
+	Until *time.Time     `json:"until,omitempty"`
+	Rows  []usageRow     `json:"rows"`
+	Total usageRow       `json:"total"`
+}
+
+func cmdUsage(ing *usage.Ingester, args []string, out io.Writer) error {
+	fs := newFlagSet("usage")
+	since := fs.String("since", "", "first day to include, as YYYY-MM-DD, an RFC 3339 time or a duration ago (7d, 12h)")
+	until := fs.String("until", "", "last day to include as YYYY-MM-DD, or an RFC 3339 time or duration ago to stop at")
+	by := fs.String("by", "day", "group by day, project, model or session")
+	format := fs.String("format", "table", "output as table, json or csv")
+	rest, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: usage takes no arguments", errUsage)
+	}
+
+	report := usageReport{Rows: []usageRow{}, Total: usageRow{Key: "total"}}
+	if report.By, err = usage.ParseGrouping(*by); err != nil {
+		return fmt.Errorf("%w: --by: %v", errUsage, err)
+	}
+	var f store.UsageFilter
+	now := time.Now()
+	if *since != "" {
+		if f.Since, err = parseReportTime(*since, false, now); err != nil {
+			return fmt.Errorf("%w: --since: %v", errUsage, err)
+		}
+		report.Since = &f.Since
+	}
+	if *until != "" {
+		if f.Until, err = parseReportTime(*until, true, now); err != nil {
+			return fmt.Errorf("%w: --until: %v", errUsage, err)
+		}
+		report.Until = &f.Until
+	}
+	var write func(io.Writer, usageReport) error
+	switch *format {
+	case "table":
+		write = writeUsageTable
+	case "json":
+		write = writeUsageJSON
+	case "csv":
+		write = writeUsageCSV
+	default:
+		return fmt.Errorf("%w: --format must be table, json or csv", errUsage)
+	}
+
+	totals, err := ing.Report(report.By, f)
+	if err != nil {
+		return err
+	}
+	for _, t := range totals {
+		row := usageRow{
+			Key:                      t.Key,
+			Messages:                 t.Messages,
+			InputTokens:              t.InputTokens,
+			OutputTokens:             t.OutputTokens,
+			CacheCreationInputTokens: t.CacheCreationInputTokens,
+			CacheReadInputTokens:     t.CacheReadInputTokens,
+			Cost:                     t.Cost,
+			UncachedCost:             t.UncachedCost,
+			CacheSavings:             t.CacheSavings(),
+			CacheHitRatio:            t.CacheHitRatio(),
+			CacheUnreadTokens:        t.CacheUnreadTokens,
+		}
+		report.Rows = append(report.Rows, row)
+		report.Total.add(row)
+	}
+	return write(out, report)
+}
+
+// parseReportTime reads a date, a time, or a duration before now such as 7d
+// or 12h. A date for --until means the end of that day, so the day is
+// included.
+func parseReportTime(s string, endOfDay bool, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("want YYYY-MM-DD, an RFC 3339 time or a duration such as 7d, got %q", s)
+}
+
+func writeUsageTable(out io.Writer, report usageReport) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tMESSAGES\tINPUT\tOUTPUT\tCACHE WRITE\tCACHE READ\tCOST\tCACHE HIT\tSAVED\tUNREAD WRITES\n",
+		strings.ToUpper(string(report.By)))
+	for _, r := range append(report.Rows, report.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%.0f%%\t%s\t%d\n", r.Key, r.Messages, r.InputTokens, r.OutputTokens,
+			r.CacheCreationInputTokens, r.CacheReadInputTokens, dollars(r.Cost),
+			r.CacheHitRatio*100, dollars(r.CacheSavings), r.CacheUnreadTokens)
+	}
+	return tw.Flush()
+}
+
+// dollars formats an amount of money, putting the sign before the dollar
+// sign
+func dollars(v float64) string {
+	if v < 0 {
+		return fmt.Sprintf("-$%.2f", -v)
+	}
+	return fmt.Sprintf("$%.2f", v)
+}
+
+func writeUsageJSON(out io.Writer, report usageReport) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// writeUsageCSV writes one row per group, without the total, so the output
+// loads straight into a spreadsheet or dashboard
+func writeUsageCSV(out io.Writer, report usageReport) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{string(report.By), "messages", "input_tokens", "output_tokens",
+		"cache_creation_input_tokens", "cache_read_input_tokens", "cost", "uncached_cost",
+		"cache_savings", "cache_hit_ratio", "cache_unread_tokens"})
+	for _, r := range report.Rows {
+		_ = w.Write([]string{
+			r.Key,
+			strconv.Itoa(r.Messages),
+			strconv.FormatInt(r.InputTokens, 10),
+			strconv.FormatInt(r.OutputTokens, 10),
+			strconv.FormatInt(r.CacheCreationInputTokens, 10),
+			strconv.FormatInt(r.CacheReadInputTokens, 10),
+			strconv.FormatFloat(r.Cost, 'f', 4, 64),
+			strconv.FormatFloat(r.UncachedCost, 'f', 4, 64),
+			strconv.FormatFloat(r.CacheSavings, 'f', 4, 64),
+			strconv.FormatFloat(r.CacheHitRatio, 'f', 4, 64),
+			strconv.FormatInt(r.CacheUnreadTokens, 10),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+func TestParseReportTime(t *testing.T) {
+	now := time.Date(2026, 6, 15, 9, 30, 0, 0, time.Local)
+	june10 := time.Date(2026, 6, 10, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		in       string
+		endOfDay bool
+		want     time.Time
+		wantErr  bool
+	}{
+		{name: "date", in: "2026-06-10", want: june10},
+		{name: "date until end of day", in: "2026-06-10", endOfDay: true, want: june10.AddDate(0, 0, 1)},
+		{name: "rfc3339", in: "2026-06-10T08:00:00Z", want: time.Date(2026, 6, 10, 8, 0, 0, 0, time.UTC)},
+		{name: "rfc3339 is exact for until", in: "2026-06-10T08:00:00Z", endOfDay: true, want: time.Date(2026, 6, 10, 8, 0, 0, 0, time.UTC)},
+		{name: "days ago", in: "7d", want: now.AddDate(0, 0, -7)},
+		{name: "today", in: "0d", want: now},
+		{name: "hours ago", in: "12h", want: now.Add(-12 * time.Hour)},
+		{name: "duration ago is exact for until", in: "90m", endOfDay: true, want: now.Add(-90 * time.Minute)},
+		{name: "future", in: "-3d", wantErr: true},
+		{name: "negative duration", in: "-2h", wantErr: true},
+		{name: "unknown unit", in: "2w", wantErr: true},
+		{name: "bad date", in: "2026-13-01", wantErr: true},
+		{name: "empty", in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReportTime(tt.in, tt.endOfDay, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReportTime(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseReportTime(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func testReport() usageReport {
+	report := usageReport{By: usage.ByProject, Total: usageRow{Key: "total"}}
+	for _, row := range []usageRow{
+		{Key: "/src/api", Messages: 3, InputTokens: 1000, OutputTokens: 200, CacheReadInputTokens: 3000,
+			Cost: 1.5, UncachedCost: 2.25, CacheSavings: 0.75},
+		{Key: "/src/web, old", Messages: 1, InputTokens: 500, Cost: 0.125, UncachedCost: 0.125, CacheSavings: -0.05},
+	} {
+		report.Rows = append(report.Rows, row)
+		report.Total.add(row)
+	}
+	return report
+}
+
+func TestUsageRowAdd(t *testing.T) {
+	total := testReport().Total
+	if total.Messages != 4 || total.InputTokens != 1500 || total.Cost != 1.625 {
+		t.Errorf("total = %+v", total)
+	}
+	if total.CacheHitRatio != 3000.0/4500 {
+		t.Errorf("CacheHitRatio = %v, want %v", total.CacheHitRatio, 3000.0/4500)
+	}
+}
+
+func TestWriteUsageCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeUsageCSV(&out, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	want := "project,messages,input_tokens,output_tokens,cache_creation_input_tokens,cache_read_input_tokens,cost,uncached_cost,cache_savings,cache_hit_ratio,cache_unread_tokens\n" +
+		"/src/api,3,1000,200,0,3000,1.5000,2.2500,0.7500,0.0000,0\n" +
+		"\"/src/web, old\",1,500,0,0,0,0.1250,0.1250,-0.0500,0.0000,0\n"
+	if out.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteUsageTable(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeUsageTable(&out, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("table has %d lines, want header, 2 rows and total:\n%s", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[0], "PROJECT ") {
+		t.Errorf("header = %q", lines[0])
+	}
+	if !strings.Contains(lines[2], "-$0.05") {
+		t.Errorf("negative savings not shown as -$0.05: %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], "total ") || !strings.Contains(lines[3], "$1.62") {
+		t.Errorf("total = %q", lines[3])
+	}
+}
+
+func TestCmdUsageArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "positional", args: []string{"extra"}},
+		{name: "grouping", args: []string{"--by", "week"}},
+		{name: "since", args: []string{"--since", "yesterday"}},
+		{name: "until", args: []string{"--until", "2026-02-30"}},
+		{name: "format", args: []string{"--format", "xml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cmdUsage(nil, tt.args, &bytes.Buffer{})
+			if !errors.Is(err, errUsage) {
+				t.Errorf("cmdUsage(%v) error = %v, want a usage error", tt.args, err)
+			}
+		})
+	}
+}
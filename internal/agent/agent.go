@@ -0,0 +1,138 @@
+// Package agent recognises the coding agents ccmanager can monitor and
+// classifies their panes.
+package agent
+
+import (
+	"fmt"
+
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+// Kind identifies a coding agent
+type Kind string
+
+const (
+	Claude Kind = "claude"
+	Aider  Kind = "aider"
+	Codex  Kind = "codex"
+	Gemini Kind = "gemini"
+	Shell  Kind = "shell"
+)
+
+// Badge returns a two-letter label for the session list
+func (k Kind) Badge() string {
+	switch k {
+	case Claude:
+		return "CC"
+	case Aider:
+		return "AI"
+	case Codex:
+		return "CX"
+	case Gemini:
+		return "GM"
+	case Shell:
+		return "SH"
+	default:
+		return "??"
+	}
+}
+
+// AgentDetector recognises one agent's panes and reads its state from the screen
+type AgentDetector interface {
+	Kind() Kind
+	// Matches reports whether a pane's content belongs to this agent
+	Matches(content string) bool
+	// ParseInfo classifies the pane and extracts what the screen shows
+	ParseInfo(content string) claude.SessionInfo
+}
+
+// UsageParser is implemented by agents that print their token usage on
+// screen. Claude's usage comes from its transcripts instead.
+type UsageParser interface {
+	// ParseUsage returns nil when no usage is visible
+	ParseUsage(content string) *usage.SessionUsage
+}
+
+// DefaultKinds are the agents monitored when none are configured. Plain
+// shells are left out, otherwise every tmux session would be listed.
+var DefaultKinds = []Kind{Claude, Aider, Codex, Gemini}
+
+// Registry picks the detector for a pane. Detectors are tried in order, with
+// the shell fallback always last.
+type Registry struct {
+	detectors []AgentDetector
+}
+
+// NewRegistry creates a registry trying detectors in the given order
+func NewRegistry(detectors ...AgentDetector) *Registry {
+	return &Registry{detectors: detectors}
+}
+
+// Default returns a registry of DefaultKinds using the given Claude detector
+func Default(claudeDetector *claude.Detector) *Registry {
+	r, _ := FromKinds(kindNames(DefaultKinds), claudeDetector)
+	return r
+}
+
+// FromKinds builds a registry of built-in detectors by name
+func FromKinds(kinds []string, claudeDetector *claude.Detector) (*Registry, error) {
+	var detectors []AgentDetector
+	var shell AgentDetector
+	seen := make(map[Kind]bool)
+
+	for _, name := range kinds {
+		kind := Kind(name)
+		if seen[kind] {
+			continue
+		}
+		seen[kind] = true
+
+		switch kind {
+		case Claude:
+			detectors = append(detectors, NewClaude(claudeDetector))
+		case Aider:
+			detectors = append(detectors, NewAider())
+		case Codex:
+			detectors = append(detectors, NewCodex())
+		case Gemini:
+			detectors = append(detectors, NewGemini())
+		case Shell:
+			shell = NewShell()
+		default:
+			return nil, fmt.Errorf("unknown agent %q", name)
+		}
+	}
+	if shell != nil {
+		detectors = append(detectors, shell)
+	}
+	return NewRegistry(detectors...), nil
+}
+
+// Detect returns the first detector matching content, or nil
+func (r *Registry) Detect(content string) AgentDetector {
+	for _, d := range r.detectors {
+		if d.Matches(content) {
+			return d
+		}
+	}
+	return nil
+}
+
+// Get returns the detector for kind, or nil if it isn't registered
+func (r *Registry) Get(kind Kind) AgentDetector {
+	for _, d := range r.detectors {
+		if d.Kind() == kind {
+			return d
+		}
+	}
+	return nil
+}
+
+func kindNames(kinds []Kind) []string {
+	names := make([]string, len(kinds))
+	for i, k := range kinds {
+		names[i] = string(k)
+	}
+	return names
+}
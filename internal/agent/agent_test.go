@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+const (
+	aiderIdle = "Aider v0.86.1\nMain model: anthropic/claude-sonnet-4 with diff edit format\n" +
+		"Repo-map: using 4096 tokens, auto refresh\n\nTokens: 4.2k sent, 1.1k cache hit, 312 received. Cost: $0.02 message, $0.15 session.\n" +
+		"────────────────\n> \n"
+	aiderUrgent  = "Tokens: 4.2k sent, 312 received.\nAdd main.go to the chat? (Y)es/(N)o/(D)on't ask again [Yes]: \n"
+	aiderWaiting = "Main model: gpt-4o with diff edit format\n\nWaiting for gpt-4o\n"
+
+	codexIdle = "╭──────────────────────────────╮\n│ >_ OpenAI Codex (v0.46.0)    │\n│ model:     gpt-5-codex       │\n╰──────────────────────────────╯\n\n" +
+		"› Ask Codex to do anything\n\n  100% context left · ? for shortcuts\n"
+	codexWorking = "• Working (12s • esc to interrupt)\n\n› \n  98% context left · ? for shortcuts\n"
+	codexUrgent  = "Would you like to run the following command?\n\n  $ rm -rf build\n\n› 1. Yes, proceed\n  2. No\n\n  97% context left · ? for shortcuts\n"
+	codexExit    = "Token usage: total=12,345 input=10,000 (+ 8,000 cached) output=2,345 (reasoning 1,000)\nTo continue this session, run codex resume\n$ "
+
+	geminiIdle = "╭──────────────────────────────────────╮\n│ >   Type your message or @path/to/file │\n╰──────────────────────────────────────╯\n" +
+		"~/src/api (main*)    no sandbox    gemini-2.5-pro (99% context left)\n"
+	geminiThinking = "⠏ Considering the test layout (esc to cancel, 12s)\n\n~/src/api    gemini-2.5-pro (97% context left)\n"
+	geminiUrgent   = "Allow execution of: 'rm'?\n● 1. Yes, allow once\n  2. No\n\ngemini-2.5-pro (97% context left)\n"
+	geminiStats    = "│ Model Usage      Reqs   Input Tokens  Output Tokens │\n│ gemini-2.5-pro      3         12,000          1,500 │\n" +
+		"│ Input Tokens       12,000 │\n│ Cached Tokens       4,000 │\n│ Output Tokens       1,500 │\n" + geminiIdle
+
+	claudeIdle = "╭──────────────────╮\n│ ✻ Claude Code    │\n╰──────────────────╯\n\n❯ \n"
+	shellIdle  = "user@host:~/src$ ls\nREADME.md  go.mod\nuser@host:~/src$ "
+	shellBusy  = "user@host:~/src$ make test\nok  \tpkg\t0.1s\n"
+)
+
+func TestRegistryDetect(t *testing.T) {
+	r, err := FromKinds([]string{"shell", "claude", "aider", "codex", "gemini"}, claude.NewDetector())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		content   string
+		wantKind  Kind
+		wantState claude.SessionState
+	}{
+		{"claude", claudeIdle, Claude, claude.StateIdle},
+		{"aider idle", aiderIdle, Aider, claude.StateIdle},
+		{"aider question", aiderUrgent, Aider, claude.StateUrgent},
+		{"aider waiting", aiderWaiting, Aider, claude.StateThinking},
+		{"codex idle", codexIdle, Codex, claude.StateIdle},
+		{"codex working", codexWorking, Codex, claude.StateThinking},
+		{"codex approval", codexUrgent, Codex, claude.StateUrgent},
+		{"gemini idle", geminiIdle, Gemini, claude.StateIdle},
+		{"gemini thinking", geminiThinking, Gemini, claude.StateThinking},
+		{"gemini approval", geminiUrgent, Gemini, claude.StateUrgent},
+		{"shell prompt", shellIdle, Shell, claude.StateIdle},
+		{"shell running", shellBusy, Shell, claude.StateActive},
+		{"fresh shell", "root@vm:/tmp# " + strings.Repeat("\n", 23), Shell, claude.StateIdle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := r.Detect(tt.content)
+			if d == nil {
+				t.Fatal("Detect() = nil")
+			}
+			if d.Kind() != tt.wantKind {
+				t.Fatalf("Detect() = %s, want %s", d.Kind(), tt.wantKind)
+			}
+			if info := d.ParseInfo(tt.content); info.State != tt.wantState {
+				t.Errorf("ParseInfo().State = %s (%s), want %s", info.State, info.Pattern, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestDefaultSkipsShells(t *testing.T) {
+	r := Default(claude.NewDetector())
+	if d := r.Detect(shellIdle); d != nil {
+		t.Errorf("Detect(shell) = %s, want nil", d.Kind())
+	}
+	if d := r.Detect(""); d != nil {
+		t.Errorf("Detect(empty) = %s, want nil", d.Kind())
+	}
+}
+
+func TestFromKindsUnknown(t *testing.T) {
+	if _, err := FromKinds([]string{"claude", "copilot"}, claude.NewDetector()); err == nil {
+		t.Error("FromKinds() accepted an unknown agent")
+	}
+}
+
+func TestParseUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		detector AgentDetector
+		content  string
+		want     *usage.SessionUsage
+	}{
+		{
+			name:     "aider",
+			detector: NewAider(),
+			content:  aiderIdle,
+			want: &usage.SessionUsage{
+				TotalUsage:    usage.TokenUsage{InputTokens: 4200, CacheReadInputTokens: 1100, OutputTokens: 312},
+				EstimatedCost: 0.15,
+				Model:         "anthropic/claude-sonnet-4",
+			},
+		},
+		{
+			name:     "aider without cost",
+			detector: NewAider(),
+			content:  aiderUrgent,
+			want: &usage.SessionUsage{
+				TotalUsage: usage.TokenUsage{InputTokens: 4200, OutputTokens: 312},
+			},
+		},
+		{
+			name:     "codex",
+			detector: NewCodex(),
+			content:  codexIdle + codexExit,
+			want: &usage.SessionUsage{
+				TotalUsage: usage.TokenUsage{InputTokens: 2000, CacheReadInputTokens: 8000, OutputTokens: 2345},
+				Model:      "gpt-5-codex",
+			},
+		},
+		{
+			name:     "gemini",
+			detector: NewGemini(),
+			content:  geminiStats,
+			want: &usage.SessionUsage{
+				TotalUsage: usage.TokenUsage{InputTokens: 8000, CacheReadInputTokens: 4000, OutputTokens: 1500},
+				Model:      "gemini-2.5-pro",
+			},
+		},
+		{
+			name:     "nothing printed yet",
+			detector: NewCodex(),
+			content:  codexIdle,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.detector.(UsageParser).ParseUsage(tt.content)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("ParseUsage() = %+v, want %+v", got, tt.want)
+			}
+			if got != nil && !reflect.DeepEqual(*got, *tt.want) {
+				t.Errorf("ParseUsage() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
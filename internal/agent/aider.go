@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+var (
+	aiderTokensPattern = regexp.MustCompile(`Tokens: ([\d.,]+[kM]?) sent(?:, ([\d.,]+[kM]?) cache write)?(?:, ([\d.,]+[kM]?) cache hit)?, ([\d.,]+[kM]?) received\.(?: Cost: \$[\d.]+ message, \$([\d.]+) session\.)?`)
+	aiderModelPattern  = regexp.MustCompile(`(?m)^(?:Main model|Model): (\S+)`)
+)
+
+type aiderAgent struct {
+	screenDetector
+}
+
+// NewAider returns the Aider detector
+func NewAider() AgentDetector {
+	return &aiderAgent{screenDetector{
+		kind: Aider,
+		identify: patterns(
+			[2]string{"banner", `Aider v\d`},
+			[2]string{"main-model", `(?m)^Main model: `},
+			[2]string{"repo-map", `(?m)^Repo-map: `},
+			[2]string{"token-report", `Tokens: [\d.,]+[kM]? sent`},
+		),
+		urgent: patterns(
+			[2]string{"yes-no", `\(Y\)es/\(N\)o`},
+		),
+		thinking: patterns(
+			[2]string{"waiting-for-model", `Waiting for \S+`},
+			[2]string{"spinner", `⠋|⠙|⠹|⠸|⠼|⠴|⠦|⠧|⠇|⠏|░█|█░`},
+		),
+		idle: patterns(
+			[2]string{"prompt", `(?m)^[\w-]*> ?$`},
+		),
+	}}
+}
+
+// ParseUsage reads the last "Tokens: ... Cost: ..." report. Aider prints
+// tokens per message but cost per session, so TotalUsage is the last
+// message's tokens while EstimatedCost covers the whole session.
+func (a *aiderAgent) ParseUsage(content string) *usage.SessionUsage {
+	content = stripANSI(content)
+	reports := aiderTokensPattern.FindAllStringSubmatch(content, -1)
+	if len(reports) == 0 {
+		return nil
+	}
+	m := reports[len(reports)-1]
+
+	u := &usage.SessionUsage{
+		TotalUsage: usage.TokenUsage{
+			InputTokens:              parseCount(m[1]),
+			CacheCreationInputTokens: parseCount(m[2]),
+			CacheReadInputTokens:     parseCount(m[3]),
+			OutputTokens:             parseCount(m[4]),
+		},
+	}
+	if m[5] != "" {
+		u.EstimatedCost, _ = strconv.ParseFloat(m[5], 64)
+	}
+	if model := aiderModelPattern.FindStringSubmatch(content); model != nil {
+		u.Model = model[1]
+	}
+	return u
+}
@@ -0,0 +1,22 @@
+package agent
+
+import "github.com/valentindosimont/ccmanager/internal/claude"
+
+// claudeAgent adapts claude.Detector. Its usage is read from transcripts by
+// the usage package, so it doesn't implement UsageParser.
+type claudeAgent struct {
+	*claude.Detector
+}
+
+// NewClaude returns the Claude Code detector
+func NewClaude(d *claude.Detector) AgentDetector {
+	return claudeAgent{d}
+}
+
+func (claudeAgent) Kind() Kind {
+	return Claude
+}
+
+func (a claudeAgent) Matches(content string) bool {
+	return a.IsClaudeSession(content)
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"regexp"
+
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+var (
+	codexTokensPattern = regexp.MustCompile(`Token usage: total=[\d,]+ input=([\d,]+)(?: \(\+ ([\d,]+) cached\))? output=([\d,]+)`)
+	codexModelPattern  = regexp.MustCompile(`(?m)^\s*│?\s*model:\s+(\S+)`)
+)
+
+type codexAgent struct {
+	screenDetector
+}
+
+// NewCodex returns the OpenAI Codex CLI detector
+func NewCodex() AgentDetector {
+	return &codexAgent{screenDetector{
+		kind: Codex,
+		identify: patterns(
+			[2]string{"banner", `OpenAI Codex`},
+			[2]string{"placeholder", `Ask Codex to do anything`},
+			[2]string{"context-left", `\d+% context left · `},
+		),
+		urgent: patterns(
+			[2]string{"run-command", `Would you like to run the following command\?`},
+			[2]string{"make-edits", `Would you like to make the following edits\?`},
+			[2]string{"allow-command", `Allow command\?`},
+			[2]string{"yes-proceed", `Yes, proceed`},
+		),
+		thinking: patterns(
+			[2]string{"working", `Working \(\d+`},
+			[2]string{"esc-to-interrupt", `esc to interrupt\)`},
+		),
+		idle: patterns(
+			[2]string{"context-left", `\d+% context left`},
+			[2]string{"send-hint", `⏎ send`},
+		),
+	}}
+}
+
+// ParseUsage reads the "Token usage:" summary Codex prints when a session ends
+func (a *codexAgent) ParseUsage(content string) *usage.SessionUsage {
+	content = stripANSI(content)
+	reports := codexTokensPattern.FindAllStringSubmatch(content, -1)
+	if len(reports) == 0 {
+		return nil
+	}
+	m := reports[len(reports)-1]
+
+	// input includes the cached tokens
+	cached := parseCount(m[2])
+	u := &usage.SessionUsage{
+		TotalUsage: usage.TokenUsage{
+			InputTokens:          parseCount(m[1]) - cached,
+			CacheReadInputTokens: cached,
+			OutputTokens:         parseCount(m[3]),
+		},
+	}
+	if model := codexModelPattern.FindStringSubmatch(content); model != nil {
+		u.Model = model[1]
+	}
+	return u
+}
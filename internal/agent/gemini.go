@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"regexp"
+
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+var (
+	geminiInputPattern  = regexp.MustCompile(`Input Tokens\s+([\d,]+)`)
+	geminiOutputPattern = regexp.MustCompile(`Output Tokens\s+([\d,]+)`)
+	geminiCachedPattern = regexp.MustCompile(`Cached Tokens\s+([\d,]+)`)
+	geminiModelPattern  = regexp.MustCompile(`(gemini-[\w.-]+)`)
+)
+
+type geminiAgent struct {
+	screenDetector
+}
+
+// NewGemini returns the Gemini CLI detector
+func NewGemini() AgentDetector {
+	return &geminiAgent{screenDetector{
+		kind: Gemini,
+		identify: patterns(
+			[2]string{"footer", `gemini-[\w.-]+ \(\d+% context left\)`},
+			[2]string{"placeholder", `Type your message or @path/to/file`},
+			[2]string{"title", `Gemini CLI`},
+		),
+		urgent: patterns(
+			[2]string{"allow-execution", `Allow execution`},
+			[2]string{"apply-change", `Apply this change\?`},
+			[2]string{"allow-once", `Yes, allow once`},
+		),
+		thinking: patterns(
+			[2]string{"esc-to-cancel", `\(esc to cancel`},
+			[2]string{"spinner", `⠋|⠙|⠹|⠸|⠼|⠴|⠦|⠧|⠇|⠏`},
+		),
+		idle: patterns(
+			[2]string{"placeholder", `Type your message`},
+			[2]string{"context-left", `\(\d+% context left\)`},
+		),
+	}}
+}
+
+// ParseUsage reads the token table printed by /stats and on exit
+func (a *geminiAgent) ParseUsage(content string) *usage.SessionUsage {
+	content = stripANSI(content)
+	input := lastSubmatch(geminiInputPattern, content)
+	output := lastSubmatch(geminiOutputPattern, content)
+	if input == "" && output == "" {
+		return nil
+	}
+
+	// Input Tokens includes the cached tokens
+	cached := parseCount(lastSubmatch(geminiCachedPattern, content))
+	u := &usage.SessionUsage{
+		TotalUsage: usage.TokenUsage{
+			InputTokens:          parseCount(input) - cached,
+			CacheReadInputTokens: cached,
+			OutputTokens:         parseCount(output),
+		},
+	}
+	u.Model = lastSubmatch(geminiModelPattern, content)
+	return u
+}
+
+func lastSubmatch(re *regexp.Regexp, s string) string {
+	matches := re.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1][1]
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valentindosimont/ccmanager/internal/claude"
+)
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// screenDetector classifies a pane by matching named patterns against its
+// last lines, checking urgent, then thinking, then idle, like claude.Detector
+type screenDetector struct {
+	kind     Kind
+	identify []claude.Pattern
+	urgent   []claude.Pattern
+	thinking []claude.Pattern
+	idle     []claude.Pattern
+}
+
+func (d *screenDetector) Kind() Kind {
+	return d.kind
+}
+
+func (d *screenDetector) Matches(content string) bool {
+	return firstMatch(d.identify, stripANSI(content)) != ""
+}
+
+func (d *screenDetector) ParseInfo(content string) claude.SessionInfo {
+	content = stripANSI(content)
+	// A fresh pane is mostly blank lines below the cursor
+	state, pattern := d.classify(lastLines(strings.TrimRight(content, " \n"), 20))
+	return claude.SessionInfo{
+		State:    state,
+		Pattern:  pattern,
+		LastLine: lastNonEmptyLine(content),
+	}
+}
+
+func (d *screenDetector) classify(lines string) (claude.SessionState, string) {
+	if name := firstMatch(d.urgent, lines); name != "" {
+		return claude.StateUrgent, name
+	}
+	if name := firstMatch(d.thinking, lines); name != "" {
+		return claude.StateThinking, name
+	}
+	if name := firstMatch(d.idle, lines); name != "" {
+		return claude.StateIdle, name
+	}
+	return claude.StateActive, ""
+}
+
+func patterns(defs ...[2]string) []claude.Pattern {
+	result := make([]claude.Pattern, len(defs))
+	for i, def := range defs {
+		result[i] = claude.Pattern{Name: def[0], Re: regexp.MustCompile(def[1])}
+	}
+	return result
+}
+
+func firstMatch(patterns []claude.Pattern, s string) string {
+	for _, p := range patterns {
+		if p.Re.MatchString(s) {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+func lastLines(content string, n int) string {
+	lines := strings.Split(content, "\n")
+	if len(lines) <= n {
+		return content
+	}
+	return strings.Join(lines[len(lines)-n:], "\n")
+}
+
+func lastNonEmptyLine(content string) string {
+	lines := strings.Split(content, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
+
+// parseCount parses "12,345", "4.2k" or "1.1M" as a token count
+func parseCount(s string) int64 {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "M"):
+		multiplier, s = 1e6, strings.TrimSuffix(s, "M")
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(n * multiplier))
+}
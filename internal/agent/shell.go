@@ -0,0 +1,23 @@
+package agent
+
+import "strings"
+
+// shellAgent is the fallback for panes running no known agent
+type shellAgent struct {
+	screenDetector
+}
+
+// NewShell returns a detector matching any pane with output. The pane is idle
+// when it ends with a prompt and active while a command runs.
+func NewShell() AgentDetector {
+	return &shellAgent{screenDetector{
+		kind: Shell,
+		idle: patterns(
+			[2]string{"prompt", `[$#%>❯]\s*$`},
+		),
+	}}
+}
+
+func (a *shellAgent) Matches(content string) bool {
+	return strings.TrimSpace(stripANSI(content)) != ""
+}
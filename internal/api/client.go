@@ -0,0 +1,242 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/hooks"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+// baseURL is a placeholder host; every request is dialled on the socket
+const baseURL = "http://ccmanager"
+
+// Client talks to a Server over its Unix socket
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the socket at path
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Client{http: &http.Client{Transport: transport}}
+}
+
+// SetTimeout limits how long each request may take (0 means no limit)
+func (c *Client) SetTimeout(d time.Duration) {
+	c.http.Timeout = d
+}
+
+// SendHook forwards a Claude Code hook invocation to the monitor
+func (c *Client) SendHook(h hooks.Hook) error {
+	return c.do(http.MethodPost, "/hooks", h, nil)
+}
+
+// Sessions lists monitored sessions
+func (c *Client) Sessions() ([]Session, error) {
+	var sessions []Session
+	err := c.do(http.MethodGet, "/sessions", nil, &sessions)
+	return sessions, err
+}
+
+// Session returns one monitored session
+func (c *Client) Session(name string) (Session, error) {
+	var sess Session
+	err := c.do(http.MethodGet, sessionPath(name, ""), nil, &sess)
+	return sess, err
+}
+
+// SendPrompt types text into a session and presses Enter
+func (c *Client) SendPrompt(name, text string) error {
+	return c.do(http.MethodPost, sessionPath(name, "/prompt"), PromptRequest{Text: text}, nil)
+}
+
+// SendKey sends a named key (escape, interrupt, cycle-mode, up, down, enter)
+func (c *Client) SendKey(name, key string) error {
+	return c.do(http.MethodPost, sessionPath(name, "/keys"), KeyRequest{Key: key}, nil)
+}
+
+// SwitchMode cycles a session into the given Claude mode
+func (c *Client) SwitchMode(name, mode string) (bool, error) {
+	var resp ModeResponse
+	err := c.do(http.MethodPost, sessionPath(name, "/mode"), ModeRequest{Mode: mode}, &resp)
+	return resp.Switched, err
+}
+
+// CreateSession creates a new session running claude
+func (c *Client) CreateSession(req CreateRequest) (CreateResponse, error) {
+	var resp CreateResponse
+	err := c.do(http.MethodPost, "/sessions", req, &resp)
+	return resp, err
+}
+
+// KillSession kills a session and cleans up its workspace
+func (c *Client) KillSession(name string) error {
+	return c.do(http.MethodDelete, sessionPath(name, ""), nil, nil)
+}
+
+// Focus switches the tmux client to a session
+func (c *Client) Focus(name string) error {
+	return c.do(http.MethodPost, sessionPath(name, "/focus"), nil, nil)
+}
+
+// AcknowledgeBudgetStop lets prompts be sent again to a session a budget
+// stopped. It returns false if the session wasn't stopped.
+func (c *Client) AcknowledgeBudgetStop(name string) (bool, error) {
+	var resp AcknowledgeResponse
+	err := c.do(http.MethodPost, sessionPath(name, "/acknowledge"), nil, &resp)
+	return resp.Acknowledged, err
+}
+
+// State returns the current game state
+func (c *Client) State() (State, error) {
+	var state State
+	err := c.do(http.MethodGet, "/state", nil, &state)
+	return state, err
+}
+
+// UsageWindow returns the usage of the current 5-hour block and week, or nil
+// until the daemon has read transcripts
+func (c *Client) UsageWindow() (*UsageWindow, error) {
+	var window *UsageWindow
+	err := c.do(http.MethodGet, "/usage/window", nil, &window)
+	return window, err
+}
+
+// Events streams events to fn until ctx is cancelled, the server goes away
+// or fn returns an error. An empty session streams every session. The
+// client's timeout doesn't apply to the stream.
+func (c *Client) Events(ctx context.Context, session string, fn func(Event) error) error {
+	path := "/events"
+	if session != "" {
+		path += "?session=" + url.QueryEscape(session)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	stream := *c.http
+	stream.Timeout = 0
+	resp, err := stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+func (c *Client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func decodeError(resp *http.Response) error {
+	var e errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err == nil && e.Error != "" {
+		return fmt.Errorf("%s", e.Error)
+	}
+	return fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+func sessionPath(name, suffix string) string {
+	return "/sessions/" + url.PathEscape(name) + suffix
+}
+
+// Remote is the daemon's monitor seen through a Client, for a monitor to
+// follow with daemon.Monitor.Follow
+type Remote struct {
+	client *Client
+}
+
+// NewRemote creates a Remote reading through client
+func NewRemote(client *Client) *Remote {
+	return &Remote{client: client}
+}
+
+// Snapshot returns the daemon's sessions
+func (r *Remote) Snapshot() ([]daemon.SessionState, error) {
+	sessions, err := r.client.Sessions()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make([]daemon.SessionState, len(sessions))
+	for i, sess := range sessions {
+		snapshot[i] = sess.SessionState()
+	}
+	return snapshot, nil
+}
+
+// UsageWindow returns the daemon's usage window
+func (r *Remote) UsageWindow() (*usage.WindowStatus, error) {
+	window, err := r.client.UsageWindow()
+	if err != nil || window == nil {
+		return nil, err
+	}
+	return window.WindowStatus(), nil
+}
+
+// Events streams the daemon's events to fn
+func (r *Remote) Events(ctx context.Context, fn func(daemon.Event) error) error {
+	return r.client.Events(ctx, "", func(e Event) error {
+		return fn(e.MonitorEvent())
+	})
+}
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/control"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/game"
+	"github.com/valentindosimont/ccmanager/internal/hooks"
+)
+
+// ErrSocketInUse is returned when another process is already serving the socket
+var ErrSocketInUse = errors.New("control socket already in use")
+
+// Server exposes session actions and events over HTTP on a Unix socket
+type Server struct {
+	ctrl    *control.Controller
+	monitor *daemon.Monitor
+	engine  *game.Engine
+
+	httpServer *http.Server
+	listener   net.Listener
+	socketPath string
+}
+
+// NewServer creates a new control API server
+func NewServer(ctrl *control.Controller, monitor *daemon.Monitor, engine *game.Engine) *Server {
+	s := &Server{
+		ctrl:    ctrl,
+		monitor: monitor,
+		engine:  engine,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", s.handleListSessions)
+	mux.HandleFunc("POST /sessions", s.handleCreateSession)
+	mux.HandleFunc("GET /sessions/{name}", s.handleGetSession)
+	mux.HandleFunc("DELETE /sessions/{name}", s.handleKillSession)
+	mux.HandleFunc("POST /sessions/{name}/prompt", s.handlePrompt)
+	mux.HandleFunc("POST /sessions/{name}/keys", s.handleKey)
+	mux.HandleFunc("POST /sessions/{name}/mode", s.handleMode)
+	mux.HandleFunc("POST /sessions/{name}/focus", s.handleFocus)
+	mux.HandleFunc("POST /sessions/{name}/acknowledge", s.handleAcknowledge)
+	mux.HandleFunc("GET /state", s.handleState)
+	mux.HandleFunc("GET /usage/window", s.handleUsageWindow)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("POST /hooks", s.handleHook)
+
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// Start listens on the Unix socket at path and serves in the background.
+// A leftover socket from a crashed process is removed; a live one is an error.
+func (s *Server) Start(path string) error {
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%w: %s", ErrSocketInUse, path)
+	}
+	_ = os.Remove(path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create socket directory: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("chmod socket: %w", err)
+	}
+
+	s.listener = listener
+	s.socketPath = path
+	go func() { _ = s.httpServer.Serve(listener) }()
+	return nil
+}
+
+// Close stops the server and removes the socket
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	err := s.httpServer.Close()
+	_ = os.Remove(s.socketPath)
+	return err
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.ctrl.Sessions()
+	sessions := make([]Session, 0, len(snapshot))
+	for _, sess := range snapshot {
+		sessions = append(sessions, NewSession(sess))
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.ctrl.Session(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, NewSession(sess))
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	path, err := s.ctrl.CreateSession(control.CreateOptions{
+		Name:      req.Name,
+		Path:      req.Path,
+		Workspace: req.Workspace,
+	})
+	resp := CreateResponse{Name: req.Name, Path: path}
+	var warning *control.CreateWarning
+	if errors.As(err, &warning) {
+		resp.Warning = warning.Error()
+	} else if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (s *Server) handleKillSession(w http.ResponseWriter, r *http.Request) {
+	if err := s.ctrl.KillSession(r.PathValue("name")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	var req PromptRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Text == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "text required"})
+		return
+	}
+	if err := s.ctrl.SendPrompt(r.PathValue("name"), req.Text); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleKey(w http.ResponseWriter, r *http.Request) {
+	var req KeyRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if err := s.ctrl.SendKey(r.PathValue("name"), req.Key); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMode(w http.ResponseWriter, r *http.Request) {
+	var req ModeRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	switched, err := s.ctrl.SwitchMode(r.PathValue("name"), req.Mode)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ModeResponse{Switched: switched})
+}
+
+func (s *Server) handleFocus(w http.ResponseWriter, r *http.Request) {
+	if err := s.ctrl.Focus(r.PathValue("name")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAcknowledge(w http.ResponseWriter, r *http.Request) {
+	acknowledged, err := s.ctrl.AcknowledgeBudgetStop(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AcknowledgeResponse{Acknowledged: acknowledged})
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	pomodoro := s.engine.Pomodoro()
+	level := game.LevelFor(s.engine.XP())
+	writeJSON(w, http.StatusOK, State{
+		Score:                    s.engine.Score(),
+		XP:                       level.XP,
+		Level:                    level.Number,
+		Rank:                     level.Rank.Name,
+		APM:                      s.engine.APM(),
+		StreakMultiplier:         s.engine.StreakMultiplier(),
+		PomodoroState:            pomodoro.StateString(),
+		PomodoroRemainingSeconds: int(pomodoro.Remaining().Seconds()),
+	})
+}
+
+// handleUsageWindow responds with null until transcripts have been read
+func (s *Server) handleUsageWindow(w http.ResponseWriter, r *http.Request) {
+	var window *UsageWindow
+	if status := s.monitor.UsageWindow(); status != nil {
+		converted := NewUsageWindow(status)
+		window = &converted
+	}
+	writeJSON(w, http.StatusOK, window)
+}
+
+func (s *Server) handleHook(w http.ResponseWriter, r *http.Request) {
+	var h hooks.Hook
+	if !readJSON(w, r, &h) {
+		return
+	}
+	if err := s.monitor.HandleHook(h); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents streams events as newline-delimited JSON until the client
+// disconnects. ?session=name limits the stream to one session.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming unsupported"})
+		return
+	}
+
+	events, unsubscribe := s.monitor.Subscribe()
+	defer unsubscribe()
+
+	filter := r.URL.Query().Get("session")
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Type == daemon.EventDebug {
+				continue
+			}
+			if filter != "" && event.Session != filter {
+				continue
+			}
+			if err := enc.Encode(newEvent(event)); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid request body: %v", err)})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, control.ErrSessionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, daemon.ErrUnknownSession):
+		status = http.StatusNotFound
+	case errors.Is(err, control.ErrUnknownKey), errors.Is(err, control.ErrNotClaude):
+		status = http.StatusBadRequest
+	case errors.Is(err, control.ErrBudgetStopped):
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
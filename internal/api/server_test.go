@@ -0,0 +1,319 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/control"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/game"
+	"github.com/valentindosimont/ccmanager/internal/hooks"
+	"github.com/valentindosimont/ccmanager/internal/tmux/tmuxtest"
+)
+
+const (
+	claudeIdle = "╭──────────────────╮\n│ ✻ Claude Code    │\n╰──────────────────╯\n\n❯ \n"
+	aiderIdle  = "Aider v0.86.1\nMain model: gpt-4o with diff edit format\n\n> "
+)
+
+// newTestServer serves two Claude sessions, api and web, and an Aider one
+func newTestServer(t *testing.T) (*Server, *tmuxtest.Fake) {
+	t.Helper()
+	fake := tmuxtest.New()
+	fake.AddSession("api", "/src/api", claudeIdle)
+	fake.AddSession("web", "/src/web", claudeIdle)
+	fake.AddSession("aider", "/src/aider", aiderIdle)
+
+	monitor := daemon.NewMonitor(time.Second, nil, fake)
+	monitor.Refresh()
+	ctrl := control.New(monitor, fake, nil, nil, config.Default())
+	return NewServer(ctrl, monitor, game.NewEngine(game.DefaultEngineConfig())), fake
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "session not found", err: fmt.Errorf("%w: api", control.ErrSessionNotFound), want: http.StatusNotFound},
+		{name: "unknown hook session", err: daemon.ErrUnknownSession, want: http.StatusNotFound},
+		{name: "unknown key", err: fmt.Errorf("%w: f13", control.ErrUnknownKey), want: http.StatusBadRequest},
+		{name: "not claude", err: fmt.Errorf("switch mode: %w", control.ErrNotClaude), want: http.StatusBadRequest},
+		{name: "budget stopped", err: fmt.Errorf("send to api: %w", control.ErrBudgetStopped), want: http.StatusConflict},
+		{name: "other", err: errors.New("tmux exploded"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeError(rec, tt.err)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			var body errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error != tt.err.Error() {
+				t.Errorf("body = %+v (%v), want error %q", body, err, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	srv, _ := newTestServer(t)
+	ts := httptest.NewServer(srv.httpServer.Handler)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{name: "list", method: http.MethodGet, path: "/sessions", want: http.StatusOK},
+		{name: "get", method: http.MethodGet, path: "/sessions/api", want: http.StatusOK},
+		{name: "get unknown", method: http.MethodGet, path: "/sessions/nope", want: http.StatusNotFound},
+		{name: "create bad body", method: http.MethodPost, path: "/sessions", body: "{", want: http.StatusBadRequest},
+		{name: "kill unknown", method: http.MethodDelete, path: "/sessions/nope", want: http.StatusInternalServerError},
+		{name: "prompt", method: http.MethodPost, path: "/sessions/api/prompt", body: `{"text":"hi"}`, want: http.StatusNoContent},
+		{name: "prompt bad body", method: http.MethodPost, path: "/sessions/api/prompt", body: `{"text":`, want: http.StatusBadRequest},
+		{name: "prompt without text", method: http.MethodPost, path: "/sessions/api/prompt", body: `{}`, want: http.StatusBadRequest},
+		{name: "prompt unknown session", method: http.MethodPost, path: "/sessions/nope/prompt", body: `{"text":"hi"}`, want: http.StatusNotFound},
+		{name: "key", method: http.MethodPost, path: "/sessions/api/keys", body: `{"key":"escape"}`, want: http.StatusNoContent},
+		{name: "key bad body", method: http.MethodPost, path: "/sessions/api/keys", body: `[]`, want: http.StatusBadRequest},
+		{name: "unknown key", method: http.MethodPost, path: "/sessions/api/keys", body: `{"key":"f13"}`, want: http.StatusBadRequest},
+		{name: "mode bad body", method: http.MethodPost, path: "/sessions/api/mode", body: `"plan"`, want: http.StatusBadRequest},
+		{name: "mode of aider", method: http.MethodPost, path: "/sessions/aider/mode", body: `{"mode":"plan"}`, want: http.StatusBadRequest},
+		{name: "focus", method: http.MethodPost, path: "/sessions/api/focus", want: http.StatusNoContent},
+		{name: "acknowledge", method: http.MethodPost, path: "/sessions/api/acknowledge", want: http.StatusOK},
+		{name: "acknowledge unknown", method: http.MethodPost, path: "/sessions/nope/acknowledge", want: http.StatusNotFound},
+		{name: "state", method: http.MethodGet, path: "/state", want: http.StatusOK},
+		{name: "usage window", method: http.MethodGet, path: "/usage/window", want: http.StatusOK},
+		{name: "hook", method: http.MethodPost, path: "/hooks", body: `{"event":"Stop","session":"api"}`, want: http.StatusNoContent},
+		{name: "hook bad body", method: http.MethodPost, path: "/hooks", body: `nope`, want: http.StatusBadRequest},
+		{name: "hook unknown session", method: http.MethodPost, path: "/hooks", body: `{"event":"Stop","session":"nope"}`, want: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodPut, path: "/sessions/api", want: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestDecoding(t *testing.T) {
+	srv, fake := newTestServer(t)
+	ts := httptest.NewServer(srv.httpServer.Handler)
+	defer ts.Close()
+
+	post := func(path, body string) {
+		t.Helper()
+		resp, err := ts.Client().Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			t.Fatalf("POST %s = %d", path, resp.StatusCode)
+		}
+	}
+
+	post("/sessions/api/prompt", `{"text":"fix the tests"}`)
+	post("/sessions/web/keys", `{"key":"enter"}`)
+	post("/sessions/web/focus", ``)
+
+	sent := fake.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("sent %+v, want a prompt and a key", sent)
+	}
+	if sent[0].Session != "api" || sent[0].Keys != "fix the tests" || sent[0].Raw {
+		t.Errorf("prompt sent as %+v", sent[0])
+	}
+	if sent[1].Session != "web" || sent[1].Keys != "Enter" || !sent[1].Raw {
+		t.Errorf("key sent as %+v", sent[1])
+	}
+	if got := fake.Focused(); got != "web" {
+		t.Errorf("focused %q, want web", got)
+	}
+
+	post("/sessions", `{"name":"docs","path":"/src/docs"}`)
+	if path, err := fake.GetSessionPath("docs"); err != nil || path != "/src/docs" {
+		t.Errorf("created session path = %q (%v), want /src/docs", path, err)
+	}
+}
+
+func TestEventsSessionFilter(t *testing.T) {
+	srv, _ := newTestServer(t)
+	ts := httptest.NewServer(srv.httpServer.Handler)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/events?session=web", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// The subscription is live once the headers are in
+	for _, name := range []string{"api", "web"} {
+		if err := srv.monitor.HandleHook(hooks.Hook{Event: hooks.EventUserPromptSubmit, Session: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var event Event
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Session != "web" {
+		t.Errorf("event for %q streamed, want only web", event.Session)
+	}
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	srv, fake := newTestServer(t)
+	socket := filepath.Join(t.TempDir(), "ccmanager.sock")
+	if err := srv.Start(socket); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = srv.Close() }()
+
+	if err := NewServer(srv.ctrl, srv.monitor, srv.engine).Start(socket); !errors.Is(err, ErrSocketInUse) {
+		t.Errorf("second Start() error = %v, want ErrSocketInUse", err)
+	}
+
+	client := NewClient(socket)
+	client.SetTimeout(5 * time.Second)
+
+	sessions, err := client.Sessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 3 {
+		t.Errorf("Sessions() = %d sessions, want 3", len(sessions))
+	}
+
+	sess, err := client.Session("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.WorkingDir != "/src/api" || sess.Agent != "claude" {
+		t.Errorf("Session(api) = %+v", sess)
+	}
+	if _, err := client.Session("nope"); err == nil || !strings.Contains(err.Error(), "session not found") {
+		t.Errorf("Session(nope) error = %v, want session not found", err)
+	}
+
+	if err := client.SendPrompt("api", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendKey("api", "f13"); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("SendKey(f13) error = %v, want unknown key", err)
+	}
+	if err := client.Focus("web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendHook(hooks.Hook{Event: hooks.EventUserPromptSubmit, Session: "api"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if acknowledged, err := client.AcknowledgeBudgetStop("api"); err != nil || acknowledged {
+		t.Errorf("AcknowledgeBudgetStop(api) = %v, %v, want false without a stop", acknowledged, err)
+	}
+
+	state, err := client.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Level != 1 || state.Rank != "Bronze" {
+		t.Errorf("State() = %+v", state)
+	}
+	// The monitor wasn't started, so transcripts haven't been read
+	if window, err := client.UsageWindow(); err != nil || window != nil {
+		t.Errorf("UsageWindow() = %+v, %v, want nil", window, err)
+	}
+
+	remote := NewRemote(client)
+	snapshot, err := remote.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot) != 3 || snapshot[0].ClaudePane == nil {
+		t.Errorf("Remote.Snapshot() = %+v, want 3 sessions with panes", snapshot)
+	}
+
+	created, err := client.CreateSession(CreateRequest{Name: "docs", Path: "/src/docs", Workspace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Path != "/src/docs" || created.Warning == "" {
+		t.Errorf("CreateSession() = %+v, want /src/docs with a workspace warning", created)
+	}
+	if err := client.KillSession("docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := fake.Sent()
+	if len(sent) == 0 || sent[0].Session != "api" || sent[0].Keys != "hello" {
+		t.Errorf("sent %+v, want the prompt first", sent)
+	}
+	if got := fake.Focused(); got != "web" {
+		t.Errorf("focused %q, want web", got)
+	}
+
+	// Events stream over the socket too
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got := make(chan Event, 1)
+	go func() {
+		_ = client.Events(ctx, "api", func(e Event) error {
+			got <- e
+			return errors.New("done")
+		})
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		_ = srv.monitor.HandleHook(hooks.Hook{Event: hooks.EventStop, Session: "api"})
+		_ = srv.monitor.HandleHook(hooks.Hook{Event: hooks.EventUserPromptSubmit, Session: "api"})
+		select {
+		case e := <-got:
+			if e.Session != "api" {
+				t.Errorf("event for %q, want api", e.Session)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no event streamed")
+		}
+	}
+}
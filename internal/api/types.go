@@ -0,0 +1,403 @@
+package api
+
+import (
+	"sort"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/agent"
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/tmux"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+// Session is the JSON representation of a monitored session
+type Session struct {
+	Name            string      `json:"name"`
+	Agent           string      `json:"agent"`
+	State           string      `json:"state"`
+	WorkingDir      string      `json:"working_dir"`
+	Tokens          int         `json:"tokens"`
+	ThinkingSeconds int         `json:"thinking_seconds"`
+	LastLine        string      `json:"last_line"`
+	Created         time.Time   `json:"created"`
+	Attached        bool        `json:"attached"`
+	Pane            *Pane       `json:"pane,omitempty"`
+	ClaudeSessionID string      `json:"claude_session_id,omitempty"`
+	Hooked          bool        `json:"hooked,omitempty"`
+	Usage           *Usage      `json:"usage,omitempty"`
+	Permission      *Permission `json:"permission,omitempty"`
+	BudgetStop      string      `json:"budget_stop,omitempty"`
+}
+
+// Pane is the tmux pane running a session's agent
+type Pane struct {
+	ID     string `json:"id"`
+	Window int    `json:"window"`
+	Index  int    `json:"index"`
+}
+
+// Permission is the permission dialog an urgent session is showing
+type Permission struct {
+	Tool        string             `json:"tool"`
+	Command     string             `json:"command,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Path        string             `json:"path,omitempty"`
+	Ambiguous   bool               `json:"ambiguous,omitempty"` // command may run into its description
+	Question    string             `json:"question"`
+	Options     []PermissionOption `json:"options"`
+}
+
+// PermissionOption is one numbered choice in a permission dialog
+type PermissionOption struct {
+	Number int    `json:"number"`
+	Label  string `json:"label"`
+}
+
+// Usage is the token usage and estimated cost of a session. Tokens and
+// EstimatedCost include what its Task subagents spent.
+type Usage struct {
+	Tokens        usage.TokenUsage `json:"tokens"`
+	EstimatedCost float64          `json:"estimated_cost"`
+	Model         string           `json:"model"`
+	Subagents     *UsageSplit      `json:"subagents,omitempty"`
+	Models        []ModelUsage     `json:"models,omitempty"`
+	Context       *Context         `json:"context,omitempty"`
+}
+
+// ModelUsage is one model's share of a session's usage
+type ModelUsage struct {
+	Model         string           `json:"model"`
+	Tokens        usage.TokenUsage `json:"tokens"`
+	EstimatedCost float64          `json:"estimated_cost"`
+	Priced        bool             `json:"priced"`
+}
+
+// Context is how full a session's context window is
+type Context struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+// UsageSplit is the share of a session's usage spent by its subagents
+type UsageSplit struct {
+	Tokens        usage.TokenUsage `json:"tokens"`
+	EstimatedCost float64          `json:"estimated_cost"`
+}
+
+// UsageWindow is how much of the plan's 5-hour block and week is used
+type UsageWindow struct {
+	Block        *Block           `json:"block,omitempty"`
+	BlockLimit   int64            `json:"block_limit"`
+	BlockMax     bool             `json:"block_max,omitempty"`
+	BurnRate     float64          `json:"burn_rate"`
+	CostPerHour  float64          `json:"cost_per_hour"`
+	Exhausts     *time.Time       `json:"exhausts,omitempty"`
+	WeekStart    time.Time        `json:"week_start"`
+	WeekEnd      *time.Time       `json:"week_end,omitempty"`
+	WeekTokens   usage.TokenUsage `json:"week_tokens"`
+	WeekCost     float64          `json:"week_cost"`
+	WeekLimit    int64            `json:"week_limit"`
+	WeekExhausts *time.Time       `json:"week_exhausts,omitempty"`
+}
+
+// Block is the 5-hour usage block in progress
+type Block struct {
+	Start        time.Time        `json:"start"`
+	End          time.Time        `json:"end"`
+	LastActivity time.Time        `json:"last_activity"`
+	Tokens       usage.TokenUsage `json:"tokens"`
+	Cost         float64          `json:"cost"`
+	Messages     int              `json:"messages"`
+}
+
+// Event is the JSON representation of a monitor event
+type Event struct {
+	Type    string    `json:"type"`
+	Session string    `json:"session,omitempty"`
+	State   string    `json:"state,omitempty"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message,omitempty"`
+	Pattern string    `json:"pattern,omitempty"`
+}
+
+// State is the current game state
+type State struct {
+	Score                    int     `json:"score"`
+	XP                       int     `json:"xp"`
+	Level                    int     `json:"level"`
+	Rank                     string  `json:"rank"`
+	APM                      int     `json:"apm"`
+	StreakMultiplier         float64 `json:"streak_multiplier"`
+	PomodoroState            string  `json:"pomodoro_state"`
+	PomodoroRemainingSeconds int     `json:"pomodoro_remaining_seconds"`
+}
+
+// PromptRequest sends text to a session
+type PromptRequest struct {
+	Text string `json:"text"`
+}
+
+// KeyRequest sends a named key (see control.Key*) to a session
+type KeyRequest struct {
+	Key string `json:"key"`
+}
+
+// ModeRequest switches a session's Claude mode
+type ModeRequest struct {
+	Mode string `json:"mode"`
+}
+
+// ModeResponse reports whether the mode was changed
+type ModeResponse struct {
+	Switched bool `json:"switched"`
+}
+
+// AcknowledgeResponse reports whether a budget stop was lifted
+type AcknowledgeResponse struct {
+	Acknowledged bool `json:"acknowledged"`
+}
+
+// CreateRequest creates a new session
+type CreateRequest struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Workspace bool   `json:"workspace"`
+}
+
+// CreateResponse describes a created session
+type CreateResponse struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Warning string `json:"warning,omitempty"` // why it wasn't created quite as asked
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewSession converts a monitored session to its JSON representation
+func NewSession(s daemon.SessionState) Session {
+	sess := Session{
+		Name:            s.Name,
+		Agent:           string(s.Agent),
+		State:           s.State.String(),
+		WorkingDir:      s.WorkingDir,
+		Tokens:          s.Tokens,
+		ThinkingSeconds: int(s.ThinkingTime.Seconds()),
+		LastLine:        s.LastLine,
+		Created:         s.Created,
+		Attached:        s.Attached,
+		ClaudeSessionID: s.ClaudeSessionID,
+		Hooked:          s.Hooked,
+		BudgetStop:      s.BudgetStop,
+	}
+	if p := s.ClaudePane; p != nil {
+		sess.Pane = &Pane{ID: p.ID, Window: p.WindowIndex, Index: p.PaneIndex}
+	}
+	if s.Usage != nil {
+		sess.Usage = &Usage{
+			Tokens:        s.Usage.TotalUsage,
+			EstimatedCost: s.Usage.EstimatedCost,
+			Model:         s.Usage.Model,
+		}
+		if sub := s.Usage.Subagents; sub.Usage != (usage.TokenUsage{}) {
+			sess.Usage.Subagents = &UsageSplit{Tokens: sub.Usage, EstimatedCost: sub.Cost}
+		}
+		for _, c := range s.Usage.ByModel() {
+			sess.Usage.Models = append(sess.Usage.Models, ModelUsage{Model: c.Model, Tokens: c.Usage, EstimatedCost: c.Cost, Priced: c.Priced})
+		}
+		if c := s.Usage.Context; c.Limit > 0 {
+			sess.Usage.Context = &Context{Used: c.Used, Limit: c.Limit}
+		}
+	}
+	if p := s.Permission; p != nil {
+		sess.Permission = &Permission{
+			Tool:        p.Tool,
+			Command:     p.Command,
+			Description: p.Description,
+			Path:        p.Path,
+			Ambiguous:   p.Ambiguous,
+			Question:    p.Question,
+		}
+		for _, opt := range p.Options {
+			sess.Permission.Options = append(sess.Permission.Options, PermissionOption{Number: opt.Number, Label: opt.Label})
+		}
+	}
+	return sess
+}
+
+func newEvent(e daemon.Event) Event {
+	ev := Event{
+		Type:    e.Type.String(),
+		Session: e.Session,
+		Time:    e.Time,
+		Message: e.Message,
+		Pattern: e.Pattern,
+	}
+	switch e.Type {
+	case daemon.EventSessionDiscovered, daemon.EventStateChanged, daemon.EventTaskCompleted, daemon.EventUrgent:
+		ev.State = e.State.String()
+	}
+	return ev
+}
+
+// SessionState converts the JSON representation back to a monitored session,
+// for a monitor following the daemon. Screen contents aren't sent, so
+// LastContent is empty.
+func (s Session) SessionState() daemon.SessionState {
+	sess := daemon.SessionState{
+		Name:            s.Name,
+		Agent:           agent.Kind(s.Agent),
+		State:           parseState(s.State),
+		WorkingDir:      s.WorkingDir,
+		Tokens:          s.Tokens,
+		ThinkingTime:    time.Duration(s.ThinkingSeconds) * time.Second,
+		LastLine:        s.LastLine,
+		Created:         s.Created,
+		Attached:        s.Attached,
+		ClaudeSessionID: s.ClaudeSessionID,
+		Hooked:          s.Hooked,
+		BudgetStop:      s.BudgetStop,
+	}
+	if p := s.Pane; p != nil {
+		sess.ClaudePane = &tmux.Pane{ID: p.ID, WindowIndex: p.Window, PaneIndex: p.Index, Active: true}
+	}
+	if u := s.Usage; u != nil {
+		sess.Usage = &usage.SessionUsage{
+			SessionID:     s.ClaudeSessionID,
+			TotalUsage:    u.Tokens,
+			EstimatedCost: u.EstimatedCost,
+			Model:         u.Model,
+		}
+		if sub := u.Subagents; sub != nil {
+			sess.Usage.Subagents = usage.UsageSplit{Usage: sub.Tokens, Cost: sub.EstimatedCost}
+		}
+		for _, m := range u.Models {
+			if sess.Usage.Models == nil {
+				sess.Usage.Models = make(map[string]usage.TokenUsage)
+				sess.Usage.ModelCosts = make(map[string]float64)
+			}
+			sess.Usage.Models[m.Model] = m.Tokens
+			sess.Usage.ModelCosts[m.Model] = m.EstimatedCost
+			if !m.Priced {
+				sess.Usage.Unpriced = append(sess.Usage.Unpriced, m.Model)
+			}
+		}
+		sort.Strings(sess.Usage.Unpriced)
+		if c := u.Context; c != nil {
+			sess.Usage.Context = usage.ContextWindow{Used: c.Used, Limit: c.Limit, Model: u.Model}
+		}
+	}
+	if p := s.Permission; p != nil {
+		sess.Permission = &claude.PermissionRequest{
+			Tool:        p.Tool,
+			Command:     p.Command,
+			Description: p.Description,
+			Path:        p.Path,
+			Ambiguous:   p.Ambiguous,
+			Question:    p.Question,
+		}
+		for _, opt := range p.Options {
+			sess.Permission.Options = append(sess.Permission.Options, claude.PermissionOption{Number: opt.Number, Label: opt.Label})
+		}
+	}
+	return sess
+}
+
+func parseState(s string) claude.SessionState {
+	for _, state := range []claude.SessionState{claude.StateIdle, claude.StateActive, claude.StateThinking, claude.StateUrgent} {
+		if state.String() == s {
+			return state
+		}
+	}
+	return claude.StateUnknown
+}
+
+// MonitorEvent converts the JSON representation back to a monitor event
+func (e Event) MonitorEvent() daemon.Event {
+	ev := daemon.Event{
+		Type:    daemon.EventDebug,
+		Session: e.Session,
+		State:   parseState(e.State),
+		Time:    e.Time,
+		Message: e.Message,
+		Pattern: e.Pattern,
+	}
+	for t := daemon.EventSessionDiscovered; t <= daemon.EventCompaction; t++ {
+		if t.String() == e.Type {
+			ev.Type = t
+		}
+	}
+	return ev
+}
+
+// NewUsageWindow converts a usage window to its JSON representation
+func NewUsageWindow(w *usage.WindowStatus) UsageWindow {
+	window := UsageWindow{
+		BlockLimit:   w.BlockLimit,
+		BlockMax:     w.BlockMax,
+		BurnRate:     w.BurnRate,
+		CostPerHour:  w.CostPerHour,
+		Exhausts:     optionalTime(w.Exhausts),
+		WeekStart:    w.WeekStart,
+		WeekEnd:      optionalTime(w.WeekEnd),
+		WeekTokens:   w.WeekUsage,
+		WeekCost:     w.WeekCost,
+		WeekLimit:    w.WeekLimit,
+		WeekExhausts: optionalTime(w.WeekExhausts),
+	}
+	if b := w.Block; b != nil {
+		window.Block = &Block{
+			Start:        b.Start,
+			End:          b.End,
+			LastActivity: b.LastActivity,
+			Tokens:       b.Usage,
+			Cost:         b.Cost,
+			Messages:     b.Messages,
+		}
+	}
+	return window
+}
+
+// WindowStatus converts the JSON representation back to a usage window
+func (w UsageWindow) WindowStatus() *usage.WindowStatus {
+	status := &usage.WindowStatus{
+		BlockLimit:  w.BlockLimit,
+		BlockMax:    w.BlockMax,
+		BurnRate:    w.BurnRate,
+		CostPerHour: w.CostPerHour,
+		WeekStart:   w.WeekStart,
+		WeekUsage:   w.WeekTokens,
+		WeekCost:    w.WeekCost,
+		WeekLimit:   w.WeekLimit,
+	}
+	if w.Exhausts != nil {
+		status.Exhausts = *w.Exhausts
+	}
+	if w.WeekEnd != nil {
+		status.WeekEnd = *w.WeekEnd
+	}
+	if w.WeekExhausts != nil {
+		status.WeekExhausts = *w.WeekExhausts
+	}
+	if b := w.Block; b != nil {
+		status.Block = &usage.Block{
+			Start:        b.Start,
+			End:          b.End,
+			LastActivity: b.LastActivity,
+			Usage:        b.Tokens,
+			Cost:         b.Cost,
+			Messages:     b.Messages,
+		}
+	}
+	return status
+}
+
+// optionalTime returns nil for the zero time, which means never
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
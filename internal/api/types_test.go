@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/agent"
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/tmux"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+// roundTrip encodes v as JSON and decodes it into out
+func roundTrip(t *testing.T, v, out interface{}) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	created := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	opus := usage.TokenUsage{InputTokens: 1000, OutputTokens: 200, CacheReadInputTokens: 50_000}
+	local := usage.TokenUsage{InputTokens: 300}
+
+	sessionUsage := &usage.SessionUsage{SessionID: "abc", Model: "claude-opus-4-5"}
+	sessionUsage.AddModelUsage("claude-opus-4-5", opus, 1.25, true)
+	sessionUsage.AddSubagentUsage("local-model", local, 0, false)
+	sessionUsage.Context = usage.ContextWindow{Used: 51_000, Limit: 200_000, Model: "claude-opus-4-5"}
+
+	tests := []struct {
+		name string
+		sess daemon.SessionState
+	}{
+		{
+			name: "screen scraped",
+			sess: daemon.SessionState{
+				Name:         "api",
+				Agent:        agent.Aider,
+				State:        claude.StateThinking,
+				WorkingDir:   "/src/api",
+				Tokens:       1200,
+				ThinkingTime: 42 * time.Second,
+				LastLine:     "Thinking…",
+				Created:      created,
+				ClaudePane:   &tmux.Pane{ID: "%3", WindowIndex: 1, PaneIndex: 2, Active: true},
+			},
+		},
+		{
+			name: "hooked with usage and a dialog",
+			sess: daemon.SessionState{
+				Name:            "web",
+				Agent:           agent.Claude,
+				State:           claude.StateUrgent,
+				WorkingDir:      "/src/web",
+				Created:         created,
+				Attached:        true,
+				ClaudePane:      &tmux.Pane{ID: "%7", Active: true},
+				ClaudeSessionID: "abc",
+				Hooked:          true,
+				Usage:           sessionUsage,
+				Permission: &claude.PermissionRequest{
+					Tool:     "Bash",
+					Command:  "go test ./...",
+					Question: "Do you want to proceed?",
+					Options:  []claude.PermissionOption{{Number: 1, Label: "Yes"}, {Number: 2, Label: "No"}},
+				},
+				BudgetStop: "daily budget of $10.00",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded Session
+			roundTrip(t, NewSession(tt.sess), &decoded)
+			got := decoded.SessionState()
+
+			want := tt.sess
+			if want.Usage != nil {
+				// Only what the dashboard shows is sent
+				u := *want.Usage
+				u.ProjectPath, u.Compaction, u.LastUpdated = "", nil, time.Time{}
+				want.Usage = &u
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip = %+v\nwant %+v", got, want)
+			}
+			if got.Usage != nil && !reflect.DeepEqual(got.Usage.ByModel(), want.Usage.ByModel()) {
+				t.Errorf("ByModel() = %+v, want %+v", got.Usage.ByModel(), want.Usage.ByModel())
+			}
+		})
+	}
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	at := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	for _, e := range []daemon.Event{
+		{Type: daemon.EventUrgent, Session: "api", State: claude.StateUrgent, Time: at, Message: "Do you want to proceed?", Pattern: "do-you-want"},
+		{Type: daemon.EventBudget, Session: "api", Time: at, Message: "daily budget reached"},
+		{Type: daemon.EventCompaction, Session: "web", Time: at, Message: "Context compacted"},
+	} {
+		var decoded Event
+		roundTrip(t, newEvent(e), &decoded)
+		if got := decoded.MonitorEvent(); !reflect.DeepEqual(got, e) {
+			t.Errorf("round trip = %+v, want %+v", got, e)
+		}
+	}
+}
+
+func TestUsageWindowRoundTrip(t *testing.T) {
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		window *usage.WindowStatus
+	}{
+		{
+			name: "between blocks",
+			window: &usage.WindowStatus{
+				BlockLimit: 1_000_000,
+				BlockMax:   true,
+				WeekStart:  start.AddDate(0, 0, -3),
+				WeekUsage:  usage.TokenUsage{InputTokens: 5000},
+				WeekCost:   3.5,
+			},
+		},
+		{
+			name: "block running out",
+			window: &usage.WindowStatus{
+				Block: &usage.Block{
+					Start:        start,
+					End:          start.Add(usage.BlockDuration),
+					LastActivity: start.Add(time.Hour),
+					Usage:        usage.TokenUsage{OutputTokens: 900},
+					Cost:         2,
+					Messages:     12,
+				},
+				BlockLimit:   1000,
+				BurnRate:     15,
+				CostPerHour:  2,
+				Exhausts:     start.Add(70 * time.Minute),
+				WeekStart:    start.AddDate(0, 0, -1),
+				WeekEnd:      start.AddDate(0, 0, 6),
+				WeekLimit:    10_000,
+				WeekExhausts: start.AddDate(0, 0, 2),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded UsageWindow
+			roundTrip(t, NewUsageWindow(tt.window), &decoded)
+			if got := decoded.WindowStatus(); !reflect.DeepEqual(got, tt.window) {
+				t.Errorf("round trip = %+v, want %+v", got, tt.window)
+			}
+		})
+	}
+}
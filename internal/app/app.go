@@ -1,23 +1,41 @@
 package app
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/valentindosimont/ccmanager/internal/agent"
+	"github.com/valentindosimont/ccmanager/internal/api"
+	"github.com/valentindosimont/ccmanager/internal/budget"
+	"github.com/valentindosimont/ccmanager/internal/claude"
 	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/control"
 	"github.com/valentindosimont/ccmanager/internal/daemon"
 	"github.com/valentindosimont/ccmanager/internal/game"
+	"github.com/valentindosimont/ccmanager/internal/policy"
 	"github.com/valentindosimont/ccmanager/internal/store"
+	"github.com/valentindosimont/ccmanager/internal/tmux"
 	"github.com/valentindosimont/ccmanager/internal/tui"
+	"github.com/valentindosimont/ccmanager/internal/usage"
 	"github.com/valentindosimont/ccmanager/internal/workspace"
 )
 
 // Config holds application configuration
 type Config struct {
 	DBPath       string
+	PIDPath      string
+	SocketPath   string
 	PollInterval time.Duration
+	ControlMode  bool
+	Detector     *claude.Detector
+	Agents       *agent.Registry
+	Policy       *policy.Policy
+	Prices       *usage.PriceTable
+	Plan         usage.PlanLimits
+	Budget       *budget.Budget
 	GameConfig   game.EngineConfig
 }
 
@@ -26,19 +44,56 @@ func DefaultConfig() Config {
 	homeDir, _ := os.UserHomeDir()
 	return Config{
 		DBPath:       filepath.Join(homeDir, ".config", "ccmanager", "ccmanager.db"),
+		PIDPath:      filepath.Join(homeDir, ".config", "ccmanager", "daemon.pid"),
+		SocketPath:   filepath.Join(homeDir, ".config", "ccmanager", "ccmanager.sock"),
 		PollInterval: 500 * time.Millisecond,
+		ControlMode:  true,
 		GameConfig:   game.DefaultEngineConfig(),
 	}
 }
 
-// LoadConfig loads configuration from file and merges with defaults
-func LoadConfig() (Config, *config.Config) {
+// LoadConfig loads configuration from file and merges with defaults. On error
+// the defaults are still returned, for callers that must not fail.
+func LoadConfig() (Config, *config.Config, error) {
 	cfg := DefaultConfig()
-	fileCfg, err := config.Load(config.DefaultPath())
+	path := config.DefaultPath()
+	fileCfg, err := config.Load(path)
 	if err != nil {
-		return cfg, nil
+		return cfg, nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	detector, err := claude.NewDetectorFromConfig(fileCfg.Detector)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	cfg.Detector = detector
+	agents, err := agent.FromKinds(fileCfg.Monitor.Agents, detector)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("load %s: monitor.agents: %w", path, err)
+	}
+	cfg.Agents = agents
+	if cfg.Policy, err = policy.New(fileCfg.Policy); err != nil {
+		return cfg, nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	pricingPath := config.PricingPath()
+	prices, err := config.LoadPricing(pricingPath)
+	if err == nil {
+		_, err = usage.NewPriceTable(prices)
+	}
+	if err != nil {
+		return cfg, nil, fmt.Errorf("load %s: %w", pricingPath, err)
+	}
+	// Prices in config.yaml take precedence over pricing.yaml
+	if cfg.Prices, err = usage.NewPriceTable(append(fileCfg.Pricing, prices...)); err != nil {
+		return cfg, nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	if cfg.Plan, err = usage.NewPlanLimits(fileCfg.Plan); err != nil {
+		return cfg, nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	if cfg.Budget, err = budget.New(fileCfg.Budget); err != nil {
+		return cfg, nil, fmt.Errorf("load %s: %w", path, err)
 	}
 	cfg.PollInterval = fileCfg.PollInterval()
+	cfg.ControlMode = fileCfg.Monitor.ControlMode
 	cfg.GameConfig = game.EngineConfig{
 		APMWindowSeconds:          fileCfg.APM.WindowSeconds,
 		StreakTimeoutSeconds:      fileCfg.Streak.TimeoutSeconds,
@@ -56,7 +111,7 @@ func LoadConfig() (Config, *config.Config) {
 		PointsPomodoroComplete:    fileCfg.Scoring.PointsPomodoroComplete,
 		DoubleTapThresholdMs:      fileCfg.UI.DoubleTapThresholdMs,
 	}
-	return cfg, fileCfg
+	return cfg, fileCfg, nil
 }
 
 // App is the main application
@@ -67,6 +122,16 @@ type App struct {
 	monitor    *daemon.Monitor
 	engine     *game.Engine
 	wsMgr      *workspace.Manager
+	ctrl       *control.Controller
+
+	// headless is set when running as the daemon; attached is set when the
+	// TUI runs alongside a daemon that owns scoring and persistence
+	headless bool
+	attached bool
+	started  bool
+
+	// Urgent sessions the daemon left for the user to answer, and since when
+	awaitingAnswer map[string]time.Time
 }
 
 // New creates a new App
@@ -78,7 +143,21 @@ func New(cfg Config, fileCfg *config.Config) (*App, error) {
 	}
 
 	// Initialize monitor
-	monitor := daemon.NewMonitor(cfg.PollInterval, st)
+	mux := tmux.NewClient()
+	monitor := daemon.NewMonitor(cfg.PollInterval, st, mux)
+	monitor.SetControlMode(cfg.ControlMode)
+	if cfg.Detector != nil {
+		monitor.SetDetector(cfg.Detector)
+	}
+	if cfg.Agents != nil {
+		monitor.SetAgents(cfg.Agents)
+	}
+	if cfg.Prices != nil {
+		monitor.Usage().SetPrices(cfg.Prices)
+	}
+	monitor.SetPlanLimits(cfg.Plan)
+	cfg.Budget.SetLedger(st)
+	monitor.SetBudget(cfg.Budget)
 
 	// Initialize game engine
 	engine := game.NewEngine(cfg.GameConfig)
@@ -91,6 +170,22 @@ func New(cfg Config, fileCfg *config.Config) (*App, error) {
 			gameState.PomodoroState,
 			gameState.PomodoroRemaining,
 		)
+		// Lifetime XP is the daily scores before the loaded one's day
+		date := gameState.LastScoreDate
+		if date == "" {
+			date = time.Now().Format("2006-01-02")
+		}
+		if pastXP, err := st.GetScoreBefore(date); err == nil {
+			engine.LoadXP(pastXP)
+		}
+	}
+
+	if achievements, err := st.GetAchievements(); err == nil {
+		ids := make([]string, len(achievements))
+		for i, a := range achievements {
+			ids[i] = a.ID
+		}
+		engine.LoadAchievements(ids)
 	}
 
 	// Load control groups
@@ -104,6 +199,9 @@ func New(cfg Config, fileCfg *config.Config) (*App, error) {
 		wsMgr, _ = workspace.NewManager(&fileCfg.Workspace)
 	}
 
+	ctrl := control.New(monitor, mux, st, wsMgr, fileCfg)
+	ctrl.SetPolicy(cfg.Policy)
+
 	return &App{
 		config:     cfg,
 		fileConfig: fileCfg,
@@ -111,17 +209,55 @@ func New(cfg Config, fileCfg *config.Config) (*App, error) {
 		monitor:    monitor,
 		engine:     engine,
 		wsMgr:      wsMgr,
+		ctrl:       ctrl,
 	}, nil
 }
 
 // Run starts the application
 func (a *App) Run() error {
+	// Attach to a running daemon instead of competing with it: its monitor
+	// is the only one polling tmux and reading transcripts, and state
+	// changes go through it
+	if daemon.RunningPID(a.config.PIDPath) != 0 {
+		a.attached = true
+		client := api.NewClient(a.config.SocketPath)
+		client.SetTimeout(5 * time.Second)
+		a.monitor.SetReadOnly(true)
+		a.monitor.Follow(api.NewRemote(client))
+		a.ctrl.SetOwner(client)
+	}
+
 	// Start monitor
+	a.started = true
 	a.monitor.Start()
 	defer a.monitor.Stop()
 
+	// Serve the control API unless the daemon already does
+	if !a.attached {
+		server := api.NewServer(a.ctrl, a.monitor, a.engine)
+		if err := server.Start(a.config.SocketPath); err == nil {
+			defer func() { _ = server.Close() }()
+		}
+	}
+
+	// The daemon flushes daily stats with its saves; the dashboard does it here
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		tick := time.NewTicker(saveInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				a.flushDailyStats()
+			case <-done:
+				return
+			}
+		}
+	}()
+
 	// Create TUI model
-	model := tui.New(a.monitor, a.engine, a.store, a.fileConfig, a.wsMgr)
+	model := tui.New(a.monitor, a.engine, a.store, a.fileConfig, a.wsMgr, a.ctrl, a.attached)
 
 	// Run Bubbletea
 	p := tea.NewProgram(model, tea.WithAltScreen())
@@ -137,13 +273,65 @@ func (a *App) Run() error {
 	return nil
 }
 
+// Controller returns the session controller, for one-shot CLI commands
+func (a *App) Controller() *control.Controller {
+	return a.ctrl
+}
+
+// Usage returns the usage ingester, for reports from the CLI
+func (a *App) Usage() *usage.Ingester {
+	return a.monitor.Usage()
+}
+
+// Refresh polls sessions once without starting the monitor loop
+func (a *App) Refresh() {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-a.monitor.Events():
+			case <-done:
+				return
+			}
+		}
+	}()
+	a.monitor.Refresh()
+	close(done)
+}
+
+// ControlGroup returns the session assigned to a control group (0 means 10)
+func (a *App) ControlGroup(groupNum int) string {
+	if groupNum == 0 {
+		groupNum = 10
+	}
+	return a.engine.ControlGroups().Get(groupNum)
+}
+
 // Close cleans up resources
 func (a *App) Close() error {
-	a.saveState()
+	if a.started {
+		a.saveState()
+	}
 	return a.store.Close()
 }
 
 func (a *App) saveState() {
+	a.flushDailyStats()
+
+	// The daemon only owns the score; pomodoro and groups belong to the TUI
+	if a.headless {
+		score, lastScoreDate, _, _ := a.engine.State()
+		_ = a.store.UpdateScore(score, lastScoreDate)
+		return
+	}
+
+	// Keep the daemon's score rather than overwriting it with a stale copy
+	if a.attached {
+		if gameState, err := a.store.GetGameState(); err == nil {
+			a.engine.SyncScore(gameState.CurrentScore, gameState.LastScoreDate)
+		}
+	}
+
 	score, lastScoreDate, pomodoroState, pomodoroRemaining := a.engine.State()
 
 	now := time.Now()
@@ -160,3 +348,18 @@ func (a *App) saveState() {
 		_ = a.store.SetControlGroup(groupNum, session)
 	}
 }
+
+// flushDailyStats adds what the engine counted since the last flush to the
+// daily statistics
+func (a *App) flushDailyStats() {
+	for _, d := range a.engine.TakeDailyStats() {
+		_ = a.store.AddDailyStats(&store.DailyStats{
+			Date:               d.Date,
+			TotalScore:         d.Score,
+			TotalActions:       d.Actions,
+			MaxStreak:          d.MaxStreak,
+			PomodorosCompleted: d.Pomodoros,
+			FlowTimeSeconds:    int(d.FlowTime / time.Second),
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/api"
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/policy"
+)
+
+// saveInterval is how often the headless daemon flushes score to the store
+const saveInterval = 10 * time.Second
+
+// RunDaemon runs the monitor and game engine without the TUI until
+// interrupted, persisting events and score so a TUI can attach later
+func (a *App) RunDaemon() error {
+	release, err := daemon.AcquirePIDFile(a.config.PIDPath)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	a.headless = true
+	a.started = true
+	// The dashboard runs the pomodoro and counts its work periods
+	a.engine.LeavePomodoro()
+
+	a.monitor.Start()
+	defer a.monitor.Stop()
+
+	server := api.NewServer(a.ctrl, a.monitor, a.engine)
+	if err := server.Start(a.config.SocketPath); err != nil {
+		return err
+	}
+	defer func() { _ = server.Close() }()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	save := time.NewTicker(saveInterval)
+	defer save.Stop()
+
+	a.logActivity("", "daemon", fmt.Sprintf("Daemon started (pid %d)", os.Getpid()))
+
+	for {
+		select {
+		case event := <-a.monitor.Events():
+			a.handleEvent(event)
+		case <-tick.C:
+			a.engine.Tick()
+			a.unlockAchievements()
+			a.logLevelUp()
+		case <-save.C:
+			a.saveState()
+		case <-sigCh:
+			a.logActivity("", "daemon", "Daemon stopped")
+			return nil
+		}
+	}
+}
+
+// handleEvent applies a monitor event to the engine and store.
+// Mirrors tui.Model.handleSessionEvent for when no dashboard is open.
+func (a *App) handleEvent(event daemon.Event) {
+	switch event.Type {
+	case daemon.EventSessionDiscovered:
+		_ = a.store.CreateSession(event.Session)
+		a.logActivity(event.Session, "discovered", "Session discovered")
+
+	case daemon.EventSessionClosed:
+		a.engine.RemoveSession(event.Session)
+		if err := a.ctrl.CleanupWorkspace(event.Session); err != nil {
+			a.logActivity(event.Session, "error", err.Error())
+		}
+		_ = a.store.DeleteSession(event.Session)
+		delete(a.awaitingAnswer, event.Session)
+		a.logActivity(event.Session, "closed", "Session closed")
+
+	case daemon.EventStateChanged:
+		isActive := event.State == claude.StateThinking || event.State == claude.StateActive
+		a.engine.SetSessionActivity(event.Session, isActive)
+		a.engine.SetSessionThinking(event.Session, event.State == claude.StateThinking)
+		_ = a.store.UpdateSessionLastSeen(event.Session)
+		if since, ok := a.awaitingAnswer[event.Session]; ok && event.State != claude.StateUrgent {
+			delete(a.awaitingAnswer, event.Session)
+			points := a.engine.RecordUrgentHandled(time.Since(since))
+			a.logActivity(event.Session, "urgent_handled", fmt.Sprintf("Urgent handled (+%d)", points))
+		}
+
+	case daemon.EventTaskCompleted:
+		points := a.engine.RecordTaskComplete()
+		a.logActivity(event.Session, "task_completed", fmt.Sprintf("Task completed (+%d)", points))
+
+	case daemon.EventUrgent:
+		_ = a.store.UpdateSessionLastSeen(event.Session)
+		if event.Pattern != "" {
+			a.logActivity(event.Session, "urgent", fmt.Sprintf("URGENT [%s]: %s", event.Pattern, event.Message))
+		} else {
+			a.logActivity(event.Session, "urgent", fmt.Sprintf("URGENT: %s", event.Message))
+		}
+		a.applyPolicy(event.Session)
+
+	case daemon.EventBudget:
+		a.logActivity(event.Session, "budget", event.Message)
+
+	case daemon.EventCompaction:
+		a.logActivity(event.Session, "compaction", event.Message)
+	}
+}
+
+// applyPolicy runs the auto-approval policy on an urgent session. Prompts it
+// doesn't answer earn PointsUrgentHandled once the user answers them.
+// Mirrors tui.Model.applyPolicy.
+func (a *App) applyPolicy(session string) {
+	decision, perm, err := a.ctrl.ApplyPolicy(session)
+	if err != nil {
+		a.logActivity(session, "error", err.Error())
+	}
+	if err == nil && perm != nil && decision.Outcome != policy.Ask && !decision.DryRun {
+		return
+	}
+	if a.awaitingAnswer == nil {
+		a.awaitingAnswer = make(map[string]time.Time)
+	}
+	a.awaitingAnswer[session] = time.Now()
+}
+
+// unlockAchievements saves the achievements the engine unlocked and logs
+// the ones no other process got to first
+func (a *App) unlockAchievements() {
+	for _, ach := range a.engine.TakeUnlocked() {
+		if isNew, err := a.store.UnlockAchievement(ach.ID, ach.Name, ach.Description); err == nil && isNew {
+			a.logActivity("", "achievement", fmt.Sprintf("🏆 Achievement unlocked: %s — %s", ach.Name, ach.Description))
+		}
+	}
+}
+
+// logLevelUp logs the level the engine's XP reached, if any
+func (a *App) logLevelUp() {
+	l, ok := a.engine.TakeLevelUp()
+	switch {
+	case !ok:
+	case l.Promoted():
+		a.logActivity("", "level_up", fmt.Sprintf("⭐ Level %d — promoted to %s", l.Number, l.Rank.Name))
+	default:
+		a.logActivity("", "level_up", fmt.Sprintf("⭐ Level %d (%s)", l.Number, l.Rank.Name))
+	}
+}
+
+func (a *App) logActivity(session, eventType, message string) {
+	_ = a.store.AddActivityLog(session, eventType, message)
+}
@@ -0,0 +1,256 @@
+// Package budget checks spending against daily, weekly, per-session and
+// per-repo limits, reporting each alert threshold once per period as it is
+// crossed.
+package budget
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+)
+
+// Scope is what a limit applies to
+type Scope string
+
+const (
+	Daily   Scope = "daily"
+	Weekly  Scope = "weekly"
+	Session Scope = "session"
+	Repo    Scope = "repo"
+)
+
+// defaultAlerts are the shares of a limit that warn when none are configured
+var defaultAlerts = []float64{0.5, 0.8, 0.9}
+
+// Target is the session whose cost grew
+type Target struct {
+	Session         string // tmux session
+	WorkingDir      string
+	ClaudeSessionID string
+}
+
+// Spend is what has been spent in each scope, including the latest growth
+type Spend struct {
+	Daily   float64
+	Weekly  float64
+	Session float64
+	Repo    float64 // today, in the target's repo
+}
+
+// Alert is a threshold crossed by spending
+type Alert struct {
+	Scope     Scope
+	Name      string // the repo pattern, for repo budgets
+	Spent     float64
+	Limit     float64
+	Threshold float64 // share of the limit crossed, 1 at the limit
+	Stop      bool    // the limit was reached and hard_stop is set
+}
+
+// String describes the alert for the activity log
+func (a Alert) String() string {
+	scope := string(a.Scope)
+	if a.Name != "" {
+		scope += " " + a.Name
+	}
+	if a.Threshold >= 1 {
+		return fmt.Sprintf("%s budget reached: $%.2f of $%.2f", scope, a.Spent, a.Limit)
+	}
+	return fmt.Sprintf("%s budget %.0f%% used: $%.2f of $%.2f", scope, a.Threshold*100, a.Spent, a.Limit)
+}
+
+type repoLimit struct {
+	name    string
+	pattern *regexp.Regexp
+	daily   float64
+}
+
+// Ledger remembers the thresholds reported, across restarts and processes
+type Ledger interface {
+	// ReportBudgetThreshold records threshold for a limit and period,
+	// returning false if it or a higher one was already reported
+	ReportBudgetThreshold(key string, threshold float64) (bool, error)
+}
+
+// Budget holds the configured limits and which thresholds were reported
+type Budget struct {
+	daily    float64
+	weekly   float64
+	session  float64
+	repos    []repoLimit
+	alerts   []float64
+	hardStop bool
+
+	mu       sync.Mutex
+	reported map[string]float64 // highest threshold reported per period
+	ledger   Ledger
+}
+
+// New validates the budgets in cfg. It returns nil, which never alerts, when
+// no limits are configured.
+func New(cfg config.BudgetConfig) (*Budget, error) {
+	if cfg.Daily < 0 || cfg.Weekly < 0 || cfg.Session < 0 {
+		return nil, fmt.Errorf("budget: limits can't be negative")
+	}
+	b := &Budget{
+		daily:    cfg.Daily,
+		weekly:   cfg.Weekly,
+		session:  cfg.Session,
+		alerts:   cfg.Alerts,
+		hardStop: cfg.HardStop,
+		reported: make(map[string]float64),
+	}
+	for i, rc := range cfg.Repos {
+		field := fmt.Sprintf("budget.repos[%d]", i)
+		if rc.Repo == "" {
+			return nil, fmt.Errorf("%s: repo is required", field)
+		}
+		if rc.Daily <= 0 {
+			return nil, fmt.Errorf("%s (%s): daily must be positive", field, rc.Repo)
+		}
+		b.repos = append(b.repos, repoLimit{name: rc.Repo, pattern: globRegexp(expandHome(rc.Repo)), daily: rc.Daily})
+	}
+	if b.daily == 0 && b.weekly == 0 && b.session == 0 && len(b.repos) == 0 {
+		return nil, nil
+	}
+
+	if len(b.alerts) == 0 {
+		b.alerts = defaultAlerts
+	}
+	for _, a := range b.alerts {
+		if a <= 0 || a >= 1 {
+			return nil, fmt.Errorf("budget.alerts: shares must be between 0 and 1, got %v", a)
+		}
+	}
+	b.alerts = append(append([]float64(nil), b.alerts...), 1)
+	sort.Float64s(b.alerts)
+	return b, nil
+}
+
+// SetLedger makes thresholds already in ledger count as reported, so each
+// is reported once per period however often the process restarts
+func (b *Budget) SetLedger(l Ledger) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ledger = l
+}
+
+// HasRepo reports whether a repo budget applies to sessions in workingDir,
+// so callers only look up repo spending when needed
+func (b *Budget) HasRepo(workingDir string) bool {
+	_, ok := b.repo(workingDir)
+	return ok
+}
+
+func (b *Budget) repo(workingDir string) (repoLimit, bool) {
+	if b == nil || workingDir == "" {
+		return repoLimit{}, false
+	}
+	for _, r := range b.repos {
+		if r.pattern.MatchString(workingDir) || r.pattern.MatchString(filepath.Base(workingDir)) {
+			return r, true
+		}
+	}
+	return repoLimit{}, false
+}
+
+// Check returns the thresholds spend has crossed since the last check, at
+// most one per limit: the highest
+func (b *Budget) Check(now time.Time, target Target, spend Spend) []Alert {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	day := now.Format("2006-01-02")
+	var alerts []Alert
+	check := func(scope Scope, name, period string, spent, limit float64) {
+		if limit <= 0 {
+			return
+		}
+		key := string(scope) + ":" + name + ":" + period
+		threshold := 0.0
+		for _, a := range b.alerts {
+			if spent >= a*limit {
+				threshold = a
+			}
+		}
+		if threshold <= b.reported[key] {
+			return
+		}
+		b.reported[key] = threshold
+		// Without the ledger, an alert twice beats none
+		if b.ledger != nil {
+			if first, err := b.ledger.ReportBudgetThreshold(key, threshold); err == nil && !first {
+				return
+			}
+		}
+		alerts = append(alerts, Alert{
+			Scope:     scope,
+			Name:      name,
+			Spent:     spent,
+			Limit:     limit,
+			Threshold: threshold,
+			Stop:      threshold >= 1 && b.hardStop,
+		})
+	}
+
+	check(Daily, "", day, spend.Daily, b.daily)
+	check(Weekly, "", WeekStart(now).Format("2006-01-02"), spend.Weekly, b.weekly)
+	session := target.ClaudeSessionID
+	if session == "" {
+		session = target.Session
+	}
+	check(Session, "", session, spend.Session, b.session)
+	if r, ok := b.repo(target.WorkingDir); ok {
+		check(Repo, r.name, day, spend.Repo, r.daily)
+	}
+	return alerts
+}
+
+// WeekStart returns midnight on the Monday starting the week holding t
+func WeekStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	return midnight.AddDate(0, 0, -int((t.Weekday()+6)%7))
+}
+
+// globRegexp compiles a glob where * matches anything and ? matches one
+// character
+func globRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,179 @@
+package budget
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	b, err := New(config.BudgetConfig{Daily: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 6, 3, 10, 0, 0, 0, time.UTC)
+	target := Target{Session: "api"}
+
+	steps := []struct {
+		spent float64
+		want  float64 // threshold reported, 0 for none
+	}{
+		{5, 0},
+		{10, 0.5},
+		{11, 0},   // already reported
+		{19, 0.9}, // skips 0.8: only the highest is reported
+		{25, 1},   // reached
+		{30, 0},   // stays reached
+	}
+	for _, s := range steps {
+		alerts := b.Check(now, target, Spend{Daily: s.spent})
+		var got float64
+		if len(alerts) > 1 {
+			t.Fatalf("spent $%v: %d alerts, want at most one", s.spent, len(alerts))
+		}
+		if len(alerts) == 1 {
+			got = alerts[0].Threshold
+			if alerts[0].Scope != Daily || alerts[0].Stop {
+				t.Errorf("spent $%v: alert = %+v, want a daily alert without stop", s.spent, alerts[0])
+			}
+		}
+		if got != s.want {
+			t.Errorf("spent $%v: threshold = %v, want %v", s.spent, got, s.want)
+		}
+	}
+
+	// A new day starts over
+	alerts := b.Check(now.AddDate(0, 0, 1), target, Spend{Daily: 12})
+	if len(alerts) != 1 || alerts[0].Threshold != 0.5 {
+		t.Errorf("next day alerts = %+v, want 50%%", alerts)
+	}
+}
+
+func TestCheckScopes(t *testing.T) {
+	b, err := New(config.BudgetConfig{
+		Weekly:   100,
+		Session:  5,
+		Repos:    []config.RepoBudget{{Repo: "api", Daily: 10}},
+		Alerts:   []float64{0.8},
+		HardStop: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 6, 3, 10, 0, 0, 0, time.UTC) // Wednesday
+
+	api := Target{Session: "api-1", WorkingDir: "/src/api", ClaudeSessionID: "s1"}
+	alerts := b.Check(now, api, Spend{Weekly: 85, Session: 5, Repo: 9})
+	want := []string{
+		"weekly budget 80% used: $85.00 of $100.00",
+		"session budget reached: $5.00 of $5.00",
+		"repo api budget 80% used: $9.00 of $10.00",
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("alerts = %+v, want %d", alerts, len(want))
+	}
+	for i, w := range want {
+		if alerts[i].String() != w {
+			t.Errorf("alert %d = %q, want %q", i, alerts[i].String(), w)
+		}
+	}
+	if !alerts[1].Stop || alerts[0].Stop {
+		t.Errorf("Stop = %v, %v, want only the reached session budget to stop", alerts[0].Stop, alerts[1].Stop)
+	}
+
+	// Another conversation has its own session budget; the week is shared
+	web := Target{Session: "web-1", WorkingDir: "/src/web", ClaudeSessionID: "s2"}
+	alerts = b.Check(now.AddDate(0, 0, 4), web, Spend{Weekly: 90, Session: 4, Repo: 50})
+	if len(alerts) != 1 || alerts[0].Scope != Session || alerts[0].Threshold != 0.8 {
+		t.Errorf("alerts = %+v, want only s2's session budget", alerts)
+	}
+	if b.HasRepo("/src/web") || !b.HasRepo("/src/api") {
+		t.Error("HasRepo should match the api repo only")
+	}
+}
+
+func TestNew(t *testing.T) {
+	b, err := New(config.BudgetConfig{HardStop: true})
+	if b != nil || err != nil {
+		t.Errorf("New() without limits = %v, %v, want nil, nil", b, err)
+	}
+	if alerts := b.Check(time.Now(), Target{}, Spend{Daily: 1e6}); alerts != nil {
+		t.Errorf("nil budget alerted: %+v", alerts)
+	}
+
+	invalid := []config.BudgetConfig{
+		{Daily: -1},
+		{Daily: 10, Alerts: []float64{1.5}},
+		{Daily: 10, Alerts: []float64{0}},
+		{Repos: []config.RepoBudget{{Daily: 5}}},
+		{Repos: []config.RepoBudget{{Repo: "api"}}},
+	}
+	for _, cfg := range invalid {
+		t.Run(fmt.Sprintf("%+v", cfg), func(t *testing.T) {
+			if _, err := New(cfg); err == nil {
+				t.Error("New() succeeded, want error")
+			}
+		})
+	}
+}
+
+// memoryLedger is a Ledger shared by Budgets standing for restarts
+type memoryLedger map[string]float64
+
+func (l memoryLedger) ReportBudgetThreshold(key string, threshold float64) (bool, error) {
+	if threshold <= l[key] {
+		return false, nil
+	}
+	l[key] = threshold
+	return true, nil
+}
+
+func TestCheckLedger(t *testing.T) {
+	ledger := memoryLedger{}
+	day := time.Date(2026, 6, 3, 10, 0, 0, 0, time.UTC)
+	steps := []struct {
+		now   time.Time
+		spent float64
+		want  float64 // threshold reported, 0 for none
+	}{
+		{day, 25, 1},
+		{day, 26, 0}, // restarted: already reported today
+		{day.Add(time.Hour), 30, 0},
+		{day.AddDate(0, 0, 1), 10, 0.5}, // a new day
+	}
+	for _, s := range steps {
+		// A new Budget per check, as after a restart
+		b, err := New(config.BudgetConfig{Daily: 20, HardStop: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.SetLedger(ledger)
+		var got float64
+		if alerts := b.Check(s.now, Target{Session: "api"}, Spend{Daily: s.spent}); len(alerts) == 1 {
+			got = alerts[0].Threshold
+		}
+		if got != s.want {
+			t.Errorf("%s, spent $%v: reported %v, want %v", s.now.Format("Jan 2 15:04"), s.spent, got, s.want)
+		}
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	tests := []struct {
+		day  int // June 2026
+		want int
+	}{
+		{1, 1}, // Monday
+		{3, 1},
+		{7, 1}, // Sunday
+		{8, 8},
+	}
+	for _, tt := range tests {
+		got := WeekStart(time.Date(2026, 6, tt.day, 15, 30, 0, 0, time.UTC))
+		if want := time.Date(2026, 6, tt.want, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+			t.Errorf("WeekStart(June %d) = %v, want %v", tt.day, got, want)
+		}
+	}
+}
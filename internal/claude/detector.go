@@ -36,6 +36,8 @@ func (s SessionState) String() string {
 // SessionInfo contains parsed information from Claude output
 type SessionInfo struct {
 	State        SessionState
+	Pattern      string             // name of the pattern that matched State
+	Permission   *PermissionRequest // the dialog behind an URGENT state, if parsed
 	Tokens       int
 	ThinkingTime time.Duration
 	LastLine     string
@@ -43,66 +45,29 @@ type SessionInfo struct {
 
 // Detector detects Claude session states from terminal output
 type Detector struct {
-	urgentPatterns       []*regexp.Regexp
-	thinkingPatterns     []*regexp.Regexp
-	idlePatterns         []*regexp.Regexp
-	promptActivePatterns []*regexp.Regexp
-	claudePatterns       []*regexp.Regexp
+	urgentPatterns       []Pattern
+	thinkingPatterns     []Pattern
+	idlePatterns         []Pattern
+	promptActivePatterns []Pattern
+	claudePatterns       []Pattern
 	tokenPattern         *regexp.Regexp
 	thinkingPattern      *regexp.Regexp
 	ansiPattern          *regexp.Regexp
 	modePattern          *regexp.Regexp
 }
 
-// NewDetector creates a new Claude state detector
+// NewDetector creates a new Claude state detector with the built-in patterns
 func NewDetector() *Detector {
 	return &Detector{
-		urgentPatterns: []*regexp.Regexp{
-			regexp.MustCompile(`(?i)\[Y/n\]`),
-			regexp.MustCompile(`(?i)\[y/N\]`),
-			regexp.MustCompile(`(?i)Permission requested`),
-			regexp.MustCompile(`(?i)Allow\?`),
-			regexp.MustCompile(`(?i)Proceed\?`),
-			regexp.MustCompile(`(?i)Are you sure`),
-			regexp.MustCompile(`(?i)Continue\?`),
-			regexp.MustCompile(`(?i)\(yes/no\)`),
-			regexp.MustCompile(`(?i)Press any key`),
-			regexp.MustCompile(`(?i)Hit enter`),
-			regexp.MustCompile(`(?i)chat about this`),
-			regexp.MustCompile(`(?i)skip interview and plan`),
-			regexp.MustCompile(`(?i)Ready to submit your answers`),
-		},
-		thinkingPatterns: []*regexp.Regexp{
-			regexp.MustCompile(`(?i)thinking\.{3}`),
-			regexp.MustCompile(`(?i)thinking…`),
-			regexp.MustCompile(`(?i)reasoning`),
-			regexp.MustCompile(`⠋|⠙|⠹|⠸|⠼|⠴|⠦|⠧|⠇|⠏`),
-			regexp.MustCompile(`Working\.\.\.`),
-			regexp.MustCompile(`Processing\.\.\.`),
-			regexp.MustCompile(`\(esc to cancel\)`),
-			regexp.MustCompile(`\(ctrl.* to interrupt\)`),
-			regexp.MustCompile(`thought for \d+`),
-			regexp.MustCompile(`thinking`),
-		},
-		idlePatterns: []*regexp.Regexp{
-			regexp.MustCompile(`(?m)❯\s*$`),
-			regexp.MustCompile(`(?m)>\s*$`),
-			regexp.MustCompile(`(?m)claude>\s*$`),
-			regexp.MustCompile(`↵ send`),
-			regexp.MustCompile(`⏵⏵`),
-			regexp.MustCompile(`▐▛███▜▌`),
-		},
-		promptActivePatterns: []*regexp.Regexp{},
-		claudePatterns: []*regexp.Regexp{
-			regexp.MustCompile(`Claude Code`),
-			regexp.MustCompile(`claude>`),
-			regexp.MustCompile(`❯`),
-			regexp.MustCompile(`ing\.\.\. \(ctrl`),
-		},
-		tokenPattern:    regexp.MustCompile(`↓\s*([\d,.]+)k?\s*tokens?`),
-		thinkingPattern: regexp.MustCompile(`Thinking[^(]*\((\d+)m?\s*(\d+)?s?\)`),
-		ansiPattern:     regexp.MustCompile(`\x1b\[[0-9;]*m`),
-		modePattern:     regexp.MustCompile(`(?i)(plan|code|auto|accept[\s-]?edits?)\s+(?:mode\s+)?on\s+\(shift\+tab`),
+		urgentPatterns:       mustCompile(defaultUrgentPatterns),
+		thinkingPatterns:     mustCompile(defaultThinkingPatterns),
+		idlePatterns:         mustCompile(defaultIdlePatterns),
+		promptActivePatterns: []Pattern{},
+		claudePatterns:       mustCompile(defaultClaudePatterns),
+		tokenPattern:         regexp.MustCompile(defaultTokenRegex),
+		thinkingPattern:      regexp.MustCompile(defaultThinkingTimeRegex),
+		ansiPattern:          regexp.MustCompile(`\x1b\[[0-9;]*m`),
+		modePattern:          regexp.MustCompile(defaultModeRegex),
 	}
 }
 
@@ -110,7 +75,7 @@ func NewDetector() *Detector {
 func (d *Detector) IsClaudeSession(content string) bool {
 	content = d.stripANSI(content)
 	for _, pattern := range d.claudePatterns {
-		if pattern.MatchString(content) {
+		if pattern.Re.MatchString(content) {
 			return true
 		}
 	}
@@ -119,32 +84,42 @@ func (d *Detector) IsClaudeSession(content string) bool {
 
 // DetectState determines the current state of a Claude session
 func (d *Detector) DetectState(content string, lastContent string, lastCapture time.Time) SessionState {
+	state, _ := d.Classify(content)
+	return state
+}
+
+// Classify determines the current state and returns the name of the pattern
+// that decided it ("" for the ACTIVE fallback)
+func (d *Detector) Classify(content string) (SessionState, string) {
 	content = d.stripANSI(content)
 	lines := d.getLastLines(content, 20)
 
 	// Check URGENT first (highest priority)
-	for _, pattern := range d.urgentPatterns {
-		if pattern.MatchString(lines) {
-			return StateUrgent
-		}
+	if name := firstMatch(d.urgentPatterns, lines); name != "" {
+		return StateUrgent, name
 	}
 
 	// Check THINKING
-	for _, pattern := range d.thinkingPatterns {
-		if pattern.MatchString(lines) {
-			return StateThinking
-		}
+	if name := firstMatch(d.thinkingPatterns, lines); name != "" {
+		return StateThinking, name
 	}
 
 	// Check IDLE (prompt with "↵ send" visible)
-	for _, pattern := range d.idlePatterns {
-		if pattern.MatchString(lines) {
-			return StateIdle
-		}
+	if name := firstMatch(d.idlePatterns, lines); name != "" {
+		return StateIdle, name
 	}
 
 	// Default: ACTIVE
-	return StateActive
+	return StateActive, ""
+}
+
+func firstMatch(patterns []Pattern, s string) string {
+	for _, pattern := range patterns {
+		if pattern.Re.MatchString(s) {
+			return pattern.Name
+		}
+	}
+	return ""
 }
 
 // ParseInfo extracts additional information from Claude output
@@ -152,10 +127,15 @@ func (d *Detector) ParseInfo(content string) SessionInfo {
 	content = d.stripANSI(content)
 	lines := d.getLastLines(content, 20)
 
+	state, pattern := d.Classify(content)
 	info := SessionInfo{
-		State:    d.DetectState(content, "", time.Time{}),
+		State:    state,
+		Pattern:  pattern,
 		LastLine: d.getLastNonEmptyLine(content),
 	}
+	if state == StateUrgent {
+		info.Permission = d.ParsePermission(content)
+	}
 
 	// Parse tokens
 	if matches := d.tokenPattern.FindStringSubmatch(content); len(matches) >= 2 {
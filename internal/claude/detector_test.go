@@ -53,6 +53,10 @@ func TestDetectState(t *testing.T) {
 		{"active shortcuts hint", "? for shortcuts", StateActive},
 		{"active accept edits", "accept edits on (shift+tab to cycle)", StateActive},
 		{"active plan mode", "plan mode on (shift+tab to cycle)", StateActive},
+		{"do you want dialog", editDialog, StateUrgent},
+		{"do you want boxed dialog", "│ Do you want to create a.go?   │\n│ ❯ 1. Yes                     │\n", StateUrgent},
+		{"do you want in text", "⏺ Fixed. Do you want to add a regression test too?\n\n❯ ", StateIdle},
+		{"do you want with a list", "⏺ Do you want to:\n  1. add tests?\n\n❯ ", StateIdle},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,172 @@
+package claude
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+)
+
+// Pattern is a named regular expression that identifies a session state
+type Pattern struct {
+	Name string
+	Re   *regexp.Regexp
+}
+
+type patternDef struct {
+	name  string
+	regex string
+}
+
+var defaultUrgentPatterns = []patternDef{
+	{"yes-no-brackets", `(?i)\[y/n\]`},
+	{"permission-requested", `(?i)Permission requested`},
+	{"allow", `(?i)Allow\?`},
+	{"proceed", `(?i)Proceed\?`},
+	{"are-you-sure", `(?i)Are you sure`},
+	{"continue", `(?i)Continue\?`},
+	{"yes-no", `(?i)\(yes/no\)`},
+	{"press-any-key", `(?i)Press any key`},
+	{"hit-enter", `(?i)Hit enter`},
+	{"chat-about-this", `(?i)chat about this`},
+	{"skip-interview", `(?i)skip interview and plan`},
+	{"submit-answers", `(?i)Ready to submit your answers`},
+	// Only as a dialog's question: numbered options follow, unlike in text
+	{"do-you-want", `Do you want to [^\n]*\?[^\n]*\n[│ \t]*(?:❯[ \t]*)?1\. `},
+}
+
+var defaultThinkingPatterns = []patternDef{
+	{"thinking-dots", `(?i)thinking\.{3}`},
+	{"thinking-ellipsis", `(?i)thinking…`},
+	{"reasoning", `(?i)reasoning`},
+	{"spinner", `⠋|⠙|⠹|⠸|⠼|⠴|⠦|⠧|⠇|⠏`},
+	{"working", `Working\.\.\.`},
+	{"processing", `Processing\.\.\.`},
+	{"esc-to-cancel", `\(esc to cancel\)`},
+	{"ctrl-to-interrupt", `\(ctrl.* to interrupt\)`},
+	{"thought-for", `thought for \d+`},
+	{"thinking", `thinking`},
+}
+
+var defaultIdlePatterns = []patternDef{
+	{"chevron-prompt", `(?m)❯\s*$`},
+	{"angle-prompt", `(?m)>\s*$`},
+	{"claude-prompt", `(?m)claude>\s*$`},
+	{"send-hint", `↵ send`},
+	{"mode-indicator", `⏵⏵`},
+	{"logo", `▐▛███▜▌`},
+}
+
+var defaultClaudePatterns = []patternDef{
+	{"title", `Claude Code`},
+	{"claude-prompt", `claude>`},
+	{"chevron", `❯`},
+	{"interrupt-hint", `ing\.\.\. \(ctrl`},
+}
+
+const (
+	defaultTokenRegex        = `↓\s*([\d,.]+)k?\s*tokens?`
+	defaultThinkingTimeRegex = `Thinking[^(]*\((\d+)m?\s*(\d+)?s?\)`
+	defaultModeRegex         = `(?i)(plan|code|auto|accept[\s-]?edits?)\s+(?:mode\s+)?on\s+\(shift\+tab`
+)
+
+func mustCompile(defs []patternDef) []Pattern {
+	patterns := make([]Pattern, len(defs))
+	for i, def := range defs {
+		patterns[i] = Pattern{Name: def.name, Re: regexp.MustCompile(def.regex)}
+	}
+	return patterns
+}
+
+// NewDetectorFromConfig creates a detector from the built-in patterns with
+// the user's additions, overrides and removals applied
+func NewDetectorFromConfig(cfg config.DetectorConfig) (*Detector, error) {
+	d := NewDetector()
+
+	var err error
+	if d.urgentPatterns, err = applyPatterns("urgent", d.urgentPatterns, cfg.Urgent); err != nil {
+		return nil, err
+	}
+	if d.thinkingPatterns, err = applyPatterns("thinking", d.thinkingPatterns, cfg.Thinking); err != nil {
+		return nil, err
+	}
+	if d.idlePatterns, err = applyPatterns("idle", d.idlePatterns, cfg.Idle); err != nil {
+		return nil, err
+	}
+	if d.claudePatterns, err = applyPatterns("claude", d.claudePatterns, cfg.Claude); err != nil {
+		return nil, err
+	}
+
+	// Each of these is read through its capture groups
+	if cfg.TokenRegex != "" {
+		if d.tokenPattern, err = compileGroups("token_regex", cfg.TokenRegex, 1); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.ThinkingTimeRegex != "" {
+		if d.thinkingPattern, err = compileGroups("thinking_time_regex", cfg.ThinkingTimeRegex, 1); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.ModeRegex != "" {
+		if d.modePattern, err = compileGroups("mode_regex", cfg.ModeRegex, 1); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// applyPatterns overlays configured patterns on a built-in list: a known name
+// is replaced in place (or removed when disabled), a new name is appended
+func applyPatterns(kind string, base []Pattern, overrides []config.PatternConfig) ([]Pattern, error) {
+	patterns := append([]Pattern(nil), base...)
+
+	for i, o := range overrides {
+		field := fmt.Sprintf("detector.%s[%d]", kind, i)
+		if o.Name == "" {
+			return nil, fmt.Errorf("%s: name is required", field)
+		}
+
+		idx := -1
+		for j, p := range patterns {
+			if p.Name == o.Name {
+				idx = j
+				break
+			}
+		}
+
+		if o.Disabled {
+			if idx < 0 {
+				return nil, fmt.Errorf("%s: cannot disable unknown %s pattern %q", field, kind, o.Name)
+			}
+			patterns = append(patterns[:idx], patterns[idx+1:]...)
+			continue
+		}
+
+		if o.Regex == "" {
+			return nil, fmt.Errorf("%s (%s): regex is required", field, o.Name)
+		}
+		re, err := regexp.Compile(o.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%s (%s): %w", field, o.Name, err)
+		}
+
+		if idx >= 0 {
+			patterns[idx].Re = re
+		} else {
+			patterns = append(patterns, Pattern{Name: o.Name, Re: re})
+		}
+	}
+	return patterns, nil
+}
+
+func compileGroups(field, regex string, groups int) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, fmt.Errorf("detector.%s: %w", field, err)
+	}
+	if re.NumSubexp() < groups {
+		return nil, fmt.Errorf("detector.%s: needs at least %d capture group(s)", field, groups)
+	}
+	return re, nil
+}
@@ -0,0 +1,108 @@
+package claude
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+)
+
+func TestNewDetectorFromConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.DetectorConfig
+		content   string
+		wantState SessionState
+		wantName  string
+	}{
+		{
+			name:      "defaults",
+			content:   "Do you want to proceed? [y/N]",
+			wantState: StateUrgent,
+			wantName:  "yes-no-brackets",
+		},
+		{
+			name: "add pattern",
+			cfg: config.DetectorConfig{Urgent: []config.PatternConfig{
+				{Name: "deploy-confirm", Regex: `(?i)deploy to production\?`},
+			}},
+			content:   "Deploy to production?",
+			wantState: StateUrgent,
+			wantName:  "deploy-confirm",
+		},
+		{
+			name: "override pattern",
+			cfg: config.DetectorConfig{Idle: []config.PatternConfig{
+				{Name: "chevron-prompt", Regex: `(?m)»\s*$`},
+			}},
+			content:   "done\n» ",
+			wantState: StateIdle,
+			wantName:  "chevron-prompt",
+		},
+		{
+			name: "disable pattern",
+			cfg: config.DetectorConfig{Thinking: []config.PatternConfig{
+				{Name: "reasoning", Disabled: true},
+			}},
+			content:   "the reasoning behind this change\n❯ ",
+			wantState: StateIdle,
+			wantName:  "chevron-prompt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDetectorFromConfig(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewDetectorFromConfig() error = %v", err)
+			}
+			state, name := d.Classify(tt.content)
+			if state != tt.wantState || name != tt.wantName {
+				t.Errorf("Classify() = %s, %q, want %s, %q", state, name, tt.wantState, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestNewDetectorFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.DetectorConfig
+		wantErr string
+	}{
+		{
+			name:    "invalid regex",
+			cfg:     config.DetectorConfig{Urgent: []config.PatternConfig{{Name: "broken", Regex: `(`}}},
+			wantErr: "detector.urgent[0] (broken)",
+		},
+		{
+			name:    "missing name",
+			cfg:     config.DetectorConfig{Idle: []config.PatternConfig{{Regex: `>`}}},
+			wantErr: "detector.idle[0]: name is required",
+		},
+		{
+			name:    "missing regex",
+			cfg:     config.DetectorConfig{Thinking: []config.PatternConfig{{Name: "busy"}}},
+			wantErr: "regex is required",
+		},
+		{
+			name:    "disable unknown",
+			cfg:     config.DetectorConfig{Claude: []config.PatternConfig{{Name: "nope", Disabled: true}}},
+			wantErr: `unknown claude pattern "nope"`,
+		},
+		{
+			name:    "token regex without group",
+			cfg:     config.DetectorConfig{TokenRegex: `\d+ tokens`},
+			wantErr: "detector.token_regex: needs at least 1 capture group(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDetectorFromConfig(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewDetectorFromConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package claude
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Answer is a reply to a permission dialog
+type Answer int
+
+const (
+	AnswerYes    Answer = iota // allow once
+	AnswerAlways               // allow and don't ask again
+	AnswerNo                   // deny
+)
+
+func (a Answer) String() string {
+	switch a {
+	case AnswerYes:
+		return "yes"
+	case AnswerAlways:
+		return "always"
+	case AnswerNo:
+		return "no"
+	default:
+		return "unknown"
+	}
+}
+
+// PermissionOption is one numbered choice in a permission dialog
+type PermissionOption struct {
+	Number int
+	Label  string
+}
+
+// PermissionRequest is a permission dialog parsed from the screen
+type PermissionRequest struct {
+	Tool        string // Bash, Edit, Write, Read, WebFetch, WebSearch, or the MCP tool
+	Command     string // Bash command, its screen lines joined by newlines
+	Description string // what Claude says the command does, its last line
+	// Ambiguous is set when a Bash command's end couldn't be told from its
+	// description, so Command holds every line shown
+	Ambiguous bool
+	Path      string // file path, or URL for WebFetch
+	Question  string
+	Options   []PermissionOption
+}
+
+// Option returns the dialog's option for an answer
+func (p *PermissionRequest) Option(a Answer) (PermissionOption, bool) {
+	for _, opt := range p.Options {
+		label := strings.ToLower(opt.Label)
+		always := strings.Contains(label, "don't ask again") ||
+			strings.Contains(label, "allow all") ||
+			strings.Contains(label, "always")
+
+		switch a {
+		case AnswerYes:
+			if strings.HasPrefix(label, "yes") && !always {
+				return opt, true
+			}
+		case AnswerAlways:
+			if strings.HasPrefix(label, "yes") && always {
+				return opt, true
+			}
+		case AnswerNo:
+			if strings.HasPrefix(label, "no") {
+				return opt, true
+			}
+		}
+	}
+	return PermissionOption{}, false
+}
+
+// Summary describes the request in one line, e.g. "Bash: go test ./..."
+func (p *PermissionRequest) Summary() string {
+	switch {
+	case p.Command != "":
+		if first, _, multiline := strings.Cut(p.Command, "\n"); multiline {
+			return p.Tool + ": " + first + " …"
+		}
+		return p.Tool + ": " + p.Command
+	case p.Path != "":
+		return p.Tool + ": " + p.Path
+	default:
+		return p.Tool
+	}
+}
+
+// permissionTitles maps dialog titles to tool names
+var permissionTitles = map[string]string{
+	"Bash command": "Bash",
+	"Edit file":    "Edit",
+	"Create file":  "Write",
+	"Write file":   "Write",
+	"Read file":    "Read",
+	"Fetch":        "WebFetch",
+	"Web Search":   "WebSearch",
+	"Tool use":     "",
+}
+
+var (
+	permissionQuestion = regexp.MustCompile(`(?i)^do you want to .*\?$`)
+	permissionOption   = regexp.MustCompile(`^(?:❯\s*)?(\d+)\.\s+(.+)$`)
+	permissionCall     = regexp.MustCompile(`^([\w:.-]+)\((.*)\)`)
+	permissionFile     = regexp.MustCompile(`(?i)(?:edit to|create|write to|read) (.+)\?$`)
+)
+
+// ParsePermission extracts the permission dialog at the bottom of the
+// screen, or returns nil if none is showing
+func (d *Detector) ParsePermission(content string) *PermissionRequest {
+	raw := strings.Split(d.stripANSI(content), "\n")
+	lines := make([]string, len(raw))
+	for i, line := range raw {
+		lines[i] = cleanDialogLine(line)
+	}
+
+	question := -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		if permissionQuestion.MatchString(lines[i]) {
+			question = i
+			break
+		}
+	}
+	if question < 0 {
+		return nil
+	}
+
+	req := &PermissionRequest{Question: lines[question]}
+	for _, line := range lines[question+1:] {
+		m := permissionOption.FindStringSubmatch(line)
+		if m == nil {
+			if len(req.Options) > 0 {
+				break
+			}
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		req.Options = append(req.Options, PermissionOption{Number: n, Label: strings.TrimSpace(m[2])})
+	}
+	if len(req.Options) == 0 {
+		return nil
+	}
+
+	// The nearest title above the question says which tool is asking
+	title := -1
+	for i := question - 1; i >= 0 && i >= question-40; i-- {
+		if tool, ok := permissionTitles[lines[i]]; ok {
+			req.Tool = tool
+			title = i
+			break
+		}
+	}
+
+	var body []string
+	var bodyRaw []string // body's lines as drawn, for telling wrapped ones
+	separated := false   // a blank line comes before body's last line
+	if title >= 0 {
+		blank := false
+		for i, line := range lines[title+1 : question] {
+			if line == "" {
+				blank = len(body) > 0
+				continue
+			}
+			separated = blank
+			blank = false
+			body = append(body, line)
+			bodyRaw = append(bodyRaw, raw[title+1+i])
+		}
+	}
+
+	switch req.Tool {
+	case "Bash":
+		// The description is the line under the command, unless that line
+		// may be the command wrapping onto it: then every line is the
+		// command's, and the request is ambiguous
+		n := len(body)
+		switch {
+		case n == 0:
+		case n == 1:
+			req.Command = body[0]
+		case separated || (isDescription(body[n-1]) && !fillsWidth(bodyRaw[n-2], screenWidth(raw))):
+			req.Command = strings.Join(body[:n-1], "\n")
+			req.Description = body[n-1]
+		default:
+			req.Command = strings.Join(body, "\n")
+			req.Ambiguous = true
+		}
+	case "Edit", "Write", "Read", "WebFetch":
+		if len(body) > 0 {
+			req.Path = body[0]
+			if m := permissionCall.FindStringSubmatch(body[0]); m != nil {
+				req.Path = m[2]
+			}
+		}
+		if m := permissionFile.FindStringSubmatch(req.Question); m != nil && req.Path == "" {
+			req.Path = m[1]
+		}
+	case "":
+		// MCP tools show as "server - tool(args) (MCP)"
+		if len(body) > 0 {
+			req.Tool = strings.TrimSpace(strings.SplitN(body[0], "(", 2)[0])
+		}
+		if req.Tool == "" {
+			req.Tool = "unknown"
+		}
+	}
+	return req
+}
+
+// isDescription reports whether a line reads as Claude's description of a
+// command rather than shell: a capitalised phrase without shell syntax
+func isDescription(line string) bool {
+	r, _ := utf8.DecodeRuneInString(line)
+	return unicode.IsUpper(r) && strings.Contains(line, " ") && !strings.ContainsAny(line, ";&|`<>$\\=")
+}
+
+// screenWidth is the widest line drawn, like the rules and box borders
+// around dialogs, which span what a wrapping line fills
+func screenWidth(lines []string) int {
+	width := 0
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(strings.TrimRight(line, " ")))
+	}
+	return width
+}
+
+// fillsWidth reports whether a line's text reaches the right edge, less a
+// box border and its padding, so the text may continue on the next line
+func fillsWidth(line string, width int) bool {
+	text := strings.TrimRight(strings.TrimRight(strings.TrimRight(line, " "), "│"), " ")
+	return utf8.RuneCountInString(text) >= width-2
+}
+
+// cleanDialogLine strips the box drawn around dialogs
+func cleanDialogLine(line string) string {
+	line = strings.Trim(line, " \t│╭╮╰╯")
+	if strings.Trim(line, "─ ") == "" {
+		return ""
+	}
+	return line
+}
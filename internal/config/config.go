@@ -38,7 +38,9 @@ type APMConfig struct {
 }
 
 type MonitorConfig struct {
-	PollIntervalMs int `yaml:"poll_interval_ms"`
+	PollIntervalMs int      `yaml:"poll_interval_ms"`
+	ControlMode    bool     `yaml:"control_mode"`
+	Agents         []string `yaml:"agents"`
 }
 
 type UIConfig struct {
@@ -47,6 +49,7 @@ type UIConfig struct {
 	SessionListWidthPct  int    `yaml:"session_list_width_pct"`
 	Editor               string `yaml:"editor"`
 	DefaultMode          string `yaml:"default_mode"`
+	InteractiveOnUrgent  bool   `yaml:"interactive_on_urgent"` // answer the focused session's prompts with y/n/a as they appear
 }
 
 type WorkspaceConfig struct {
@@ -54,6 +57,96 @@ type WorkspaceConfig struct {
 	BasePath string `yaml:"base_path"`
 }
 
+// PatternConfig adds a detector pattern, or replaces/disables the built-in
+// pattern with the same name
+type PatternConfig struct {
+	Name     string `yaml:"name"`
+	Regex    string `yaml:"regex"`
+	Disabled bool   `yaml:"disabled"`
+}
+
+// DetectorConfig customises Claude state detection on top of the built-in patterns
+type DetectorConfig struct {
+	Urgent            []PatternConfig `yaml:"urgent"`
+	Thinking          []PatternConfig `yaml:"thinking"`
+	Idle              []PatternConfig `yaml:"idle"`
+	Claude            []PatternConfig `yaml:"claude"`
+	TokenRegex        string          `yaml:"token_regex"`
+	ThinkingTimeRegex string          `yaml:"thinking_time_regex"`
+	ModeRegex         string          `yaml:"mode_regex"`
+}
+
+// PolicyRule answers permission prompts matching all of its set fields
+type PolicyRule struct {
+	Name    string `yaml:"name"`
+	Tool    string `yaml:"tool"`    // Bash, Edit, Write, Read, WebFetch, ...
+	Command string `yaml:"command"` // glob on the Bash command, * matches anything
+	Path    string `yaml:"path"`    // path prefix, relative to the session's directory
+	Session string `yaml:"session"` // glob on the tmux session name
+	Repo    string `yaml:"repo"`    // glob on the session's directory or its base name
+	Action  string `yaml:"action"`  // allow, deny or ask
+	// Compound lets an allow rule's command glob approve commands that span
+	// several lines or use shell operators such as && or |
+	Compound bool `yaml:"compound"`
+}
+
+// PolicyConfig auto-answers permission prompts. The first matching rule wins.
+type PolicyConfig struct {
+	DryRun bool         `yaml:"dry_run"`
+	Rules  []PolicyRule `yaml:"rules"`
+}
+
+// PriceConfig prices the models matching a pattern, per 1M tokens. Cache
+// rates left at 0 follow Anthropic's multiples of the input rate: 1.25x for
+// 5-minute writes, 2x for 1-hour writes and 0.1x for reads.
+type PriceConfig struct {
+	Model        string            `yaml:"model"` // glob on the model ID, * matches anything
+	From         string            `yaml:"from"`  // YYYY-MM-DD the price takes effect; empty for always
+	Input        float64           `yaml:"input"`
+	Output       float64           `yaml:"output"`
+	CacheWrite   float64           `yaml:"cache_write"`
+	CacheWrite1h float64           `yaml:"cache_write_1h"`
+	CacheRead    float64           `yaml:"cache_read"`
+	LongContext  *LongContextPrice `yaml:"long_context"`
+}
+
+// LongContextPrice prices whole requests whose input, cached or not, exceeds
+// Threshold tokens
+type LongContextPrice struct {
+	Threshold    int64   `yaml:"threshold"` // default 200000
+	Input        float64 `yaml:"input"`
+	Output       float64 `yaml:"output"`
+	CacheWrite   float64 `yaml:"cache_write"`
+	CacheWrite1h float64 `yaml:"cache_write_1h"`
+	CacheRead    float64 `yaml:"cache_read"`
+}
+
+// PlanConfig sets a subscription plan's usage limits. Anthropic doesn't
+// publish them in tokens, so they are left to the user; tokens count input,
+// output and cache tokens alike.
+type PlanConfig struct {
+	BlockTokens  int64  `yaml:"block_tokens"`  // per 5-hour block; 0 compares against the largest recent block
+	WeeklyTokens int64  `yaml:"weekly_tokens"` // per week; 0 for no weekly limit
+	WeeklyReset  string `yaml:"weekly_reset"`  // when the week starts, e.g. "mon 09:00"; empty for a rolling 7 days
+}
+
+// RepoBudget caps daily spending in the repos matching a pattern
+type RepoBudget struct {
+	Repo  string  `yaml:"repo"` // glob on the session's directory or its base name
+	Daily float64 `yaml:"daily"`
+}
+
+// BudgetConfig caps spending, in dollars of estimated cost. Limits left at 0
+// are off.
+type BudgetConfig struct {
+	Daily    float64      `yaml:"daily"`
+	Weekly   float64      `yaml:"weekly"`  // Monday to Sunday
+	Session  float64      `yaml:"session"` // per Claude conversation
+	Repos    []RepoBudget `yaml:"repos"`   // the first matching repo applies
+	Alerts   []float64    `yaml:"alerts"`  // shares of a limit that warn; default 0.5, 0.8, 0.9
+	HardStop bool         `yaml:"hard_stop"`
+}
+
 type Config struct {
 	Pomodoro     PomodoroConfig  `yaml:"pomodoro"`
 	Streak       StreakConfig    `yaml:"streak"`
@@ -63,6 +156,11 @@ type Config struct {
 	Monitor      MonitorConfig   `yaml:"monitor"`
 	UI           UIConfig        `yaml:"ui"`
 	Workspace    WorkspaceConfig `yaml:"workspace"`
+	Detector     DetectorConfig  `yaml:"detector"`
+	Policy       PolicyConfig    `yaml:"policy"`
+	Pricing      []PriceConfig   `yaml:"pricing"`
+	Plan         PlanConfig      `yaml:"plan"`
+	Budget       BudgetConfig    `yaml:"budget"`
 	SessionPaths []string        `yaml:"session_paths"`
 }
 
@@ -94,6 +192,8 @@ func Default() *Config {
 		},
 		Monitor: MonitorConfig{
 			PollIntervalMs: 500,
+			ControlMode:    true,
+			Agents:         []string{"claude", "aider", "codex", "gemini"},
 		},
 		UI: UIConfig{
 			DoubleTapThresholdMs: 300,
@@ -113,6 +213,32 @@ func DefaultPath() string {
 	return filepath.Join(homeDir, ".config", "ccmanager", "config.yaml")
 }
 
+// PricingPath is where prices can be kept apart from the main config
+func PricingPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "ccmanager", "pricing.yaml")
+}
+
+// LoadPricing reads the pricing list from a pricing.yaml file, which has the
+// same "pricing:" key as the main config. A missing file has no prices.
+func LoadPricing(path string) ([]PriceConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var file struct {
+		Pricing []PriceConfig `yaml:"pricing"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Pricing, nil
+}
+
 func Load(path string) (*Config, error) {
 	cfg := Default()
 
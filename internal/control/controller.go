@@ -0,0 +1,310 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/agent"
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/policy"
+	"github.com/valentindosimont/ccmanager/internal/store"
+	"github.com/valentindosimont/ccmanager/internal/tmux"
+	"github.com/valentindosimont/ccmanager/internal/workspace"
+)
+
+// Keys that can be sent to a session without text input
+const (
+	KeyEscape    = "escape"
+	KeyInterrupt = "interrupt"
+	KeyCycleMode = "cycle-mode"
+	KeyUp        = "up"
+	KeyDown      = "down"
+	KeyEnter     = "enter"
+)
+
+var tmuxKeys = map[string]string{
+	KeyEscape:    "Escape",
+	KeyInterrupt: "C-c",
+	KeyCycleMode: "BTab",
+	KeyUp:        "Up",
+	KeyDown:      "Down",
+	KeyEnter:     "Enter",
+}
+
+// Errors
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrUnknownKey      = errors.New("unknown key")
+	ErrNotClaude       = errors.New("only supported for Claude sessions")
+	ErrBudgetStopped   = errors.New("stopped by a budget limit until acknowledged")
+)
+
+// CreateWarning is returned by CreateSession for a session it created, but
+// not as asked: outside the workspace that couldn't be created, or without
+// claude running
+type CreateWarning struct {
+	Err error
+}
+
+func (w *CreateWarning) Error() string { return w.Err.Error() }
+func (w *CreateWarning) Unwrap() error { return w.Err }
+
+// CreateOptions describes a new session
+type CreateOptions struct {
+	Name      string
+	Path      string
+	Workspace bool // create a git worktree / jj workspace for the session
+}
+
+// Owner is the process whose monitor owns session state, like the daemon
+// for an attached dashboard. State changes go through it.
+type Owner interface {
+	AcknowledgeBudgetStop(name string) (bool, error)
+}
+
+// Controller performs session actions shared by the TUI and the control API
+type Controller struct {
+	monitor    *daemon.Monitor
+	tmux       tmux.Multiplexer
+	store      *store.Store
+	workspaces *workspace.Manager
+	config     *config.Config
+	policy     *policy.Policy
+	owner      Owner
+}
+
+// New creates a new Controller
+func New(monitor *daemon.Monitor, mux tmux.Multiplexer, st *store.Store, wsMgr *workspace.Manager, cfg *config.Config) *Controller {
+	return &Controller{
+		monitor:    monitor,
+		tmux:       mux,
+		store:      st,
+		workspaces: wsMgr,
+		config:     cfg,
+	}
+}
+
+// Sessions returns a snapshot of all monitored sessions
+func (c *Controller) Sessions() []daemon.SessionState {
+	return c.monitor.Snapshot()
+}
+
+// Session returns a snapshot of one monitored session
+func (c *Controller) Session(name string) (daemon.SessionState, error) {
+	for _, s := range c.monitor.Snapshot() {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return daemon.SessionState{}, fmt.Errorf("%w: %s", ErrSessionNotFound, name)
+}
+
+// SendPrompt switches to the configured default mode and types text into
+// the session's Claude pane. A session a budget stopped takes no prompts.
+func (c *Controller) SendPrompt(name, text string) error {
+	sess, err := c.Session(name)
+	if err != nil {
+		return err
+	}
+	if sess.BudgetStop != "" {
+		return fmt.Errorf("send to %s: %w: %s", name, ErrBudgetStopped, sess.BudgetStop)
+	}
+	if c.config != nil && c.config.UI.DefaultMode != "" {
+		_, _ = c.SwitchMode(name, c.config.UI.DefaultMode)
+	}
+	return c.tmux.SendKeysToPane(sess.Name, sess.ClaudePane, text)
+}
+
+// SendKey sends one of the named keys (KeyEscape, KeyInterrupt, ...)
+func (c *Controller) SendKey(name, key string) error {
+	tmuxKey, ok := tmuxKeys[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+	sess, err := c.Session(name)
+	if err != nil {
+		return err
+	}
+	return c.tmux.SendKeysToPaneRaw(sess.Name, sess.ClaudePane, tmuxKey)
+}
+
+// SwitchMode cycles Claude's mode with Shift+Tab until targetMode is shown.
+// Returns false if the session was already in that mode or it never appeared.
+func (c *Controller) SwitchMode(name, targetMode string) (bool, error) {
+	sess, err := c.Session(name)
+	if err != nil {
+		return false, err
+	}
+	if sess.Agent != agent.Claude {
+		return false, fmt.Errorf("switch mode of %s (%s): %w", sess.Name, sess.Agent, ErrNotClaude)
+	}
+	if targetMode == "" || sess.State == claude.StateUrgent {
+		return false, nil
+	}
+
+	// A monitor following the daemon has no screen contents: capture one
+	content := sess.LastContent
+	if content == "" {
+		content, _ = c.capture(sess)
+	}
+	detector := c.monitor.Detector()
+	currentMode := detector.DetectMode(content)
+	if strings.EqualFold(currentMode, targetMode) {
+		return false, nil
+	}
+
+	const maxCycles = 3
+	for i := 0; i < maxCycles; i++ {
+		_ = c.tmux.SendKeysToPaneRaw(sess.Name, sess.ClaudePane, "BTab")
+		time.Sleep(50 * time.Millisecond)
+
+		newContent, _ := c.capture(sess)
+		if strings.EqualFold(detector.DetectMode(newContent), targetMode) {
+			time.Sleep(50 * time.Millisecond)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateSession starts a tmux session running claude, optionally inside a
+// new workspace. Returns the directory the session was started in. A session
+// created without its workspace, in opts.Path instead, or without claude
+// started comes with a *CreateWarning.
+func (c *Controller) CreateSession(opts CreateOptions) (string, error) {
+	if opts.Name == "" {
+		return "", fmt.Errorf("session name required")
+	}
+	path := opts.Path
+	var warnings []error
+
+	if opts.Workspace {
+		wsPath, err := c.createWorkspace(path, opts.Name)
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("workspace creation failed, starting in %s: %w", path, err))
+		} else {
+			path = wsPath
+		}
+	}
+
+	if err := c.tmux.NewSession(opts.Name, path); err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := c.tmux.SendKeys(opts.Name, "claude"); err != nil {
+		warnings = append(warnings, fmt.Errorf("failed to send claude command: %w", err))
+	}
+
+	if c.store != nil {
+		_ = c.store.AddRecentPath(path)
+	}
+	if len(warnings) > 0 {
+		return path, &CreateWarning{Err: errors.Join(warnings...)}
+	}
+	return path, nil
+}
+
+// createWorkspace creates and records the workspace for a new session
+func (c *Controller) createWorkspace(sourceRepo, name string) (string, error) {
+	if c.workspaces == nil {
+		return "", fmt.Errorf("workspace support not configured")
+	}
+	wsPath, err := c.workspaces.CreateWorkspace(sourceRepo, name)
+	if err != nil {
+		return "", err
+	}
+	if c.store != nil {
+		_ = c.store.SaveSessionWorkspace(name, wsPath, sourceRepo)
+	}
+	return wsPath, nil
+}
+
+// KillSession kills a tmux session and removes any workspace created for it
+func (c *Controller) KillSession(name string) error {
+	if err := c.tmux.KillSession(name); err != nil {
+		return fmt.Errorf("kill session %s: %w", name, err)
+	}
+	return c.CleanupWorkspace(name)
+}
+
+// CleanupWorkspace deletes the workspace recorded for a session, if any
+func (c *Controller) CleanupWorkspace(name string) error {
+	if c.store == nil {
+		return nil
+	}
+	wsPath, sourceRepo, err := c.store.GetSessionWorkspace(name)
+	if err != nil || wsPath == "" {
+		return err
+	}
+	defer func() { _ = c.store.DeleteSessionWorkspace(name) }()
+	if c.workspaces == nil {
+		return nil
+	}
+	if err := c.workspaces.DeleteWorkspace(sourceRepo, wsPath); err != nil {
+		return fmt.Errorf("workspace cleanup failed: %w", err)
+	}
+	return nil
+}
+
+// WorkspaceRepo returns the basename of the repo a session's workspace was
+// created from, or "" if it has none
+func (c *Controller) WorkspaceRepo(name string) string {
+	if c.store == nil {
+		return ""
+	}
+	_, sourceRepo, err := c.store.GetSessionWorkspace(name)
+	if err != nil || sourceRepo == "" {
+		return ""
+	}
+	return filepath.Base(sourceRepo)
+}
+
+// NextSessionName returns "<dir basename>-N" with the lowest N not already taken
+func NextSessionName(dir string, existing []string) string {
+	taken := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		taken[name] = true
+	}
+	base := filepath.Base(dir)
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s-%d", base, i)
+		if !taken[name] {
+			return name
+		}
+	}
+}
+
+// Focus switches the tmux client to a session
+func (c *Controller) Focus(name string) error {
+	return c.tmux.SwitchClient(name)
+}
+
+// SetOwner sends state changes to the process owning session state instead
+// of the local monitor
+func (c *Controller) SetOwner(o Owner) {
+	c.owner = o
+}
+
+// AcknowledgeBudgetStop lets prompts be sent again to a session a budget
+// stopped. It returns false if the session wasn't stopped.
+func (c *Controller) AcknowledgeBudgetStop(name string) (bool, error) {
+	if c.owner != nil {
+		return c.owner.AcknowledgeBudgetStop(name)
+	}
+	if _, err := c.Session(name); err != nil {
+		return false, err
+	}
+	return c.monitor.AcknowledgeBudgetStop(name), nil
+}
+
+func (c *Controller) capture(sess daemon.SessionState) (string, error) {
+	if sess.ClaudePane != nil {
+		return c.tmux.CapturePane(sess.Name, sess.ClaudePane.WindowIndex, sess.ClaudePane.PaneIndex)
+	}
+	return c.tmux.CapturePaneDefault(sess.Name)
+}
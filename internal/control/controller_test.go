@@ -0,0 +1,128 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/agent"
+	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/tmux/tmuxtest"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+	"github.com/valentindosimont/ccmanager/internal/workspace"
+)
+
+func TestCreateSession(t *testing.T) {
+	notRepo := t.TempDir()
+	wsMgr, err := workspace.NewManager(&config.WorkspaceConfig{BasePath: filepath.Join(t.TempDir(), "ws")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        CreateOptions
+		wsMgr       *workspace.Manager
+		wantWarning bool
+	}{
+		{name: "plain", opts: CreateOptions{Name: "api-1", Path: notRepo}},
+		// Without a workspace, the session still starts in its directory
+		{name: "workspace fails", opts: CreateOptions{Name: "api-2", Path: notRepo, Workspace: true}, wsMgr: wsMgr, wantWarning: true},
+		{name: "no workspace support", opts: CreateOptions{Name: "api-3", Path: notRepo, Workspace: true}, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tmuxtest.New()
+			c := New(daemon.NewMonitor(time.Second, nil, fake), fake, nil, tt.wsMgr, config.Default())
+
+			path, err := c.CreateSession(tt.opts)
+			var warning *CreateWarning
+			if tt.wantWarning != errors.As(err, &warning) || (err != nil && warning == nil) {
+				t.Fatalf("CreateSession() error = %v, want warning %v", err, tt.wantWarning)
+			}
+			if path != notRepo {
+				t.Errorf("CreateSession() path = %q, want %q", path, notRepo)
+			}
+			if got, _ := fake.GetSessionPath(tt.opts.Name); got != notRepo {
+				t.Errorf("session started in %q, want %q", got, notRepo)
+			}
+			if sent := fake.Sent(); len(sent) != 1 || sent[0].Keys != "claude" {
+				t.Errorf("sent %+v, want claude started", sent)
+			}
+		})
+	}
+
+	fake := tmuxtest.New()
+	fake.AddSession("taken", notRepo, "")
+	c := New(daemon.NewMonitor(time.Second, nil, fake), fake, nil, nil, config.Default())
+	var warning *CreateWarning
+	if _, err := c.CreateSession(CreateOptions{Name: "taken", Path: notRepo}); err == nil || errors.As(err, &warning) {
+		t.Errorf("CreateSession() of a taken name = %v, want a failure", err)
+	}
+}
+
+// recordingOwner records the acknowledgements sent to it
+type recordingOwner []string
+
+func (o *recordingOwner) AcknowledgeBudgetStop(name string) (bool, error) {
+	*o = append(*o, name)
+	return true, nil
+}
+
+func TestAcknowledgeBudgetStop(t *testing.T) {
+	fake := tmuxtest.New()
+	c := New(daemon.NewMonitor(time.Second, nil, fake), fake, nil, nil, config.Default())
+	if _, err := c.AcknowledgeBudgetStop("api"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("AcknowledgeBudgetStop() of an unknown session = %v, want ErrSessionNotFound", err)
+	}
+
+	// An attached dashboard leaves it to the daemon
+	var owner recordingOwner
+	c.SetOwner(&owner)
+	if ok, err := c.AcknowledgeBudgetStop("api"); !ok || err != nil || len(owner) != 1 || owner[0] != "api" {
+		t.Errorf("AcknowledgeBudgetStop() = %v, %v, owner got %v", ok, err, owner)
+	}
+}
+
+// staticSource is a daemon whose sessions never change
+type staticSource []daemon.SessionState
+
+func (s staticSource) Snapshot() ([]daemon.SessionState, error) { return s, nil }
+
+func (s staticSource) UsageWindow() (*usage.WindowStatus, error) { return nil, nil }
+
+func (s staticSource) Events(ctx context.Context, fn func(daemon.Event) error) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestSendPromptBudgetStop(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", "")
+	fake.AddSession("web", "", "")
+	monitor := daemon.NewMonitor(time.Second, nil, fake)
+	monitor.Follow(staticSource{
+		{Name: "api", Agent: agent.Claude, BudgetStop: "daily budget reached: $10.00 of $10.00"},
+		{Name: "web", Agent: agent.Claude},
+	})
+	monitor.Start()
+	defer monitor.Stop()
+	for len(monitor.Sessions()) < 2 {
+		<-monitor.Events()
+	}
+
+	c := New(monitor, fake, nil, nil, &config.Config{})
+	if err := c.SendPrompt("api", "keep going"); !errors.Is(err, ErrBudgetStopped) {
+		t.Errorf("SendPrompt() to a stopped session = %v, want ErrBudgetStopped", err)
+	}
+	if err := c.SendPrompt("web", "keep going"); err != nil {
+		t.Fatal(err)
+	}
+	if sent := fake.Sent(); len(sent) != 1 || sent[0].Session != "web" {
+		t.Errorf("sent %+v, want only the prompt to web", sent)
+	}
+}
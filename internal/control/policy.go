@@ -0,0 +1,94 @@
+package control
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/valentindosimont/ccmanager/internal/agent"
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/policy"
+)
+
+// SetPolicy sets the rules used by ApplyPolicy. A nil policy always asks.
+func (c *Controller) SetPolicy(p *policy.Policy) {
+	c.policy = p
+}
+
+// ApplyPolicy evaluates the permission prompt a session is showing and, unless
+// the policy asks or is in dry-run mode, answers it. Every decision is written
+// to the activity log. Returns a nil request when no prompt is showing.
+func (c *Controller) ApplyPolicy(name string) (policy.Decision, *claude.PermissionRequest, error) {
+	if c.policy == nil {
+		return policy.Decision{Outcome: policy.Ask}, nil, nil
+	}
+	sess, err := c.Session(name)
+	if err != nil {
+		return policy.Decision{}, nil, err
+	}
+	if sess.Agent != agent.Claude {
+		return policy.Decision{Outcome: policy.Ask}, nil, nil
+	}
+
+	// Parse a fresh capture: hooks can report a prompt before the monitor
+	// has seen it, and the answer must match what is on screen now
+	content, err := c.capture(sess)
+	if err != nil {
+		return policy.Decision{}, nil, fmt.Errorf("capture %s: %w", name, err)
+	}
+	perm := c.monitor.Detector().ParsePermission(content)
+	if perm == nil {
+		return policy.Decision{Outcome: policy.Ask}, nil, nil
+	}
+
+	decision := c.policy.Evaluate(policy.Request{
+		Session:    sess.Name,
+		WorkingDir: sess.WorkingDir,
+		Permission: perm,
+	})
+	// Only allow a command read off the screen without doubt about its end
+	if decision.Outcome == policy.Allow && perm.Tool == "Bash" && perm.Ambiguous {
+		decision.Outcome, decision.Reason = policy.Ask, "ambiguous command"
+	}
+	c.logActivity(sess.Name, "policy", fmt.Sprintf("Policy %s: %s", decision, perm.Summary()))
+
+	answer, ok := decision.Outcome.Answer()
+	if !ok || decision.DryRun {
+		return decision, perm, nil
+	}
+	return decision, perm, c.pickOption(sess, perm, answer)
+}
+
+// Answer answers the permission prompt a session shows: the matching
+// numbered option of a dialog, or y or n for a [y/N] prompt
+func (c *Controller) Answer(name string, answer claude.Answer) error {
+	sess, err := c.Session(name)
+	if err != nil {
+		return err
+	}
+	if sess.Permission != nil {
+		return c.pickOption(sess, sess.Permission, answer)
+	}
+	switch answer {
+	case claude.AnswerYes:
+		return c.tmux.SendKeysToPaneRaw(sess.Name, sess.ClaudePane, "y")
+	case claude.AnswerNo:
+		return c.tmux.SendKeysToPaneRaw(sess.Name, sess.ClaudePane, "n")
+	}
+	return fmt.Errorf("answer %s: no %s option without a dialog", name, answer)
+}
+
+// pickOption sends the number of perm's option for answer
+func (c *Controller) pickOption(sess daemon.SessionState, perm *claude.PermissionRequest, answer claude.Answer) error {
+	opt, ok := perm.Option(answer)
+	if !ok {
+		return fmt.Errorf("answer %s: no %s option in %q", sess.Name, answer, perm.Question)
+	}
+	return c.tmux.SendKeysToPaneRaw(sess.Name, sess.ClaudePane, strconv.Itoa(opt.Number))
+}
+
+func (c *Controller) logActivity(session, eventType, message string) {
+	if c.store != nil {
+		_ = c.store.AddActivityLog(session, eventType, message)
+	}
+}
@@ -0,0 +1,128 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/daemon"
+	"github.com/valentindosimont/ccmanager/internal/policy"
+	"github.com/valentindosimont/ccmanager/internal/tmux/tmuxtest"
+)
+
+const bashPrompt = "│ ✻ Claude Code    │\n\n Bash command\n\n   go test ./...\n   Run the tests\n\n" +
+	" Do you want to proceed?\n ❯ 1. Yes\n   2. Yes, and don't ask again for go test commands\n" +
+	"   3. No, and tell Claude what to do differently (esc)\n"
+
+// The command's second line may be its wrapped tail or its description
+const ambiguousPrompt = "│ ✻ Claude Code    │\n\n Bash command\n\n   go test ./...\n   rm -rf build\n\n" +
+	" Do you want to proceed?\n ❯ 1. Yes\n   2. No, and tell Claude what to do differently (esc)\n"
+
+const yesNoPrompt = "│ ✻ Claude Code    │\n\n Overwrite config? [y/N]\n"
+
+func TestApplyPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		dryRun   bool
+		compound bool
+		want     policy.Outcome
+		content  string // bashPrompt if empty
+		wantKeys string // "" when nothing is sent
+	}{
+		{name: "allow", action: "allow", want: policy.Allow, wantKeys: "1"},
+		{name: "allow ambiguous", action: "allow", compound: true, content: ambiguousPrompt, want: policy.Ask},
+		{name: "deny", action: "deny", want: policy.Deny, wantKeys: "3"},
+		{name: "ask", action: "ask", want: policy.Ask},
+		{name: "dry run", action: "allow", dryRun: true, want: policy.Allow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := tt.content
+			if content == "" {
+				content = bashPrompt
+			}
+			fake := tmuxtest.New()
+			fake.AddSession("api", "/src/api", content)
+
+			monitor := daemon.NewMonitor(time.Second, nil, fake)
+			monitor.Refresh()
+
+			p, err := policy.New(config.PolicyConfig{DryRun: tt.dryRun, Rules: []config.PolicyRule{
+				{Name: "tests", Tool: "Bash", Command: "go test *", Compound: tt.compound, Action: tt.action},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := New(monitor, fake, nil, nil, config.Default())
+			c.SetPolicy(p)
+
+			decision, perm, err := c.ApplyPolicy("api")
+			if err != nil {
+				t.Fatalf("ApplyPolicy() error = %v", err)
+			}
+			if perm == nil || decision.Outcome != tt.want {
+				t.Fatalf("ApplyPolicy() = %v, %+v, want %s", decision, perm, tt.want)
+			}
+
+			sent := fake.Sent()
+			if tt.wantKeys == "" {
+				if len(sent) != 0 {
+					t.Errorf("sent %+v, want nothing", sent)
+				}
+				return
+			}
+			if len(sent) != 1 || sent[0].Keys != tt.wantKeys || !sent[0].Raw {
+				t.Errorf("sent %+v, want raw %q", sent, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		answer   claude.Answer
+		wantKeys string // "" when the answer fails
+	}{
+		{name: "dialog yes", content: bashPrompt, answer: claude.AnswerYes, wantKeys: "1"},
+		{name: "dialog always", content: bashPrompt, answer: claude.AnswerAlways, wantKeys: "2"},
+		{name: "dialog no", content: bashPrompt, answer: claude.AnswerNo, wantKeys: "3"},
+		{name: "y/n prompt", content: yesNoPrompt, answer: claude.AnswerNo, wantKeys: "n"},
+		{name: "no always without a dialog", content: yesNoPrompt, answer: claude.AnswerAlways},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tmuxtest.New()
+			fake.AddSession("api", "/src/api", tt.content)
+
+			monitor := daemon.NewMonitor(time.Second, nil, fake)
+			monitor.Refresh()
+			c := New(monitor, fake, nil, nil, config.Default())
+
+			err := c.Answer("api", tt.answer)
+			sent := fake.Sent()
+			if tt.wantKeys == "" {
+				if err == nil || len(sent) != 0 {
+					t.Errorf("Answer() = %v, sent %+v, want an error and nothing sent", err, sent)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Answer() error = %v", err)
+			}
+			if len(sent) != 1 || sent[0].Keys != tt.wantKeys || !sent[0].Raw {
+				t.Errorf("sent %+v, want raw %q", sent, tt.wantKeys)
+			}
+		})
+	}
+
+	if err := New(daemon.NewMonitor(time.Second, nil, tmuxtest.New()), tmuxtest.New(), nil, nil, config.Default()).
+		Answer("missing", claude.AnswerYes); err == nil {
+		t.Error("Answer() on a missing session succeeded")
+	}
+}
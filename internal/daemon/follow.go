@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+// Source is a monitor running in another process, like the daemon, that a
+// Monitor can follow instead of polling tmux and reading transcripts itself
+type Source interface {
+	Snapshot() ([]SessionState, error)
+	UsageWindow() (*usage.WindowStatus, error)
+	// Events calls fn with each event until ctx is cancelled or the stream
+	// ends
+	Events(ctx context.Context, fn func(Event) error) error
+}
+
+// followInterval is how often a following monitor refreshes its sessions
+// between events, to pick up token and cost updates
+const followInterval = 2 * time.Second
+
+// Follow makes Start mirror src instead of polling, so that two processes
+// never disagree about a session. Sessions discovered and closed are derived
+// from the mirrored sessions; other events are forwarded as src emits them.
+func (m *Monitor) Follow(src Source) {
+	m.source = src
+}
+
+// followLoop mirrors the source's sessions on every event and tick,
+// reconnecting to its event stream when it ends
+func (m *Monitor) followLoop() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 100)
+	streamDone := make(chan struct{}, 1)
+	stream := func() {
+		_ = m.source.Events(ctx, func(e Event) error {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+		streamDone <- struct{}{}
+	}
+
+	m.mirror()
+	go stream()
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	streaming := true
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case e := <-events:
+			m.mirror()
+			switch e.Type {
+			case EventSessionDiscovered, EventSessionClosed:
+				// mirror emitted these
+			default:
+				m.emit(e)
+			}
+		case <-streamDone:
+			streaming = false
+		case <-ticker.C:
+			m.mirror()
+			if !streaming {
+				streaming = true
+				go stream()
+			}
+		}
+	}
+}
+
+// mirror replaces the monitored sessions with the source's, emitting
+// discovered and closed events for the difference. Sessions still present are
+// updated in place, like a poll would.
+func (m *Monitor) mirror() {
+	snapshot, err := m.source.Snapshot()
+	if err != nil {
+		m.debugLog("follow: %v", err)
+		return
+	}
+
+	var events []Event
+	now := time.Now()
+	m.mu.Lock()
+	seen := make(map[string]bool, len(snapshot))
+	for i := range snapshot {
+		s := snapshot[i]
+		seen[s.Name] = true
+		if existing, ok := m.sessions[s.Name]; ok {
+			*existing = s
+			continue
+		}
+		m.sessions[s.Name] = &s
+		events = append(events, Event{Type: EventSessionDiscovered, Session: s.Name, State: s.State, Time: now})
+	}
+	for name := range m.sessions {
+		if !seen[name] {
+			delete(m.sessions, name)
+			events = append(events, Event{Type: EventSessionClosed, Session: name, Time: now})
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range events {
+		m.emit(e)
+	}
+}
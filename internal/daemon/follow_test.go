@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/tmux/tmuxtest"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+// fakeSource is a monitor in another process, streaming what is sent on events
+type fakeSource struct {
+	mu       sync.Mutex
+	sessions []SessionState
+	window   *usage.WindowStatus
+	events   chan Event
+}
+
+func newFakeSource(sessions ...SessionState) *fakeSource {
+	return &fakeSource{sessions: sessions, events: make(chan Event)}
+}
+
+func (s *fakeSource) set(sessions ...SessionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = sessions
+}
+
+func (s *fakeSource) Snapshot() ([]SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SessionState(nil), s.sessions...), nil
+}
+
+func (s *fakeSource) UsageWindow() (*usage.WindowStatus, error) {
+	return s.window, nil
+}
+
+func (s *fakeSource) Events(ctx context.Context, fn func(Event) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-s.events:
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	created := time.Now()
+	api := SessionState{Name: "api", State: claude.StateIdle, Created: created}
+	web := SessionState{Name: "web", State: claude.StateIdle, Created: created.Add(time.Second)}
+
+	src := newFakeSource(api, web)
+	m := newTestMonitor(tmuxtest.New())
+	m.Follow(src)
+
+	m.mirror()
+	assertEvents(t, drainEvents(m), EventSessionDiscovered, EventSessionDiscovered)
+	held := m.GetSession("api")
+
+	// Updates land in place, like a poll's, without events
+	api.State, api.Tokens = claude.StateThinking, 1200
+	src.set(api, web)
+	m.mirror()
+	assertEvents(t, drainEvents(m))
+	if held.State != claude.StateThinking || held.Tokens != 1200 {
+		t.Errorf("held session = %+v, want it updated", held)
+	}
+
+	src.set(api)
+	m.mirror()
+	events := drainEvents(m)
+	assertEvents(t, events, EventSessionClosed)
+	if events[0].Session != "web" || len(m.Sessions()) != 1 {
+		t.Errorf("closed %q leaving %d sessions, want web leaving 1", events[0].Session, len(m.Sessions()))
+	}
+}
+
+func TestFollow(t *testing.T) {
+	src := newFakeSource(SessionState{Name: "api", State: claude.StateIdle, Created: time.Now()})
+	src.window = &usage.WindowStatus{BlockLimit: 1000}
+
+	m := newTestMonitor(tmuxtest.New())
+	m.Follow(src)
+	m.Start()
+	defer m.Stop()
+
+	next := func() Event {
+		t.Helper()
+		for {
+			select {
+			case e := <-m.Events():
+				if e.Type != EventDebug {
+					return e
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no event")
+			}
+		}
+	}
+	if e := next(); e.Type != EventSessionDiscovered || e.Session != "api" {
+		t.Fatalf("first event = %+v, want api discovered", e)
+	}
+
+	// The daemon's discovery is derived from the mirror, not forwarded
+	web := SessionState{Name: "web", State: claude.StateUrgent, Created: time.Now()}
+	src.set(SessionState{Name: "api", State: claude.StateIdle}, web)
+	src.events <- Event{Type: EventSessionDiscovered, Session: "web", State: claude.StateUrgent}
+	src.events <- Event{Type: EventUrgent, Session: "web", State: claude.StateUrgent, Pattern: "do-you-want"}
+
+	if e := next(); e.Type != EventSessionDiscovered || e.Session != "web" {
+		t.Fatalf("event = %+v, want web discovered", e)
+	}
+	e := next()
+	if e.Type != EventUrgent || e.Pattern != "do-you-want" {
+		t.Fatalf("event = %+v, want the forwarded urgent event", e)
+	}
+	if sess := m.GetSession("web"); sess == nil || sess.State != claude.StateUrgent {
+		t.Errorf("web = %+v when its urgent event arrived, want it mirrored first", sess)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.UsageWindow() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if w := m.UsageWindow(); w == nil || w.BlockLimit != 1000 {
+		t.Errorf("UsageWindow() = %+v, want the source's", w)
+	}
+}
@@ -1,21 +1,26 @@
 package daemon
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"sort"
 	"sync"
 	"time"
 
+	"github.com/valentindosimont/ccmanager/internal/agent"
+	"github.com/valentindosimont/ccmanager/internal/budget"
 	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/hooks"
 	"github.com/valentindosimont/ccmanager/internal/store"
 	"github.com/valentindosimont/ccmanager/internal/tmux"
 	"github.com/valentindosimont/ccmanager/internal/usage"
 )
 
-// SessionState represents the monitored state of a Claude session
+// SessionState represents the monitored state of a coding agent's session
 type SessionState struct {
 	Name            string
+	Agent           agent.Kind
 	State           claude.SessionState
 	LastContent     string
 	LastCapture     time.Time
@@ -24,12 +29,28 @@ type SessionState struct {
 	LastLine        string
 	Created         time.Time
 	Attached        bool
-	ClaudePane      *tmux.Pane
+	ClaudePane      *tmux.Pane // pane running the agent, whichever it is
 	WorkingDir      string
 	Usage           *usage.SessionUsage
-	ClaudeSessionID string // Locked Claude session UUID for usage tracking
+	ClaudeSessionID string                    // Locked Claude session UUID for usage tracking
+	Hooked          bool                      // State is driven by Claude Code hooks, not screen scraping
+	LastHook        time.Time                 // when the last hook arrived
+	ScreenState     claude.SessionState       // state the screen shows, which hooks override
+	Permission      *claude.PermissionRequest // permission dialog on screen, if any
+	BudgetStop      string                    // budget limit that stopped the session, until acknowledged
 }
 
+const (
+	// hookTimeout is how long a hooked session's state stands without a
+	// hook before the screen decides again
+	hookTimeout = 2 * time.Minute
+	// hookGrace is how long the screen may lag behind a hook
+	hookGrace = 2 * time.Second
+)
+
+// ErrUnknownSession is returned when a hook can't be matched to a session
+var ErrUnknownSession = errors.New("no monitored session for hook")
+
 // Event represents a session event
 type Event struct {
 	Type    EventType
@@ -37,6 +58,7 @@ type Event struct {
 	State   claude.SessionState
 	Time    time.Time
 	Message string
+	Pattern string // detector pattern or "hook:<event>" behind a state change
 }
 
 // EventType represents the type of event
@@ -49,12 +71,39 @@ const (
 	EventTaskCompleted
 	EventUrgent
 	EventDebug
+	EventBudget     // Message describes the budget threshold crossed
+	EventCompaction // the session's conversation was compacted
 )
 
+func (t EventType) String() string {
+	switch t {
+	case EventSessionDiscovered:
+		return "session_discovered"
+	case EventSessionClosed:
+		return "session_closed"
+	case EventStateChanged:
+		return "state_changed"
+	case EventTaskCompleted:
+		return "task_completed"
+	case EventUrgent:
+		return "urgent"
+	case EventDebug:
+		return "debug"
+	case EventBudget:
+		return "budget"
+	case EventCompaction:
+		return "compaction"
+	default:
+		return "unknown"
+	}
+}
+
 // Monitor polls tmux sessions and detects Claude state
 type Monitor struct {
-	tmux     *tmux.Client
+	tmux     tmux.Multiplexer
+	control  *tmux.ControlMode
 	detector *claude.Detector
+	agents   *agent.Registry
 	store    *store.Store
 
 	mu       sync.RWMutex
@@ -64,24 +113,40 @@ type Monitor struct {
 	stopCh        chan struct{}
 	eventCh       chan Event
 	debug         bool
+	ingester      *usage.Ingester
 	usageWatcher  *usage.Watcher
 	usagePollTick int
 	lastCosts     map[string]float64
+	readOnly      bool
+	useControl    bool
+	source        Source // followed instead of polling, if set
+
+	planLimits usage.PlanLimits
+	window     *usage.WindowStatus // guarded by mu
+	budget     *budget.Budget
+
+	subMu       sync.Mutex
+	subscribers map[chan Event]struct{}
 }
 
 // NewMonitor creates a new session monitor
-func NewMonitor(pollInterval time.Duration, st *store.Store) *Monitor {
+func NewMonitor(pollInterval time.Duration, st *store.Store, mux tmux.Multiplexer) *Monitor {
+	detector := claude.NewDetector()
+	ingester := usage.NewIngester(st)
 	return &Monitor{
-		tmux:         tmux.NewClient(),
-		detector:     claude.NewDetector(),
+		tmux:         mux,
+		detector:     detector,
+		agents:       agent.Default(detector),
 		store:        st,
 		sessions:     make(map[string]*SessionState),
 		pollInterval: pollInterval,
 		stopCh:       make(chan struct{}),
 		eventCh:      make(chan Event, 100),
 		debug:        os.Getenv("CCMANAGER_DEBUG") == "1",
-		usageWatcher: usage.NewWatcher(5 * time.Second),
+		ingester:     ingester,
+		usageWatcher: usage.NewWatcher(5*time.Second, ingester),
 		lastCosts:    make(map[string]float64),
+		subscribers:  make(map[chan Event]struct{}),
 	}
 }
 
@@ -89,28 +154,146 @@ func (m *Monitor) debugLog(format string, args ...interface{}) {
 	if !m.debug {
 		return
 	}
-	m.eventCh <- Event{
+	m.emit(Event{
 		Type:    EventDebug,
 		Message: fmt.Sprintf(format, args...),
 		Time:    time.Now(),
+	})
+}
+
+// emit delivers an event to the main channel and to every subscriber.
+// Slow subscribers miss events rather than stalling the poll loop.
+func (m *Monitor) emit(event Event) {
+	m.eventCh <- event
+
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
 	}
 }
 
+// Subscribe returns a channel receiving a copy of every event, alongside
+// Events(). Call the returned function to unsubscribe.
+func (m *Monitor) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 100)
+
+	m.subMu.Lock()
+	m.subscribers[ch] = struct{}{}
+	m.subMu.Unlock()
+
+	return ch, func() {
+		m.subMu.Lock()
+		defer m.subMu.Unlock()
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// SetReadOnly stops the monitor from writing costs and session IDs to the
+// store. Used when another process (the daemon) owns persistence.
+func (m *Monitor) SetReadOnly(readOnly bool) {
+	m.readOnly = readOnly
+}
+
+// SetControlMode makes Start read tmux through control-mode clients instead
+// of spawning tmux for every poll, falling back if tmux doesn't support it
+func (m *Monitor) SetControlMode(enabled bool) {
+	m.useControl = enabled
+}
+
+// SetDetector replaces the built-in detector, e.g. with one built from config
+func (m *Monitor) SetDetector(d *claude.Detector) {
+	m.detector = d
+}
+
+// Detector returns the detector used for Claude-specific screen reading,
+// like mode detection
+func (m *Monitor) Detector() *claude.Detector {
+	return m.detector
+}
+
+// Usage returns the ingester that reads Claude transcripts
+func (m *Monitor) Usage() *usage.Ingester {
+	return m.ingester
+}
+
+// SetPlanLimits sets the subscription limits usage windows are measured against
+func (m *Monitor) SetPlanLimits(limits usage.PlanLimits) {
+	m.planLimits = limits
+}
+
+// UsageWindow returns the usage of the current 5-hour block and week across
+// all projects, or nil until transcripts have been read
+func (m *Monitor) UsageWindow() *usage.WindowStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.window
+}
+
+// SetBudget sets the spending limits checked as session costs grow
+func (m *Monitor) SetBudget(b *budget.Budget) {
+	m.budget = b
+}
+
+// AcknowledgeBudgetStop lets prompts be sent to a session a budget stopped.
+// It returns false if the session wasn't stopped.
+func (m *Monitor) AcknowledgeBudgetStop(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess, ok := m.sessions[name]
+	if !ok || sess.BudgetStop == "" {
+		return false
+	}
+	sess.BudgetStop = ""
+	return true
+}
+
+// SetAgents sets which agents are recognised in panes
+func (m *Monitor) SetAgents(r *agent.Registry) {
+	m.agents = r
+}
+
 // Events returns the event channel
 func (m *Monitor) Events() <-chan Event {
 	return m.eventCh
 }
 
-// Start starts the monitor polling loop
+// Start starts the monitor polling loop, or mirroring the source it follows
 func (m *Monitor) Start() {
+	if m.source != nil {
+		go m.followLoop()
+		go m.windowLoop()
+		return
+	}
+	if m.useControl {
+		if cm, err := tmux.NewControlMode(); err == nil {
+			m.control = cm
+			m.tmux = cm
+		} else {
+			m.debugLog("control mode disabled: %v", err)
+		}
+	}
 	m.usageWatcher.Start()
 	go m.pollLoop()
+	// Reading every project is left to the monitor owning persistence
+	if !m.readOnly {
+		go m.windowLoop()
+	}
 }
 
 // Stop stops the monitor
 func (m *Monitor) Stop() {
 	close(m.stopCh)
 	m.usageWatcher.Stop()
+	if m.control != nil {
+		m.control.Close()
+	}
 }
 
 // Sessions returns all currently known sessions
@@ -128,6 +311,22 @@ func (m *Monitor) Sessions() []*SessionState {
 	return result
 }
 
+// Snapshot returns copies of all known sessions, safe to read while the
+// monitor keeps polling
+func (m *Monitor) Snapshot() []SessionState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]SessionState, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+	return result
+}
+
 // GetSession returns a specific session
 func (m *Monitor) GetSession(name string) *SessionState {
 	m.mu.RLock()
@@ -135,6 +334,13 @@ func (m *Monitor) GetSession(name string) *SessionState {
 	return m.sessions[name]
 }
 
+// Refresh polls tmux and usage once, for one-shot callers that don't Start
+// the monitor. Events are still emitted and must be drained.
+func (m *Monitor) Refresh() {
+	m.poll()
+	m.updateUsage()
+}
+
 func (m *Monitor) pollLoop() {
 	ticker := time.NewTicker(m.pollInterval)
 	defer ticker.Stop()
@@ -166,7 +372,8 @@ func (m *Monitor) updateUsage() {
 	}
 	sessions := make(map[string]sessionInfo)
 	for name, sess := range m.sessions {
-		if sess.WorkingDir != "" {
+		// Other agents' usage is read from the screen in poll
+		if sess.Agent == agent.Claude && sess.WorkingDir != "" {
 			sessions[name] = sessionInfo{
 				workingDir:      sess.WorkingDir,
 				claudeSessionID: sess.ClaudeSessionID,
@@ -177,64 +384,218 @@ func (m *Monitor) updateUsage() {
 
 	for name, info := range sessions {
 		// Use the locked session ID instead of finding most recent
-		sessionUsage, err := usage.GetSessionByID(info.workingDir, info.claudeSessionID)
-		if err != nil || sessionUsage == nil {
+		sessionUsage, err := m.ingester.SessionUsage(info.workingDir, info.claudeSessionID)
+		if err != nil {
+			m.debugLog("usage of %s: %v", name, err)
+			continue
+		}
+		if sessionUsage == nil {
 			continue
 		}
 
+		var compacted bool
 		m.mu.Lock()
 		if sess, ok := m.sessions[name]; ok {
+			compacted = newCompaction(sess.Usage, sessionUsage)
 			sess.Usage = sessionUsage
 		}
 		m.mu.Unlock()
 
-		if sessionUsage.EstimatedCost > 0 {
-			last, exists := m.lastCosts[name]
-			current := sessionUsage.EstimatedCost
-			if exists {
-				delta := current - last
-				if delta > 0 && m.store != nil {
-					_ = m.store.AddToDailyCost(delta)
+		if compacted {
+			m.emit(Event{
+				Type:    EventCompaction,
+				Session: name,
+				Time:    time.Now(),
+				Message: sessionUsage.Compaction.String(),
+			})
+		}
+		m.recordCost(name, sessionUsage.EstimatedCost)
+	}
+}
+
+// newCompaction reports whether next holds a compaction since prev. Ones
+// already in the transcript when the session was discovered don't count.
+func newCompaction(prev, next *usage.SessionUsage) bool {
+	if prev == nil || next.Compaction == nil {
+		return false
+	}
+	return prev.Compaction == nil || next.Compaction.Time.After(prev.Compaction.Time)
+}
+
+// windowLoop keeps the usage window up to date. It reads every project's
+// transcripts, so it runs apart from the poll loop.
+func (m *Monitor) windowLoop() {
+	m.updateWindow()
+
+	ticker := time.NewTicker(windowInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			m.updateWindow()
+		}
+	}
+}
+
+// windowInterval is how often the usage window is recalculated
+const windowInterval = 30 * time.Second
+
+func (m *Monitor) updateWindow() {
+	var window *usage.WindowStatus
+	var err error
+	if m.source != nil {
+		window, err = m.source.UsageWindow()
+	} else {
+		window, err = m.ingester.WindowStatus(m.planLimits, time.Now())
+	}
+	if err != nil {
+		m.debugLog("usage window: %v", err)
+		return
+	}
+	m.mu.Lock()
+	m.window = window
+	m.mu.Unlock()
+}
+
+// linkUsageSession records that a Claude session ran in a tmux session, for
+// usage reports by session name
+func (m *Monitor) linkUsageSession(claudeSessionID, name string) {
+	if claudeSessionID == "" || m.store == nil || m.readOnly {
+		return
+	}
+	_ = m.store.LinkUsageSession(claudeSessionID, name)
+}
+
+// recordCost adds the growth of a session's cost since the last call to
+// today's total. Growth that couldn't be saved is added on the next call.
+func (m *Monitor) recordCost(name string, current float64) {
+	if current <= 0 {
+		return
+	}
+	last, exists := m.lastCosts[name]
+	if exists {
+		delta := current - last
+		if delta > 0 {
+			if m.store != nil && !m.readOnly {
+				if err := m.store.AddToDailyCost(delta); err != nil {
+					m.debugLog("record cost of %s: %v", name, err)
+					return
 				}
 			}
-			m.lastCosts[name] = current
+			m.checkBudget(name, current)
 		}
 	}
+	m.lastCosts[name] = current
 }
 
-func (m *Monitor) findClaudePane(session string) (*tmux.Pane, string) {
-	panes, err := m.tmux.ListPanes(session)
-	if err != nil {
-		m.debugLog("%s: ListPanes error: %v", session, err)
-		return nil, ""
+// checkBudget reports the budget thresholds crossed now that a session's
+// cost grew to sessionCost, stopping sessions at a hard limit. Only the
+// monitor that owns the sessions checks, so alerts and stops happen once.
+func (m *Monitor) checkBudget(name string, sessionCost float64) {
+	if m.budget == nil || m.readOnly {
+		return
+	}
+	m.mu.RLock()
+	sess, ok := m.sessions[name]
+	var target budget.Target
+	if ok {
+		target = budget.Target{Session: name, WorkingDir: sess.WorkingDir, ClaudeSessionID: sess.ClaudeSessionID}
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return
 	}
 
-	// First pass: check active pane
-	for _, p := range panes {
-		if !p.Active {
-			continue
+	now := time.Now()
+	spend := budget.Spend{Session: sessionCost}
+	if m.store != nil {
+		if stats, err := m.store.GetTodayStats(); err == nil {
+			spend.Daily = stats.DailyCost
 		}
-		content, err := m.tmux.CapturePane(session, p.WindowIndex, p.PaneIndex)
-		if err != nil {
+		spend.Weekly, _ = m.store.GetCostSince(budget.WeekStart(now).Format("2006-01-02"))
+		if m.budget.HasRepo(target.WorkingDir) {
+			if projectDir, err := usage.FindProjectDir(target.WorkingDir); err == nil {
+				y, mo, d := now.Date()
+				spend.Repo, _ = m.store.ProjectCostSince(projectDir, time.Date(y, mo, d, 0, 0, 0, 0, now.Location()))
+			}
+		}
+	}
+
+	for _, alert := range m.budget.Check(now, target, spend) {
+		m.emit(Event{
+			Type:    EventBudget,
+			Session: name,
+			Time:    now,
+			Message: alert.String(),
+		})
+		if alert.Stop {
+			m.stopForBudget(name, alert)
+		}
+	}
+}
+
+// stopForBudget holds the prompts of the sessions a reached limit covers
+// until acknowledged, interrupting the busy ones: the session itself for
+// session and repo limits, and every session for daily and weekly ones
+func (m *Monitor) stopForBudget(name string, alert budget.Alert) {
+	type stop struct {
+		name string
+		pane *tmux.Pane
+	}
+	var stops []stop
+
+	m.mu.Lock()
+	for n, sess := range m.sessions {
+		if n != name && alert.Scope != budget.Daily && alert.Scope != budget.Weekly {
 			continue
 		}
-		if m.detector.IsClaudeSession(content) {
-			pane := p
-			return &pane, content
+		sess.BudgetStop = alert.String()
+		if n == name || sess.State == claude.StateThinking || sess.State == claude.StateActive {
+			stops = append(stops, stop{n, sess.ClaudePane})
 		}
 	}
-	// Second pass: check all panes if active pane isn't Claude
+	m.mu.Unlock()
+
+	for _, s := range stops {
+		_ = m.tmux.SendKeysToPaneRaw(s.name, s.pane, "Escape")
+	}
+}
+
+// findAgentPane returns the pane running an agent, checking the active pane
+// first. Panes with a known agent win over the shell fallback.
+func (m *Monitor) findAgentPane(session string) (*tmux.Pane, string, agent.AgentDetector) {
+	panes, err := m.tmux.ListPanes(session)
+	if err != nil {
+		m.debugLog("%s: ListPanes error: %v", session, err)
+		return nil, "", nil
+	}
+	sort.SliceStable(panes, func(i, j int) bool {
+		return panes[i].Active && !panes[j].Active
+	})
+
+	var shellPane *tmux.Pane
+	var shellContent string
+	var shell agent.AgentDetector
 	for _, p := range panes {
 		content, err := m.tmux.CapturePane(session, p.WindowIndex, p.PaneIndex)
 		if err != nil {
 			continue
 		}
-		if m.detector.IsClaudeSession(content) {
-			pane := p
-			return &pane, content
+		det := m.agents.Detect(content)
+		if det == nil {
+			continue
+		}
+		pane := p
+		if det.Kind() != agent.Shell {
+			return &pane, content, det
+		}
+		if shell == nil {
+			shellPane, shellContent, shell = &pane, content, det
 		}
 	}
-	return nil, ""
+	return shellPane, shellContent, shell
 }
 
 func (m *Monitor) poll() {
@@ -260,69 +621,79 @@ func (m *Monitor) poll() {
 		m.mu.RLock()
 		existing, exists := m.sessions[ts.Name]
 		var knownPane *tmux.Pane
+		var known agent.AgentDetector
 		if exists {
 			knownPane = existing.ClaudePane
+			known = m.agents.Get(existing.Agent)
 		}
 		m.mu.RUnlock()
 
 		var content string
 		var claudePane *tmux.Pane
+		var det agent.AgentDetector
 
-		if knownPane != nil {
+		// Stay with the known agent while it's on screen. A shell is
+		// rechecked in case an agent was started in it.
+		if knownPane != nil && known != nil && known.Kind() != agent.Shell {
 			content, err = m.tmux.CapturePane(ts.Name, knownPane.WindowIndex, knownPane.PaneIndex)
-			if err == nil && m.detector.IsClaudeSession(content) {
-				claudePane = knownPane
-			} else {
-				claudePane, content = m.findClaudePane(ts.Name)
+			if err == nil && known.Matches(content) {
+				claudePane, det = knownPane, known
 			}
-		} else {
-			claudePane, content = m.findClaudePane(ts.Name)
+		}
+		if det == nil {
+			claudePane, content, det = m.findAgentPane(ts.Name)
 		}
 
-		if claudePane == nil {
+		if det == nil {
 			content, err = m.tmux.CapturePaneDefault(ts.Name)
 			if err != nil {
 				m.debugLog("%s: capture error: %v", ts.Name, err)
 				continue
 			}
-			if !m.detector.IsClaudeSession(content) {
+			if det = m.agents.Detect(content); det == nil {
 				continue
 			}
 		}
 
-		m.debugLog("%s: Claude=true pane=%v", ts.Name, claudePane)
+		m.debugLog("%s: agent=%s pane=%v", ts.Name, det.Kind(), claudePane)
 
 		m.mu.Lock()
 		existing, exists = m.sessions[ts.Name]
 
 		if !exists {
-			state := m.detector.DetectState(content, "", time.Time{})
-			info := m.detector.ParseInfo(content)
+			info := det.ParseInfo(content)
+			state := info.State
 
 			// Get working directory for usage tracking
 			workingDir, _ := m.tmux.GetSessionPath(ts.Name)
 
-			// Find and lock the Claude session ID for this tmux session
-			// Try to load persisted ID first, so usage survives restarts
 			var claudeSessionID string
 			var initialUsage *usage.SessionUsage
-			if m.store != nil {
-				claudeSessionID, _ = m.store.GetClaudeSessionID(ts.Name)
-			}
-			if claudeSessionID == "" && workingDir != "" {
-				claudeSessionID, _ = usage.FindActiveSessionID(workingDir)
-				// Persist it for next restart
-				if m.store != nil && claudeSessionID != "" {
-					_ = m.store.CreateSession(ts.Name)
-					_ = m.store.SetClaudeSessionID(ts.Name, claudeSessionID)
+			if det.Kind() == agent.Claude {
+				// Find and lock the Claude session ID for this tmux session
+				// Try to load persisted ID first, so usage survives restarts
+				if m.store != nil {
+					claudeSessionID, _ = m.store.GetClaudeSessionID(ts.Name)
 				}
-			}
-			if claudeSessionID != "" && workingDir != "" {
-				initialUsage, _ = usage.GetSessionByID(workingDir, claudeSessionID)
+				if claudeSessionID == "" && workingDir != "" {
+					claudeSessionID, _ = usage.FindActiveSessionID(workingDir)
+					// Persist it for next restart
+					if m.store != nil && claudeSessionID != "" && !m.readOnly {
+						_ = m.store.CreateSession(ts.Name)
+						_ = m.store.SetClaudeSessionID(ts.Name, claudeSessionID)
+					}
+				}
+				if claudeSessionID != "" && workingDir != "" {
+					initialUsage, _ = m.ingester.SessionUsage(workingDir, claudeSessionID)
+				}
+				m.linkUsageSession(claudeSessionID, ts.Name)
+			} else {
+				initialUsage = parseScreenUsage(det, content)
 			}
 
 			m.sessions[ts.Name] = &SessionState{
 				Name:            ts.Name,
+				Agent:           det.Kind(),
 				State:           state,
 				LastContent:     content,
 				LastCapture:     now,
@@ -335,26 +706,45 @@ func (m *Monitor) poll() {
 				WorkingDir:      workingDir,
 				Usage:           initialUsage,
 				ClaudeSessionID: claudeSessionID,
+				Permission:      info.Permission,
+				ScreenState:     state,
 			}
 
 			// Start watching for usage updates with the locked session ID
-			if workingDir != "" {
+			if det.Kind() == agent.Claude && workingDir != "" {
 				m.usageWatcher.WatchSession(ts.Name, workingDir, claudeSessionID)
 			}
 			m.mu.Unlock()
 
-			m.eventCh <- Event{
+			if initialUsage != nil && det.Kind() != agent.Claude {
+				m.recordCost(ts.Name, initialUsage.EstimatedCost)
+			}
+
+			m.emit(Event{
 				Type:    EventSessionDiscovered,
 				Session: ts.Name,
 				State:   state,
 				Time:    now,
-			}
+				Pattern: info.Pattern,
+			})
+		} else if content == existing.LastContent && (!existing.Hooked || existing.State == existing.ScreenState) {
+			// Nothing printed since the last poll: skip detection
+			existing.LastCapture = now
+			existing.Attached = ts.Attached
+			existing.ClaudePane = claudePane
+			m.mu.Unlock()
 		} else {
 			oldState := existing.State
-			newState := m.detector.DetectState(content, existing.LastContent, existing.LastCapture)
-			info := m.detector.ParseInfo(content)
+			newState := oldState
+			info := det.ParseInfo(content)
+			// Hook-driven sessions only use the screen for preview and stats,
+			// until hooks go quiet
+			if !existing.Hooked || hooksStale(existing, info.State, now) {
+				newState = info.State
+			}
 
 			existing.State = newState
+			existing.ScreenState = info.State
 			existing.LastContent = content
 			existing.LastCapture = now
 			existing.Tokens = info.Tokens
@@ -362,34 +752,47 @@ func (m *Monitor) poll() {
 			existing.LastLine = info.LastLine
 			existing.Attached = ts.Attached
 			existing.ClaudePane = claudePane
+			existing.Agent = det.Kind()
+			existing.Permission = info.Permission
+
+			screenUsage := parseScreenUsage(det, content)
+			if screenUsage != nil {
+				existing.Usage = screenUsage
+			}
 
 			m.mu.Unlock()
 
+			if screenUsage != nil {
+				m.recordCost(ts.Name, screenUsage.EstimatedCost)
+			}
+
 			if oldState != newState {
-				m.eventCh <- Event{
+				m.emit(Event{
 					Type:    EventStateChanged,
 					Session: ts.Name,
 					State:   newState,
 					Time:    now,
-				}
+					Pattern: info.Pattern,
+				})
 
 				if oldState == claude.StateThinking && (newState == claude.StateIdle || newState == claude.StateActive) {
-					m.eventCh <- Event{
+					m.emit(Event{
 						Type:    EventTaskCompleted,
 						Session: ts.Name,
 						State:   newState,
 						Time:    now,
-					}
+					})
 				}
 
 				if newState == claude.StateUrgent {
-					m.eventCh <- Event{
+					m.emit(Event{
 						Type:    EventUrgent,
 						Session: ts.Name,
 						State:   newState,
 						Time:    now,
-						Message: info.LastLine,
-					}
+						Message: urgentMessage(info),
+						Pattern: info.Pattern,
+					})
 				}
 			}
 		}
@@ -401,12 +804,148 @@ func (m *Monitor) poll() {
 			m.usageWatcher.UnwatchSession(name)
 			delete(m.sessions, name)
 			delete(m.lastCosts, name)
-			m.eventCh <- Event{
+			m.emit(Event{
 				Type:    EventSessionClosed,
 				Session: name,
 				Time:    now,
-			}
+			})
+		}
+	}
+	m.mu.Unlock()
+}
+
+// hooksStale reports whether the screen should decide a hooked session's
+// state again: no hook arrived for hookTimeout, or the screen shows Claude
+// idle while hooks say otherwise. Claude Code sends no hook when the user
+// interrupts it with Esc.
+func hooksStale(sess *SessionState, screen claude.SessionState, now time.Time) bool {
+	since := now.Sub(sess.LastHook)
+	if since >= hookTimeout {
+		return true
+	}
+	return screen == claude.StateIdle && sess.State != claude.StateIdle && since >= hookGrace
+}
+
+// urgentMessage describes what an urgent session is asking for
+func urgentMessage(info claude.SessionInfo) string {
+	if info.Permission != nil {
+		return info.Permission.Summary()
+	}
+	return info.LastLine
+}
+
+// parseScreenUsage reads usage from agents that print it, nil otherwise
+func parseScreenUsage(det agent.AgentDetector, content string) *usage.SessionUsage {
+	if p, ok := det.(agent.UsageParser); ok {
+		return p.ParseUsage(content)
+	}
+	return nil
+}
+
+// HandleHook applies a Claude Code hook to its session. The first hook marks
+// the session as hook-driven, which turns off regex state detection for it
+// while hooks keep arriving (see hooksStale).
+func (m *Monitor) HandleHook(h hooks.Hook) error {
+	now := time.Now()
+
+	m.mu.Lock()
+	sess := m.findHookSession(h)
+	if sess == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownSession, h.Event)
+	}
+
+	oldState := sess.State
+	newState := oldState
+	message := ""
+	switch h.Event {
+	case hooks.EventUserPromptSubmit, hooks.EventPreToolUse:
+		newState = claude.StateThinking
+	case hooks.EventStop:
+		newState = claude.StateIdle
+	case hooks.EventNotification:
+		message = h.Payload.Message
+		if hooks.IsIdleNotification(message) {
+			newState = claude.StateIdle
+		} else {
+			newState = claude.StateUrgent
 		}
+	default:
+		m.mu.Unlock()
+		return fmt.Errorf("unknown hook event: %s", h.Event)
+	}
+
+	sess.Hooked = true
+	sess.LastHook = now
+	sess.State = newState
+	linked := ""
+	if sess.ClaudeSessionID == "" && h.Payload.SessionID != "" {
+		sess.ClaudeSessionID = h.Payload.SessionID
+		linked = sess.ClaudeSessionID
 	}
+	name := sess.Name
+	pattern := "hook:" + h.Event
 	m.mu.Unlock()
+
+	m.linkUsageSession(linked, name)
+	m.debugLog("%s: hook %s -> %s", name, h.Event, newState)
+
+	if oldState != newState {
+		m.emit(Event{
+			Type:    EventStateChanged,
+			Session: name,
+			State:   newState,
+			Time:    now,
+			Pattern: pattern,
+		})
+	}
+
+	switch {
+	case h.Event == hooks.EventStop && oldState == claude.StateThinking:
+		m.emit(Event{
+			Type:    EventTaskCompleted,
+			Session: name,
+			State:   newState,
+			Time:    now,
+		})
+	case newState == claude.StateUrgent && oldState != claude.StateUrgent:
+		m.emit(Event{
+			Type:    EventUrgent,
+			Session: name,
+			State:   newState,
+			Time:    now,
+			Message: message,
+			Pattern: pattern,
+		})
+	}
+	return nil
+}
+
+// findHookSession matches a hook to a session by tmux session name, then
+// Claude session ID, then working directory. Caller must hold m.mu.
+func (m *Monitor) findHookSession(h hooks.Hook) *SessionState {
+	if sess, ok := m.sessions[h.Session]; ok {
+		return sess
+	}
+	if h.Payload.SessionID != "" {
+		for _, sess := range m.sessions {
+			if sess.ClaudeSessionID == h.Payload.SessionID {
+				return sess
+			}
+		}
+	}
+	if h.Payload.Cwd != "" {
+		var match *SessionState
+		for _, sess := range m.sessions {
+			// Only Claude sends hooks, whatever else runs in that directory
+			if sess.Agent == agent.Claude && sess.WorkingDir == h.Payload.Cwd {
+				if match != nil {
+					return nil // ambiguous
+				}
+				match = sess
+			}
+		}
+		return match
+	}
+	return nil
 }
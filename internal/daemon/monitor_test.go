@@ -0,0 +1,530 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/agent"
+	"github.com/valentindosimont/ccmanager/internal/budget"
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/hooks"
+	"github.com/valentindosimont/ccmanager/internal/store"
+	"github.com/valentindosimont/ccmanager/internal/tmux/tmuxtest"
+	"github.com/valentindosimont/ccmanager/internal/usage"
+)
+
+const (
+	claudeIdle     = "╭──────────────────╮\n│ ✻ Claude Code    │\n╰──────────────────╯\n\n❯ \n"
+	claudeThinking = "╭──────────────────╮\n│ ✻ Claude Code    │\n╰──────────────────╯\n\n✻ Thinking… (esc to interrupt)\n"
+	claudeUrgent   = "│ ✻ Claude Code    │\n\nBash command: rm -rf build\nDo you want to proceed? [y/N]\n"
+	shellPrompt    = "user@host:~/src$ ls\nREADME.md  go.mod\n"
+	aiderIdle      = "Aider v0.86.1\nMain model: gpt-4o with diff edit format\n\n" +
+		"Tokens: 2.5k sent, 120 received. Cost: $0.01 message, $0.04 session.\n> \n"
+)
+
+func newTestMonitor(fake *tmuxtest.Fake) *Monitor {
+	return NewMonitor(time.Second, nil, fake)
+}
+
+// drainEvents returns the events emitted so far, skipping debug output
+func drainEvents(m *Monitor) []Event {
+	var events []Event
+	for {
+		select {
+		case e := <-m.Events():
+			if e.Type != EventDebug {
+				events = append(events, e)
+			}
+		default:
+			return events
+		}
+	}
+}
+
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func assertEvents(t *testing.T, got []Event, want ...EventType) {
+	t.Helper()
+	types := eventTypes(got)
+	if len(types) != len(want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("events = %v, want %v", types, want)
+		}
+	}
+}
+
+func TestPollDiscoversClaudeSessions(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeIdle)
+	fake.AddSession("scratch", "", shellPrompt)
+
+	m := newTestMonitor(fake)
+	m.poll()
+
+	events := drainEvents(m)
+	assertEvents(t, events, EventSessionDiscovered)
+	if events[0].Session != "api" || events[0].State != claude.StateIdle {
+		t.Errorf("discovered %s in %s, want api in IDLE", events[0].Session, events[0].State)
+	}
+
+	sessions := m.Snapshot()
+	if len(sessions) != 1 || sessions[0].Name != "api" {
+		t.Fatalf("sessions = %+v, want only api", sessions)
+	}
+	if sessions[0].ClaudePane == nil || sessions[0].ClaudePane.WindowIndex != 0 || sessions[0].ClaudePane.PaneIndex != 0 {
+		t.Errorf("ClaudePane = %+v, want 0.0", sessions[0].ClaudePane)
+	}
+
+	// A second poll with nothing changed emits nothing
+	m.poll()
+	assertEvents(t, drainEvents(m))
+}
+
+func TestPollFindsClaudeInInactivePane(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", shellPrompt)
+	fake.AddPane("api", 0, 1, claudeIdle)
+
+	m := newTestMonitor(fake)
+	m.poll()
+
+	sess := m.GetSession("api")
+	if sess == nil {
+		t.Fatal("session with Claude in a non-active pane was not discovered")
+	}
+	if sess.ClaudePane == nil || sess.ClaudePane.PaneIndex != 1 {
+		t.Errorf("ClaudePane = %+v, want 0.1", sess.ClaudePane)
+	}
+}
+
+func TestPollRecordsAgent(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeIdle)
+	fake.AddSession("refactor", "", aiderIdle)
+
+	m := newTestMonitor(fake)
+	m.poll()
+	assertEvents(t, drainEvents(m), EventSessionDiscovered, EventSessionDiscovered)
+
+	if got := m.GetSession("api").Agent; got != agent.Claude {
+		t.Errorf("api agent = %s, want claude", got)
+	}
+	sess := m.GetSession("refactor")
+	if sess.Agent != agent.Aider || sess.State != claude.StateIdle {
+		t.Fatalf("refactor = %s in %s, want aider in IDLE", sess.Agent, sess.State)
+	}
+	// Aider's usage comes from its on-screen token report
+	if sess.Usage == nil || sess.Usage.EstimatedCost != 0.04 || sess.Usage.TotalUsage.InputTokens != 2500 {
+		t.Errorf("refactor usage = %+v, want 2500 input tokens and $0.04", sess.Usage)
+	}
+}
+
+func TestPollPrefersAgentOverShell(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", shellPrompt)
+	fake.AddPane("api", 1, 0, claudeIdle)
+	fake.AddSession("scratch", "", shellPrompt)
+
+	m := newTestMonitor(fake)
+	m.SetAgents(agent.NewRegistry(agent.NewClaude(claude.NewDetector()), agent.NewAider(), agent.NewShell()))
+	m.poll()
+
+	sess := m.GetSession("api")
+	if sess == nil || sess.Agent != agent.Claude {
+		t.Fatalf("api = %+v, want a Claude session", sess)
+	}
+	if sess.ClaudePane == nil || sess.ClaudePane.WindowIndex != 1 {
+		t.Errorf("ClaudePane = %+v, want 1.0 over the active shell", sess.ClaudePane)
+	}
+	if sess := m.GetSession("scratch"); sess == nil || sess.Agent != agent.Shell {
+		t.Errorf("scratch = %+v, want a shell session", sess)
+	}
+
+	// Starting an agent in a tracked shell switches the session over
+	fake.SetContent("scratch", 0, 0, aiderIdle)
+	m.poll()
+	if got := m.GetSession("scratch").Agent; got != agent.Aider {
+		t.Errorf("scratch agent = %s, want aider", got)
+	}
+}
+
+func TestPollPaneMigration(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeIdle)
+	fake.AddPane("api", 1, 0, shellPrompt)
+
+	m := newTestMonitor(fake)
+	m.poll()
+	drainEvents(m)
+
+	// Claude exits in 0.0 and is restarted in window 1
+	fake.SetContent("api", 0, 0, shellPrompt)
+	fake.SetContent("api", 1, 0, claudeThinking)
+	m.poll()
+
+	sess := m.GetSession("api")
+	if sess == nil {
+		t.Fatal("session lost after pane migration")
+	}
+	if sess.ClaudePane == nil || sess.ClaudePane.WindowIndex != 1 || sess.ClaudePane.PaneIndex != 0 {
+		t.Errorf("ClaudePane = %+v, want 1.0", sess.ClaudePane)
+	}
+	if sess.State != claude.StateThinking {
+		t.Errorf("State = %s, want THINKING", sess.State)
+	}
+	assertEvents(t, drainEvents(m), EventStateChanged)
+}
+
+func TestPollStateTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []EventType
+	}{
+		{
+			name: "thinking to idle completes a task",
+			from: claudeThinking,
+			to:   claudeIdle,
+			want: []EventType{EventStateChanged, EventTaskCompleted},
+		},
+		{
+			name: "idle to thinking",
+			from: claudeIdle,
+			to:   claudeThinking,
+			want: []EventType{EventStateChanged},
+		},
+		{
+			name: "permission prompt is urgent",
+			from: claudeThinking,
+			to:   claudeUrgent,
+			want: []EventType{EventStateChanged, EventUrgent},
+		},
+		{
+			name: "unchanged content",
+			from: claudeThinking,
+			to:   claudeThinking,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tmuxtest.New()
+			fake.AddSession("api", "", tt.from)
+
+			m := newTestMonitor(fake)
+			m.poll()
+			drainEvents(m)
+
+			fake.SetContent("api", 0, 0, tt.to)
+			m.poll()
+
+			events := drainEvents(m)
+			assertEvents(t, events, tt.want...)
+			for _, e := range events {
+				if e.Session != "api" {
+					t.Errorf("event %s for session %q, want api", e.Type, e.Session)
+				}
+				if e.Type == EventUrgent && e.Pattern == "" {
+					t.Error("urgent event without the pattern that matched")
+				}
+			}
+		})
+	}
+}
+
+func TestPollSessionClosed(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeIdle)
+	fake.AddSession("web", "", claudeIdle)
+
+	m := newTestMonitor(fake)
+	m.poll()
+	drainEvents(m)
+
+	if err := fake.KillSession("api"); err != nil {
+		t.Fatal(err)
+	}
+	m.poll()
+
+	events := drainEvents(m)
+	assertEvents(t, events, EventSessionClosed)
+	if events[0].Session != "api" {
+		t.Errorf("closed %q, want api", events[0].Session)
+	}
+	if m.GetSession("api") != nil {
+		t.Error("closed session still tracked")
+	}
+	if m.GetSession("web") == nil {
+		t.Error("open session dropped")
+	}
+}
+
+func TestPollNoServer(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeIdle)
+
+	m := newTestMonitor(fake)
+	m.poll()
+	drainEvents(m)
+
+	// A stopped server is not treated as every session closing
+	fake.SetRunning(false)
+	m.poll()
+
+	assertEvents(t, drainEvents(m))
+	if m.GetSession("api") == nil {
+		t.Error("session dropped while tmux was unreachable")
+	}
+}
+
+func TestHookedSessionSkipsDetection(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeIdle)
+
+	m := newTestMonitor(fake)
+	m.poll()
+	drainEvents(m)
+
+	if err := m.HandleHook(hooks.Hook{Event: hooks.EventUserPromptSubmit, Session: "api"}); err != nil {
+		t.Fatalf("HandleHook: %v", err)
+	}
+	assertEvents(t, drainEvents(m), EventStateChanged)
+
+	// The screen still looks idle, but hooks are authoritative
+	fake.SetContent("api", 0, 0, claudeIdle+"\n")
+	m.poll()
+	assertEvents(t, drainEvents(m))
+	if state := m.GetSession("api").State; state != claude.StateThinking {
+		t.Errorf("State = %s, want THINKING", state)
+	}
+
+	if err := m.HandleHook(hooks.Hook{Event: hooks.EventStop, Session: "api"}); err != nil {
+		t.Fatalf("HandleHook: %v", err)
+	}
+	assertEvents(t, drainEvents(m), EventStateChanged, EventTaskCompleted)
+}
+
+func TestHookedSessionFallsBackToScreen(t *testing.T) {
+	tests := []struct {
+		name     string
+		hook     string
+		hookAge  time.Duration
+		screen   string
+		want     claude.SessionState
+		wantDone bool // task completed
+	}{
+		// Esc sends no Stop hook
+		{name: "interrupted", hook: hooks.EventPreToolUse, hookAge: 5 * time.Second, screen: claudeIdle, want: claude.StateIdle, wantDone: true},
+		{name: "dialog dismissed", hook: hooks.EventNotification, hookAge: 5 * time.Second, screen: claudeIdle, want: claude.StateIdle},
+		{name: "screen lagging the hook", hook: hooks.EventPreToolUse, screen: claudeIdle, want: claude.StateThinking},
+		{name: "still thinking", hook: hooks.EventPreToolUse, hookAge: 5 * time.Second, screen: claudeThinking, want: claude.StateThinking},
+		{name: "hooks went quiet", hook: hooks.EventStop, hookAge: hookTimeout, screen: claudeUrgent, want: claude.StateUrgent},
+		{name: "recent hook", hook: hooks.EventStop, hookAge: time.Minute, screen: claudeUrgent, want: claude.StateIdle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tmuxtest.New()
+			fake.AddSession("api", "", claudeThinking)
+			m := newTestMonitor(fake)
+			m.poll()
+			if err := m.HandleHook(hooks.Hook{Event: tt.hook, Session: "api", Payload: hooks.Payload{Message: "Claude needs your permission to use Bash"}}); err != nil {
+				t.Fatalf("HandleHook: %v", err)
+			}
+			m.GetSession("api").LastHook = time.Now().Add(-tt.hookAge)
+			drainEvents(m)
+
+			fake.SetContent("api", 0, 0, tt.screen)
+			m.poll()
+			// The screen is read again while it disagrees with the hooks
+			m.poll()
+			if got := m.GetSession("api").State; got != tt.want {
+				t.Errorf("State = %s, want %s", got, tt.want)
+			}
+			done := false
+			for _, e := range drainEvents(m) {
+				done = done || e.Type == EventTaskCompleted
+			}
+			if done != tt.wantDone {
+				t.Errorf("task completed = %v, want %v", done, tt.wantDone)
+			}
+		})
+	}
+
+	// Interrupted right after a hook: the screen stops changing, but is
+	// still read once the grace period is over
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeIdle)
+	m := newTestMonitor(fake)
+	m.poll()
+	if err := m.HandleHook(hooks.Hook{Event: hooks.EventUserPromptSubmit, Session: "api"}); err != nil {
+		t.Fatalf("HandleHook: %v", err)
+	}
+	m.poll()
+	if got := m.GetSession("api").State; got != claude.StateThinking {
+		t.Fatalf("State = %s within the grace period, want THINKING", got)
+	}
+	m.GetSession("api").LastHook = time.Now().Add(-hookGrace)
+	m.poll()
+	if got := m.GetSession("api").State; got != claude.StateIdle {
+		t.Errorf("State = %s on an unchanged idle screen, want IDLE", got)
+	}
+}
+
+func TestPollParsesPermission(t *testing.T) {
+	dialog := "│ ✻ Claude Code    │\n\n Bash command\n\n   go test ./...\n   Run the tests\n\n" +
+		" Do you want to proceed?\n ❯ 1. Yes\n   2. No, and tell Claude what to do differently (esc)\n"
+
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeThinking)
+
+	m := newTestMonitor(fake)
+	m.poll()
+	drainEvents(m)
+
+	fake.SetContent("api", 0, 0, dialog)
+	m.poll()
+
+	events := drainEvents(m)
+	assertEvents(t, events, EventStateChanged, EventUrgent)
+	if events[1].Message != "Bash: go test ./..." {
+		t.Errorf("urgent message = %q, want the command", events[1].Message)
+	}
+	if p := m.GetSession("api").Permission; p == nil || len(p.Options) != 2 {
+		t.Fatalf("Permission = %+v, want the parsed dialog", p)
+	}
+
+	// Answering clears it
+	fake.SetContent("api", 0, 0, claudeThinking)
+	m.poll()
+	if p := m.GetSession("api").Permission; p != nil {
+		t.Errorf("Permission = %+v after the dialog closed", p)
+	}
+}
+
+func TestBudgetHardStop(t *testing.T) {
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeThinking)
+
+	m := newTestMonitor(fake)
+	b, err := budget.New(config.BudgetConfig{Session: 2, HardStop: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetBudget(b)
+	m.poll()
+	drainEvents(m)
+
+	m.recordCost("api", 0.5)
+	m.recordCost("api", 1.2)
+	events := drainEvents(m)
+	assertEvents(t, events, EventBudget)
+	if events[0].Message != "session budget 50% used: $1.20 of $2.00" {
+		t.Errorf("message = %q", events[0].Message)
+	}
+	if len(fake.Sent()) != 0 || m.GetSession("api").BudgetStop != "" {
+		t.Fatal("stopped the session below the limit")
+	}
+
+	m.recordCost("api", 2.1)
+	assertEvents(t, drainEvents(m), EventBudget)
+	sent := fake.Sent()
+	if len(sent) != 1 || sent[0].Keys != "Escape" || !sent[0].Raw {
+		t.Errorf("sent = %+v, want Escape", sent)
+	}
+	if m.GetSession("api").BudgetStop == "" {
+		t.Fatal("BudgetStop not set at the limit")
+	}
+
+	if !m.AcknowledgeBudgetStop("api") || m.GetSession("api").BudgetStop != "" {
+		t.Error("AcknowledgeBudgetStop didn't clear the stop")
+	}
+	if m.AcknowledgeBudgetStop("api") {
+		t.Error("AcknowledgeBudgetStop = true with no stop")
+	}
+
+	// A read-only monitor leaves budgets to the one owning the sessions
+	follower := newTestMonitor(fake)
+	follower.SetReadOnly(true)
+	follower.SetBudget(b)
+	follower.poll()
+	drainEvents(follower)
+	follower.recordCost("api", 3)
+	follower.recordCost("api", 5)
+	if events := drainEvents(follower); len(events) != 0 {
+		t.Errorf("read-only monitor emitted %+v", events)
+	}
+	if len(fake.Sent()) != 1 || follower.GetSession("api").BudgetStop != "" {
+		t.Error("read-only monitor stopped the session")
+	}
+}
+
+func TestDailyBudgetStopHoldsEverySession(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = st.Close() }()
+
+	fake := tmuxtest.New()
+	fake.AddSession("api", "", claudeThinking)
+	fake.AddSession("web", "", claudeIdle)
+
+	m := NewMonitor(time.Second, st, fake)
+	b, err := budget.New(config.BudgetConfig{Daily: 2, HardStop: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetBudget(b)
+	m.poll()
+	drainEvents(m)
+
+	m.recordCost("api", 0.5)
+	m.recordCost("api", 2.5)
+	drainEvents(m)
+
+	// Only the busy session is interrupted, but the idle one is held too
+	sent := fake.Sent()
+	if len(sent) != 1 || sent[0].Session != "api" || sent[0].Keys != "Escape" {
+		t.Errorf("sent = %+v, want Escape to api", sent)
+	}
+	for _, name := range []string{"api", "web"} {
+		if m.GetSession(name).BudgetStop == "" {
+			t.Errorf("%s not held by the daily limit", name)
+		}
+	}
+}
+
+func TestNewCompaction(t *testing.T) {
+	early := &usage.Compaction{Time: time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)}
+	late := &usage.Compaction{Time: early.Time.Add(time.Hour)}
+	tests := []struct {
+		name       string
+		prev, next *usage.SessionUsage
+		want       bool
+	}{
+		{"discovered compacted", nil, &usage.SessionUsage{Compaction: early}, false},
+		{"first compaction", &usage.SessionUsage{}, &usage.SessionUsage{Compaction: early}, true},
+		{"same compaction", &usage.SessionUsage{Compaction: early}, &usage.SessionUsage{Compaction: early}, false},
+		{"another compaction", &usage.SessionUsage{Compaction: early}, &usage.SessionUsage{Compaction: late}, true},
+		{"never compacted", &usage.SessionUsage{}, &usage.SessionUsage{}, false},
+	}
+	for _, tt := range tests {
+		if got := newCompaction(tt.prev, tt.next); got != tt.want {
+			t.Errorf("%s: newCompaction() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrAlreadyRunning is returned when another daemon holds the PID file
+var ErrAlreadyRunning = errors.New("daemon already running")
+
+// RunningPID returns the PID of a live daemon recorded in the PID file, or 0
+func RunningPID(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	// Signal 0 checks for existence without delivering anything
+	if err := syscall.Kill(pid, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return 0
+	}
+	return pid
+}
+
+// AcquirePIDFile records the current process as the running daemon.
+// The returned function removes the PID file.
+func AcquirePIDFile(path string) (func(), error) {
+	if pid := RunningPID(path); pid != 0 && pid != os.Getpid() {
+		return nil, fmt.Errorf("%w (pid %d)", ErrAlreadyRunning, pid)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create pid directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("write pid file: %w", err)
+	}
+
+	return func() { _ = os.Remove(path) }, nil
+}
@@ -0,0 +1,73 @@
+package game
+
+import "time"
+
+// Metric is what an achievement measures
+type Metric int
+
+const (
+	MetricAPM              Metric = iota // actions per minute, as the HUD shows it
+	MetricThinkingSessions               // sessions thinking at once
+	MetricQuickUrgents                   // urgent prompts answered within QuickUrgentWindow today
+	MetricPomodorosToday                 // work periods completed today
+	MetricTasksToday                     // tasks completed today
+	MetricScoreToday                     // points scored today
+)
+
+// QuickUrgentWindow is how soon an urgent prompt must be answered to count
+// as a quick answer
+const QuickUrgentWindow = 5 * time.Second
+
+// Achievement is unlocked the first time its metric reaches Target
+type Achievement struct {
+	ID          string
+	Name        string
+	Description string
+	Metric      Metric
+	Target      int
+}
+
+// Achievements lists every achievement in display order
+var Achievements = []Achievement{
+	{ID: "first_blood", Name: "First Blood", Description: "Complete a task", Metric: MetricTasksToday, Target: 1},
+	{ID: "macro_hand", Name: "Macro Hand", Description: "Reach 150 APM", Metric: MetricAPM, Target: 150},
+	{ID: "multitasker", Name: "Multitasker", Description: "Have 5 sessions thinking at once", Metric: MetricThinkingSessions, Target: 5},
+	{ID: "zero_queue", Name: "Zero Queue", Description: "Handle 10 urgent prompts within 5s each in a day", Metric: MetricQuickUrgents, Target: 10},
+	{ID: "marathon", Name: "Marathon", Description: "Complete 8 pomodoros in a day", Metric: MetricPomodorosToday, Target: 8},
+	{ID: "workhorse", Name: "Workhorse", Description: "Complete 50 tasks in a day", Metric: MetricTasksToday, Target: 50},
+	{ID: "high_score", Name: "High Score", Description: "Score 50,000 points in a day", Metric: MetricScoreToday, Target: 50000},
+}
+
+// achievementTracker keeps the daily counts achievements measure and which
+// are unlocked. The engine's lock guards it.
+type achievementTracker struct {
+	quickUrgents int
+	pomodoros    int
+	tasks        int
+
+	thinking map[string]bool // sessions thinking now
+
+	unlocked map[string]bool
+	pending  []Achievement // unlocked since the last TakeUnlocked
+}
+
+func newAchievementTracker() *achievementTracker {
+	return &achievementTracker{thinking: make(map[string]bool), unlocked: make(map[string]bool)}
+}
+
+// resetDaily starts the daily counts over
+func (t *achievementTracker) resetDaily() {
+	t.quickUrgents = 0
+	t.pomodoros = 0
+	t.tasks = 0
+}
+
+// check unlocks the achievements of metric that value reaches
+func (t *achievementTracker) check(metric Metric, value int) {
+	for _, a := range Achievements {
+		if a.Metric == metric && value >= a.Target && !t.unlocked[a.ID] {
+			t.unlocked[a.ID] = true
+			t.pending = append(t.pending, a)
+		}
+	}
+}
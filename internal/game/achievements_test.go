@@ -0,0 +1,113 @@
+package game
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func unlockedIDs(e *Engine) []string {
+	var ids []string
+	for _, a := range e.TakeUnlocked() {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+func TestAchievementsUnlock(t *testing.T) {
+	tests := []struct {
+		name string
+		play func(e *Engine)
+		want []string
+	}{
+		{"first task", func(e *Engine) { e.RecordTaskComplete() }, []string{"first_blood"}},
+		// Actions within a second count as a second's worth
+		{"120 APM", func(e *Engine) {
+			for i := 0; i < 2; i++ {
+				e.RecordAction(ActionKeypress)
+			}
+		}, nil},
+		{"180 APM", func(e *Engine) {
+			for i := 0; i < 3; i++ {
+				e.RecordAction(ActionKeypress)
+			}
+		}, []string{"macro_hand"}},
+		{"5 active sessions", func(e *Engine) {
+			for i := 0; i < 5; i++ {
+				e.SetSessionActivity(fmt.Sprintf("s%d", i), true)
+			}
+		}, nil},
+		{"4 thinking sessions and one that stopped", func(e *Engine) {
+			for i := 0; i < 4; i++ {
+				e.SetSessionThinking(fmt.Sprintf("s%d", i), true)
+			}
+			e.SetSessionThinking("s0", false)
+			e.SetSessionThinking("s1", true)
+			e.SetSessionThinking("s4", true)
+		}, nil},
+		{"5 thinking sessions", func(e *Engine) {
+			for i := 0; i < 5; i++ {
+				e.SetSessionThinking(fmt.Sprintf("s%d", i), true)
+			}
+		}, []string{"multitasker"}},
+		{"9 quick urgents and a slow one", func(e *Engine) {
+			for i := 0; i < 9; i++ {
+				e.RecordUrgentHandled(time.Second)
+			}
+			e.RecordUrgentHandled(time.Minute)
+		}, nil},
+		{"10 quick urgents", func(e *Engine) {
+			for i := 0; i < 10; i++ {
+				e.RecordUrgentHandled(QuickUrgentWindow)
+			}
+		}, []string{"zero_queue"}},
+		{"8 pomodoros", func(e *Engine) {
+			e.Pomodoro().Start()
+			for i := 0; i < 16; i++ { // zero-length work and break periods
+				e.Tick()
+			}
+		}, []string{"marathon"}},
+		{"50 tasks", func(e *Engine) {
+			for i := 0; i < 50; i++ {
+				e.RecordTaskComplete()
+			}
+		}, []string{"first_blood", "workhorse"}},
+		{"100 urgents", func(e *Engine) {
+			for i := 0; i < 100; i++ {
+				e.RecordUrgentHandled(time.Second)
+			}
+		}, []string{"zero_queue", "high_score"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultEngineConfig()
+			cfg.PomodoroWorkMinutes, cfg.PomodoroShortBreakMinutes, cfg.PomodoroLongBreakMinutes = 0, 0, 0
+			e := NewEngine(cfg)
+			tt.play(e)
+			if got := unlockedIDs(e); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unlocked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAchievementsUnlockOnce(t *testing.T) {
+	e := NewEngine(DefaultEngineConfig())
+	e.LoadAchievements([]string{"first_blood"})
+	e.RecordTaskComplete()
+	if got := unlockedIDs(e); got != nil {
+		t.Errorf("unlocked %v, want nothing already unlocked", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		e.SetSessionThinking(fmt.Sprintf("s%d", i), true)
+	}
+	e.SetSessionThinking("s5", true)
+	if got := unlockedIDs(e); !reflect.DeepEqual(got, []string{"multitasker"}) {
+		t.Errorf("unlocked %v, want multitasker once", got)
+	}
+	if got := e.TakeUnlocked(); got != nil {
+		t.Errorf("TakeUnlocked() again = %v, want nothing", got)
+	}
+}
@@ -62,7 +62,6 @@ type Engine struct {
 
 	// Core state
 	dailyScore    int
-	totalScore    int
 	lastScoreDate string
 	apm           *APMTracker
 	streak        *StreakTracker
@@ -72,16 +71,33 @@ type Engine struct {
 	// Focus tracking
 	focusSession string
 	focusStart   time.Time
+
+	// pomodoroElsewhere is set when another process runs the pomodoro
+	pomodoroElsewhere bool
+
+	achievements *achievementTracker
+
+	// Lifetime XP is pastXP plus the daily score
+	pastXP  int // from the days before lastScoreDate
+	level   int
+	levelUp bool // a level was reached since the last TakeLevelUp
+
+	// Daily stats not yet taken
+	day      DayStats
+	pastDays []DayStats
+	lastTick time.Time
 }
 
 // NewEngine creates a new game engine
 func NewEngine(cfg EngineConfig) *Engine {
 	return &Engine{
-		config:      cfg,
-		apm:         NewAPMTracker(cfg.APMWindowSeconds),
-		streak:      NewStreakTracker(cfg.StreakTimeoutSeconds, cfg.StreakMultiplierCap),
-		pomodoro:    NewPomodoroTimer(cfg.PomodoroWorkMinutes, cfg.PomodoroShortBreakMinutes, cfg.PomodoroLongBreakMinutes, cfg.PomodorosBeforeLongBreak),
-		controlGrps: NewControlGroups(cfg.DoubleTapThresholdMs),
+		config:       cfg,
+		apm:          NewAPMTracker(cfg.APMWindowSeconds),
+		streak:       NewStreakTracker(cfg.StreakTimeoutSeconds, cfg.StreakMultiplierCap),
+		pomodoro:     NewPomodoroTimer(cfg.PomodoroWorkMinutes, cfg.PomodoroShortBreakMinutes, cfg.PomodoroLongBreakMinutes, cfg.PomodorosBeforeLongBreak),
+		controlGrps:  NewControlGroups(cfg.DoubleTapThresholdMs),
+		achievements: newAchievementTracker(),
+		level:        1,
 	}
 }
 
@@ -90,10 +106,14 @@ func (e *Engine) Config() EngineConfig {
 }
 
 func (e *Engine) checkDailyReset() {
-	today := time.Now().Format("2006-01-02")
+	now := time.Now()
+	e.today(now) // sets aside yesterday's stats with its score
+	today := now.Format("2006-01-02")
 	if e.lastScoreDate != today {
+		e.pastXP += e.dailyScore
 		e.dailyScore = 0
 		e.lastScoreDate = today
+		e.achievements.resetDaily()
 	}
 }
 
@@ -121,7 +141,31 @@ func (e *Engine) RecordTaskComplete() int {
 	points := int(float64(basePoints) * mult)
 
 	e.dailyScore += points
-	e.totalScore += points
+	e.checkLevel()
+
+	e.achievements.tasks++
+	e.achievements.check(MetricTasksToday, e.achievements.tasks)
+	e.achievements.check(MetricScoreToday, e.dailyScore)
+	return points
+}
+
+// RecordUrgentHandled awards points for answering an urgent prompt by hand,
+// waited after it appeared
+func (e *Engine) RecordUrgentHandled(waited time.Duration) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.checkDailyReset()
+
+	points := int(float64(e.config.PointsUrgentHandled) * e.calculateMultiplier())
+	e.dailyScore += points
+	e.checkLevel()
+
+	if waited <= QuickUrgentWindow {
+		e.achievements.quickUrgents++
+		e.achievements.check(MetricQuickUrgents, e.achievements.quickUrgents)
+	}
+	e.achievements.check(MetricScoreToday, e.dailyScore)
 	return points
 }
 
@@ -134,6 +178,8 @@ func (e *Engine) RecordAction(actionType ActionType) int {
 
 	// Update APM
 	e.apm.RecordAction(now)
+	e.today(now).Actions++
+	e.achievements.check(MetricAPM, e.apm.Current())
 
 	return 0
 }
@@ -157,11 +203,20 @@ func (e *Engine) Score() int {
 	return e.dailyScore
 }
 
-// TotalScore returns the total score
-func (e *Engine) TotalScore() int {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-	return e.totalScore
+// XP returns the lifetime XP: every day's score, today's included
+func (e *Engine) XP() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checkDailyReset()
+	return e.pastXP + e.dailyScore
+}
+
+// checkLevel notes a level-up when the XP reaches a new level
+func (e *Engine) checkLevel() {
+	if n := LevelFor(e.pastXP + e.dailyScore).Number; n > e.level {
+		e.level = n
+		e.levelUp = true
+	}
 }
 
 // APM returns the current APM
@@ -190,6 +245,22 @@ func (e *Engine) SetSessionActivity(name string, isActive bool) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.streak.SetSessionActive(name, isActive)
+	if day := e.today(time.Now()); e.streak.Multiplier() > day.MaxStreak {
+		day.MaxStreak = e.streak.Multiplier()
+	}
+}
+
+// SetSessionThinking updates whether a session is thinking. Unlike activity,
+// it leaves out sessions the detector could only tell are not idle.
+func (e *Engine) SetSessionThinking(name string, thinking bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if thinking {
+		e.achievements.thinking[name] = true
+	} else {
+		delete(e.achievements.thinking, name)
+	}
+	e.achievements.check(MetricThinkingSessions, len(e.achievements.thinking))
 }
 
 // RemoveSession removes a session from activity tracking
@@ -197,6 +268,7 @@ func (e *Engine) RemoveSession(name string) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.streak.SetSessionActive(name, false)
+	delete(e.achievements.thinking, name)
 }
 
 // Pomodoro returns the pomodoro timer
@@ -216,7 +288,57 @@ func (e *Engine) Tick() {
 
 	now := time.Now()
 	e.apm.Tick(now)
+
+	if e.pomodoroElsewhere {
+		e.lastTick = now
+		return
+	}
+
+	working := e.pomodoro.IsWorking()
+	if working && e.focusSession != "" && e.streak.IsActive(e.focusSession) && !e.lastTick.IsZero() {
+		e.today(now).FlowTime += now.Sub(e.lastTick)
+	}
+	e.lastTick = now
+
 	e.pomodoro.Tick(now)
+	if working && !e.pomodoro.IsWorking() {
+		e.checkDailyReset()
+		e.today(now).Pomodoros++
+		e.achievements.pomodoros++
+		e.achievements.check(MetricPomodorosToday, e.achievements.pomodoros)
+	}
+}
+
+// LeavePomodoro leaves the pomodoro to another process: the timer is
+// stopped, even if restored by LoadState, and Tick no longer runs or counts
+// it
+func (e *Engine) LeavePomodoro() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.pomodoroElsewhere = true
+	e.pomodoro.Stop()
+}
+
+// LoadAchievements marks achievements unlocked earlier, so they aren't
+// unlocked again
+func (e *Engine) LoadAchievements(ids []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, id := range ids {
+		e.achievements.unlocked[id] = true
+	}
+}
+
+// TakeUnlocked returns the achievements unlocked since the last call
+func (e *Engine) TakeUnlocked() []Achievement {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	unlocked := e.achievements.pending
+	e.achievements.pending = nil
+	return unlocked
 }
 
 // LoadState loads game state from persistence
@@ -225,11 +347,50 @@ func (e *Engine) LoadState(score int, lastScoreDate string, pomodoroState string
 	defer e.mu.Unlock()
 
 	e.dailyScore = score
-	e.totalScore = score
 	e.lastScoreDate = lastScoreDate
+	e.level = LevelFor(e.pastXP + e.dailyScore).Number
 	e.pomodoro.SetState(pomodoroState, time.Duration(pomodoroRemaining)*time.Second)
 }
 
+// LoadXP sets the XP earned before the day of the loaded score, from the
+// persisted daily scores. It doesn't count as levelling up.
+func (e *Engine) LoadXP(pastXP int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.pastXP = pastXP
+	e.level = LevelFor(e.pastXP + e.dailyScore).Number
+}
+
+// TakeLevelUp returns the level reached since the last call, if any
+func (e *Engine) TakeLevelUp() (Level, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.levelUp {
+		return Level{}, false
+	}
+	e.levelUp = false
+	return LevelFor(e.pastXP + e.dailyScore), true
+}
+
+// SyncScore replaces the daily score with one persisted by another process.
+// A score of an earlier day than this engine's is stale and ignored.
+func (e *Engine) SyncScore(score int, lastScoreDate string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if lastScoreDate < e.lastScoreDate {
+		return
+	}
+	if lastScoreDate != e.lastScoreDate {
+		e.pastXP += e.dailyScore
+	}
+	e.dailyScore = score
+	e.lastScoreDate = lastScoreDate
+	e.checkLevel()
+}
+
 // State returns current state for persistence
 func (e *Engine) State() (score int, lastScoreDate string, pomodoroState string, pomodoroRemaining int) {
 	e.mu.RLock()
@@ -0,0 +1,64 @@
+package game
+
+// xpPerLevel is how much more XP each level takes than the one before: level
+// 2 takes 1,000 XP, level 3 another 2,000, and so on
+const xpPerLevel = 1000
+
+// Rank is a step of the rank ladder, reached at MinLevel
+type Rank struct {
+	Name     string
+	MinLevel int
+}
+
+// Ranks is the rank ladder, lowest first
+var Ranks = []Rank{
+	{Name: "Bronze", MinLevel: 1},
+	{Name: "Silver", MinLevel: 10},
+	{Name: "Gold", MinLevel: 20},
+	{Name: "Platinum", MinLevel: 30},
+	{Name: "Diamond", MinLevel: 40},
+	{Name: "Master", MinLevel: 50},
+	{Name: "Grandmaster", MinLevel: 75},
+}
+
+// Level is where an amount of lifetime XP stands on the ladder
+type Level struct {
+	Number int
+	Rank   Rank
+	XP     int
+	Start  int // XP at which Number was reached
+	Next   int // XP at which Number+1 is reached
+}
+
+// LevelXP returns the lifetime XP at which level is reached
+func LevelXP(level int) int {
+	return xpPerLevel * level * (level - 1) / 2
+}
+
+// LevelFor returns the level xp reaches
+func LevelFor(xp int) Level {
+	n := 1
+	for LevelXP(n+1) <= xp {
+		n++
+	}
+	l := Level{Number: n, Rank: Ranks[0], XP: xp, Start: LevelXP(n), Next: LevelXP(n + 1)}
+	for _, r := range Ranks {
+		if n >= r.MinLevel {
+			l.Rank = r
+		}
+	}
+	return l
+}
+
+// Promoted reports whether the level is the first of a rank above the lowest
+func (l Level) Promoted() bool {
+	return l.Number == l.Rank.MinLevel && l.Number > Ranks[0].MinLevel
+}
+
+// Progress returns how far the XP is towards the next level, from 0 to 1
+func (l Level) Progress() float64 {
+	if l.XP <= l.Start {
+		return 0
+	}
+	return float64(l.XP-l.Start) / float64(l.Next-l.Start)
+}
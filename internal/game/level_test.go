@@ -0,0 +1,79 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLevelFor(t *testing.T) {
+	tests := []struct {
+		xp        int
+		wantLevel int
+		wantRank  string
+		promoted  bool
+	}{
+		{0, 1, "Bronze", false},
+		{999, 1, "Bronze", false},
+		{1000, 2, "Bronze", false},
+		{3000, 3, "Bronze", false},
+		{LevelXP(10), 10, "Silver", true},
+		{LevelXP(11) - 1, 10, "Silver", true},
+		{LevelXP(20), 20, "Gold", true},
+		{LevelXP(49), 49, "Diamond", false},
+		{LevelXP(75), 75, "Grandmaster", true},
+		{LevelXP(200), 200, "Grandmaster", false},
+	}
+	for _, tt := range tests {
+		l := LevelFor(tt.xp)
+		if l.Number != tt.wantLevel || l.Rank.Name != tt.wantRank || l.Promoted() != tt.promoted {
+			t.Errorf("LevelFor(%d) = level %d %s, promoted %v; want %d %s, %v",
+				tt.xp, l.Number, l.Rank.Name, l.Promoted(), tt.wantLevel, tt.wantRank, tt.promoted)
+		}
+	}
+
+	if p := LevelFor(4000).Progress(); p != 1.0/3 {
+		t.Errorf("Progress() = %v, want a third of the way from 3000 to 6000", p)
+	}
+}
+
+func TestEngineXP(t *testing.T) {
+	e := NewEngine(DefaultEngineConfig())
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	e.LoadState(800, yesterday, "stopped", 0)
+	e.LoadXP(2000)
+	if _, ok := e.TakeLevelUp(); ok {
+		t.Error("loading XP levelled up")
+	}
+
+	// Yesterday's score stays in the XP when the daily score resets
+	if xp := e.XP(); xp != 2800 {
+		t.Errorf("XP() = %d, want 2800", xp)
+	}
+	if e.Score() != 0 {
+		t.Errorf("Score() = %d, want a new day's", e.Score())
+	}
+
+	e.RecordTaskComplete()
+	if _, ok := e.TakeLevelUp(); ok {
+		t.Error("levelled up before reaching 3000 XP")
+	}
+	e.RecordTaskComplete()
+	l, ok := e.TakeLevelUp()
+	if !ok || l.Number != 3 || l.XP != 3000 {
+		t.Errorf("TakeLevelUp() = %+v, %v; want level 3 at 3000 XP", l, ok)
+	}
+	if _, ok := e.TakeLevelUp(); ok {
+		t.Error("TakeLevelUp() returned the same level-up twice")
+	}
+
+	// Another process's score for today replaces ours; yesterday's is stale
+	today := time.Now().Format("2006-01-02")
+	e.SyncScore(4000, today)
+	e.SyncScore(9000, yesterday)
+	if xp := e.XP(); xp != 6800 {
+		t.Errorf("XP() = %d after syncing, want 6800", xp)
+	}
+	if l, ok := e.TakeLevelUp(); !ok || l.Number != 4 {
+		t.Errorf("TakeLevelUp() = %+v, %v after syncing; want level 4", l, ok)
+	}
+}
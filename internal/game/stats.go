@@ -0,0 +1,58 @@
+package game
+
+import "time"
+
+// DayStats is what the engine counted on one day since its stats were last
+// taken
+type DayStats struct {
+	Date      string // YYYY-MM-DD, local time
+	Score     int    // the day's score so far, not an increment
+	Actions   int
+	MaxStreak float64 // highest streak multiplier
+	Pomodoros int
+	// FlowTime is how long the focused session was thinking or active
+	// during a pomodoro work period
+	FlowTime time.Duration
+}
+
+func (d DayStats) empty() bool {
+	return d.Score == 0 && d.Actions == 0 && d.MaxStreak == 0 && d.Pomodoros == 0 && d.FlowTime == 0
+}
+
+// today returns the stats of now's day, setting aside those of an earlier
+// day with its final score
+func (e *Engine) today(now time.Time) *DayStats {
+	date := now.Format("2006-01-02")
+	if e.day.Date != date {
+		if e.day.Date == e.lastScoreDate {
+			e.day.Score = e.dailyScore
+		}
+		if e.day.Date != "" && !e.day.empty() {
+			e.pastDays = append(e.pastDays, e.day)
+		}
+		e.day = DayStats{Date: date}
+	}
+	return &e.day
+}
+
+// TakeDailyStats returns what was counted since the last call, oldest day
+// first, and starts counting again
+func (e *Engine) TakeDailyStats() []DayStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.checkDailyReset()
+	day := e.today(time.Now())
+	day.Score = e.dailyScore
+
+	// Whole seconds of flow are taken; the rest is kept for the next call
+	taken := *day
+	taken.FlowTime = day.FlowTime.Truncate(time.Second)
+	days := e.pastDays
+	if !taken.empty() {
+		days = append(days, taken)
+	}
+	e.pastDays = nil
+	e.day = DayStats{Date: day.Date, FlowTime: day.FlowTime - taken.FlowTime}
+	return days
+}
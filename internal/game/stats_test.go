@@ -0,0 +1,93 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeDailyStats(t *testing.T) {
+	e := NewEngine(DefaultEngineConfig())
+	if days := e.TakeDailyStats(); days != nil {
+		t.Fatalf("TakeDailyStats() = %+v before anything happened", days)
+	}
+
+	for i := 0; i < 3; i++ {
+		e.RecordAction(ActionKeypress)
+	}
+	e.SetSessionActivity("a", true)
+	e.SetSessionActivity("b", true)
+	e.SetSessionActivity("b", false)
+	e.RecordTaskComplete()
+
+	// 90s focused on a busy session during a pomodoro is flow time
+	e.Pomodoro().Start()
+	e.SetFocusSession("a")
+	e.lastTick = time.Now().Add(-90*time.Second - 300*time.Millisecond)
+	e.Tick()
+
+	days := e.TakeDailyStats()
+	if len(days) != 1 {
+		t.Fatalf("TakeDailyStats() = %+v, want today", days)
+	}
+	d := days[0]
+	if d.Date != time.Now().Format("2006-01-02") || d.Actions != 3 || d.MaxStreak != 2 || d.Score != 100 {
+		t.Errorf("stats = %+v, want 3 actions, streak x2 and a task's score", d)
+	}
+	if d.FlowTime != 90*time.Second {
+		t.Errorf("FlowTime = %v, want whole seconds of 90.3s", d.FlowTime)
+	}
+
+	// Counts start over; the score is the day's so far, and unfocused or
+	// idle time isn't flow
+	e.SetSessionActivity("a", false)
+	e.lastTick = time.Now().Add(-time.Minute)
+	e.Tick()
+	e.RecordAction(ActionKeypress)
+	days = e.TakeDailyStats()
+	if len(days) != 1 || days[0].Actions != 1 || days[0].MaxStreak != 1 || days[0].Score != 100 || days[0].FlowTime != 0 {
+		t.Errorf("second take = %+v, want 1 action, streak x1 and the score", days)
+	}
+}
+
+func TestTakeDailyStatsAcrossDays(t *testing.T) {
+	e := NewEngine(DefaultEngineConfig())
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	e.LoadState(700, yesterday, "stopped", 0)
+	e.day = DayStats{Date: yesterday, Actions: 40}
+
+	e.RecordAction(ActionKeypress)
+	days := e.TakeDailyStats()
+	if len(days) != 2 {
+		t.Fatalf("TakeDailyStats() = %+v, want yesterday and today", days)
+	}
+	if days[0].Date != yesterday || days[0].Actions != 40 || days[0].Score != 700 {
+		t.Errorf("yesterday = %+v, want 40 actions and its final score", days[0])
+	}
+	if days[1].Actions != 1 || days[1].Score != 0 {
+		t.Errorf("today = %+v, want 1 action and a fresh score", days[1])
+	}
+}
+
+func TestLeavePomodoro(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	for _, leave := range []bool{false, true} {
+		e := NewEngine(DefaultEngineConfig())
+		// A work period saved with no time left completes on the next tick
+		e.LoadState(0, today, "work", 0)
+		if leave {
+			e.LeavePomodoro()
+		}
+		e.Tick()
+
+		var pomodoros int
+		for _, d := range e.TakeDailyStats() {
+			pomodoros += d.Pomodoros
+		}
+		switch {
+		case leave && (pomodoros != 0 || e.Pomodoro().State() != PomodoroStopped):
+			t.Errorf("after LeavePomodoro: %d pomodoros, timer %s; want none and stopped", pomodoros, e.Pomodoro().StateString())
+		case !leave && pomodoros != 1:
+			t.Errorf("restored work period: %d pomodoros, want 1", pomodoros)
+		}
+	}
+}
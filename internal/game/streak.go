@@ -42,6 +42,13 @@ func (s *StreakTracker) Multiplier() float64 {
 	return mult
 }
 
+// IsActive reports whether a session is thinking or active
+func (s *StreakTracker) IsActive(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.activeSessions[name]
+}
+
 func (s *StreakTracker) Count() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -0,0 +1,53 @@
+package hooks
+
+import "strings"
+
+// Claude Code hook events ccmanager listens to
+const (
+	EventNotification     = "Notification"
+	EventStop             = "Stop"
+	EventPreToolUse       = "PreToolUse"
+	EventUserPromptSubmit = "UserPromptSubmit"
+)
+
+// Events lists every hook event the installer registers
+var Events = []string{
+	EventNotification,
+	EventStop,
+	EventPreToolUse,
+	EventUserPromptSubmit,
+}
+
+// IsEvent reports whether name is a hook event ccmanager handles
+func IsEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON Claude Code writes to a hook command's stdin.
+// Only the fields ccmanager uses are decoded.
+type Payload struct {
+	SessionID      string `json:"session_id"`
+	TranscriptPath string `json:"transcript_path,omitempty"`
+	Cwd            string `json:"cwd,omitempty"`
+	HookEventName  string `json:"hook_event_name,omitempty"`
+	Message        string `json:"message,omitempty"`   // Notification
+	ToolName       string `json:"tool_name,omitempty"` // PreToolUse
+}
+
+// Hook is a hook invocation forwarded to the monitor
+type Hook struct {
+	Event   string  `json:"event"`
+	Session string  `json:"session,omitempty"` // tmux session the hook ran in, if known
+	Payload Payload `json:"payload"`
+}
+
+// IsIdleNotification reports whether a Notification only says Claude has been
+// waiting for input, as opposed to asking for a permission decision
+func IsIdleNotification(message string) bool {
+	return strings.Contains(strings.ToLower(message), "waiting for your input")
+}
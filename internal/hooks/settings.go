@@ -0,0 +1,168 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSettingsPath returns the user-level Claude Code settings file
+func DefaultSettingsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude", "settings.json")
+}
+
+// Command returns the hook command line for an event
+func Command(executable, event string) string {
+	return fmt.Sprintf("%s hook %s", quote(executable), event)
+}
+
+// Install registers `<executable> hook <event>` for every event in the
+// settings file, replacing earlier ccmanager entries and keeping the rest
+func Install(settingsPath, executable string) error {
+	return update(settingsPath, func(hooks map[string]interface{}) {
+		for _, event := range Events {
+			groups := removeOurs(hooks[event], event, executable)
+			group := map[string]interface{}{
+				"hooks": []interface{}{
+					map[string]interface{}{
+						"type":    "command",
+						"command": Command(executable, event),
+					},
+				},
+			}
+			if event == EventPreToolUse {
+				group["matcher"] = "*"
+			}
+			hooks[event] = append(groups, group)
+		}
+	})
+}
+
+// Uninstall removes ccmanager's hook entries from the settings file
+func Uninstall(settingsPath, executable string) error {
+	return update(settingsPath, func(hooks map[string]interface{}) {
+		for _, event := range Events {
+			groups := removeOurs(hooks[event], event, executable)
+			if len(groups) == 0 {
+				delete(hooks, event)
+			} else {
+				hooks[event] = groups
+			}
+		}
+	})
+}
+
+// update applies fn to the "hooks" object of the settings file and writes it
+// back atomically. A missing file is treated as empty settings.
+func update(settingsPath string, fn func(hooks map[string]interface{})) error {
+	settings := map[string]interface{}{}
+	data, err := os.ReadFile(settingsPath)
+	switch {
+	case err == nil:
+		if len(strings.TrimSpace(string(data))) > 0 {
+			if err := json.Unmarshal(data, &settings); err != nil {
+				return fmt.Errorf("parse %s: %w", settingsPath, err)
+			}
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("read settings: %w", err)
+	}
+
+	hooks, _ := settings["hooks"].(map[string]interface{})
+	if hooks == nil {
+		hooks = map[string]interface{}{}
+	}
+	fn(hooks)
+	if len(hooks) == 0 {
+		delete(settings, "hooks")
+	} else {
+		settings["hooks"] = hooks
+	}
+
+	out, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode settings: %w", err)
+	}
+	out = append(out, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+		return fmt.Errorf("create settings directory: %w", err)
+	}
+	// The settings may hold tokens, so a new file is private and an existing
+	// one keeps its mode
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(settingsPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := settingsPath + ".tmp"
+	if err := os.WriteFile(tmp, out, mode); err != nil {
+		return fmt.Errorf("write settings: %w", err)
+	}
+	// WriteFile leaves the mode of a stale temp file and applies the umask
+	if err := os.Chmod(tmp, mode); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write settings: %w", err)
+	}
+	if err := os.Rename(tmp, settingsPath); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write settings: %w", err)
+	}
+	return nil
+}
+
+// removeOurs drops ccmanager commands from an event's matcher groups,
+// dropping groups left without any hooks
+func removeOurs(value interface{}, event, executable string) []interface{} {
+	groups, _ := value.([]interface{})
+	result := make([]interface{}, 0, len(groups))
+	for _, g := range groups {
+		group, ok := g.(map[string]interface{})
+		if !ok {
+			result = append(result, g)
+			continue
+		}
+		entries, _ := group["hooks"].([]interface{})
+		kept := make([]interface{}, 0, len(entries))
+		for _, e := range entries {
+			entry, ok := e.(map[string]interface{})
+			if ok {
+				if cmd, _ := entry["command"].(string); isOurs(cmd, event, executable) {
+					continue
+				}
+			}
+			kept = append(kept, e)
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		group["hooks"] = kept
+		result = append(result, group)
+	}
+	return result
+}
+
+// isOurs matches commands written by Install for this executable, or for any
+// binary named ccmanager* (e.g. after reinstalling to a new path)
+func isOurs(command, event, executable string) bool {
+	if command == Command(executable, event) {
+		return true
+	}
+	suffix := " hook " + event
+	if !strings.HasSuffix(command, suffix) {
+		return false
+	}
+	path := strings.Trim(strings.TrimSuffix(command, suffix), "'")
+	return strings.HasPrefix(filepath.Base(path), "ccmanager")
+}
+
+// quote single-quotes paths containing shell metacharacters
+func quote(s string) string {
+	if !strings.ContainsAny(s, " \t'\"$`\\;&|<>()*?[]#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
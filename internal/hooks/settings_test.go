@@ -0,0 +1,150 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readSettings(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read settings: %v", err)
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		t.Fatalf("parse settings: %v", err)
+	}
+	return settings
+}
+
+func commandsFor(settings map[string]interface{}, event string) []string {
+	hooks, _ := settings["hooks"].(map[string]interface{})
+	groups, _ := hooks[event].([]interface{})
+	var commands []string
+	for _, g := range groups {
+		entries, _ := g.(map[string]interface{})["hooks"].([]interface{})
+		for _, e := range entries {
+			cmd, _ := e.(map[string]interface{})["command"].(string)
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+func TestInstallPreservesSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	existing := `{
+  "model": "opus",
+  "hooks": {
+    "Stop": [{"hooks": [{"type": "command", "command": "notify-send done"}]}]
+  }
+}`
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Install(path, "/usr/local/bin/ccmanager"); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	// Installing twice must not duplicate entries
+	if err := Install(path, "/opt/bin/ccmanager"); err != nil {
+		t.Fatalf("second Install: %v", err)
+	}
+
+	settings := readSettings(t, path)
+	if settings["model"] != "opus" {
+		t.Errorf("model = %v, want opus", settings["model"])
+	}
+
+	stop := commandsFor(settings, EventStop)
+	want := []string{"notify-send done", "/opt/bin/ccmanager hook Stop"}
+	if len(stop) != len(want) {
+		t.Fatalf("Stop commands = %v, want %v", stop, want)
+	}
+	for i := range want {
+		if stop[i] != want[i] {
+			t.Errorf("Stop[%d] = %q, want %q", i, stop[i], want[i])
+		}
+	}
+
+	for _, event := range Events {
+		if cmds := commandsFor(settings, event); len(cmds) == 0 {
+			t.Errorf("no hook installed for %s", event)
+		}
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude", "settings.json")
+
+	if err := Install(path, "/home/me/go/bin/ccmanager"); err != nil {
+		t.Fatalf("Install into missing file: %v", err)
+	}
+	if err := Uninstall(path, "/home/me/go/bin/ccmanager"); err != nil {
+		t.Fatalf("Uninstall: %v", err)
+	}
+
+	settings := readSettings(t, path)
+	if _, ok := settings["hooks"]; ok {
+		t.Errorf("hooks left after uninstall: %v", settings["hooks"])
+	}
+}
+
+func TestInstallKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private.json")
+	if err := os.WriteFile(private, []byte(`{"env": {"TOKEN": "secret"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	shared := filepath.Join(dir, "shared.json")
+	if err := os.WriteFile(shared, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]os.FileMode{
+		private:                        0600,
+		shared:                         0644,
+		filepath.Join(dir, "new.json"): 0600,
+	} {
+		if err := Install(path, "/usr/local/bin/ccmanager"); err != nil {
+			t.Fatalf("Install %s: %v", path, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %v, want %v", filepath.Base(path), got, want)
+		}
+	}
+}
+
+func TestCommandQuoting(t *testing.T) {
+	tests := []struct {
+		executable string
+		want       string
+	}{
+		{"/usr/bin/ccmanager", "/usr/bin/ccmanager hook Stop"},
+		{"/Users/me/My Tools/ccmanager", "'/Users/me/My Tools/ccmanager' hook Stop"},
+	}
+
+	for _, tt := range tests {
+		got := Command(tt.executable, EventStop)
+		if got != tt.want {
+			t.Errorf("Command(%q) = %q, want %q", tt.executable, got, tt.want)
+		}
+		if !isOurs(got, EventStop, "/somewhere/else/ccmanager") {
+			t.Errorf("isOurs(%q) = false, want true", got)
+		}
+	}
+
+	if isOurs("other-tool hook Stop", EventStop, "/usr/bin/ccmanager") {
+		t.Error("isOurs matched a foreign command")
+	}
+	if !isOurs("/tmp/ccm hook Stop", EventStop, "/tmp/ccm") {
+		t.Error("isOurs missed the current executable")
+	}
+}
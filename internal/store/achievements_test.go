@@ -0,0 +1,32 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUnlockAchievement(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "ccmanager.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	for i, want := range []bool{true, false} {
+		isNew, err := s.UnlockAchievement("macro_hand", "Macro Hand", "Reach 150 APM")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isNew != want {
+			t.Errorf("unlock #%d: new = %v, want %v", i+1, isNew, want)
+		}
+	}
+
+	got, err := s.GetAchievements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "macro_hand" || got[0].Description != "Reach 150 APM" || got[0].UnlockedAt.IsZero() {
+		t.Errorf("GetAchievements() = %+v, want macro_hand with its unlock time", got)
+	}
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migration is one schema change, read from a NNN_name.sql file
+type migration struct {
+	version int
+	file    string
+	sql     string
+}
+
+// legacySchema is what each migration added, newest first, for databases
+// migrated before applied versions were recorded. Migrations from 006 on came
+// with the version table, so they never need placing here.
+var legacySchema = []struct {
+	version int
+	table   string // or index
+	column  string // "" to check only that the table exists
+}{
+	{5, "daily_stats", "daily_cost"},
+	{4, "sessions", "claude_session_id"},
+	{3, "game_state", "last_score_date"},
+	{2, "session_workspaces", ""},
+	{1, "game_state", ""},
+}
+
+// migrate brings the schema up to date with the embedded migrations
+func (s *Store) migrate() error {
+	fsys, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+	return runMigrations(s.db, fsys)
+}
+
+// loadMigrations reads the NNN_name.sql files of fsys, oldest first
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	var migrations []migration
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		prefix, _, _ := strings.Cut(e.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", e.Name())
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", e.Name(), err)
+		}
+		migrations = append(migrations, migration{version: version, file: e.Name(), sql: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", migrations[i-1].file, migrations[i].file)
+		}
+	}
+	return migrations, nil
+}
+
+// runMigrations applies the migrations of fsys that the database hasn't
+// recorded in schema_migrations, each in its own transaction. A database
+// with versions this binary doesn't know is left alone.
+func runMigrations(db *sql.DB, fsys fs.FS) error {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			file TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		if applied, err = recordLegacyVersions(db, migrations); err != nil {
+			return err
+		}
+	}
+
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.version] = true
+	}
+	for _, v := range applied {
+		if !known[v] {
+			return fmt.Errorf("database schema version %d is newer than this ccmanager; upgrade ccmanager", v)
+		}
+	}
+
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+	for _, m := range migrations {
+		if done[m.version] {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appliedVersions returns the versions recorded in schema_migrations
+func appliedVersions(db *sql.DB) ([]int, error) {
+	rows, err := db.Query(`SELECT version FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("read schema_migrations: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// applyMigration runs m and records it, or neither if it fails. A migration
+// another process applied meanwhile is skipped.
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("migration %s: %w", m.file, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var done int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, m.version).Scan(&done); err != nil {
+		return fmt.Errorf("migration %s: %w", m.file, err)
+	}
+	if done > 0 {
+		return nil
+	}
+	if _, err := tx.Exec(m.sql); err != nil {
+		return fmt.Errorf("migration %s: %w", m.file, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, file) VALUES (?, ?)`, m.version, m.file); err != nil {
+		return fmt.Errorf("migration %s: %w", m.file, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %s: %w", m.file, err)
+	}
+	return nil
+}
+
+// recordLegacyVersions marks the migrations a database got before versions
+// were recorded as applied, and returns them. Those migrations always ran in
+// order, so the newest change found implies all before it.
+func recordLegacyVersions(db *sql.DB, migrations []migration) ([]int, error) {
+	legacy, err := legacyVersion(db)
+	if err != nil || legacy == 0 {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("record legacy migrations: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var versions []int
+	for _, m := range migrations {
+		if m.version > legacy {
+			break
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO schema_migrations (version, file) VALUES (?, ?)`, m.version, m.file); err != nil {
+			return nil, fmt.Errorf("record legacy migrations: %w", err)
+		}
+		versions = append(versions, m.version)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("record legacy migrations: %w", err)
+	}
+	return versions, nil
+}
+
+// legacyVersion returns the newest migration whose change is in the schema,
+// or 0 for an empty database
+func legacyVersion(db *sql.DB) (int, error) {
+	for _, l := range legacySchema {
+		var n int
+		var err error
+		if l.column == "" {
+			err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = ?`, l.table).Scan(&n)
+		} else {
+			err = db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, l.table, l.column).Scan(&n)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("detect schema version: %w", err)
+		}
+		if n > 0 {
+			return l.version, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func headVersions(t *testing.T) []int {
+	t.Helper()
+	fsys, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int
+	for _, m := range migrations {
+		versions = append(versions, m.version)
+	}
+	return versions
+}
+
+func openDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestMigrateUpgradesV1Database(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccmanager.db")
+	fixture, err := os.ReadFile("testdata/v1.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openDB(t, path).Exec(string(fixture)); err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+
+	// Opening twice must not run anything again
+	for i := 0; i < 2; i++ {
+		s, err := New(path)
+		if err != nil {
+			t.Fatalf("New() #%d: %v", i+1, err)
+		}
+		versions, err := appliedVersions(s.db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := headVersions(t); !reflect.DeepEqual(versions, want) {
+			t.Errorf("applied versions = %v, want %v", versions, want)
+		}
+
+		state, err := s.GetGameState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.CurrentScore != 1200 || state.PomodorosToday != 2 {
+			t.Errorf("game state = %+v, want the fixture's score and pomodoros", state)
+		}
+		groups, err := s.GetControlGroups()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if groups[1] != "api-1" {
+			t.Errorf("control groups = %v, want api-1 in group 1", groups)
+		}
+		if err := s.SetClaudeSessionID("api-1", "c1"); err != nil {
+			t.Errorf("SetClaudeSessionID after upgrade: %v", err)
+		}
+		if _, err := s.CostByDay(UsageFilter{}); err != nil {
+			t.Errorf("CostByDay after upgrade: %v", err)
+		}
+		_ = s.Close()
+	}
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "ccmanager.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+	versions, err := appliedVersions(s.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := headVersions(t); !reflect.DeepEqual(versions, want) {
+		t.Errorf("applied versions = %v, want %v", versions, want)
+	}
+}
+
+func TestMigrateRefusesNewerDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccmanager.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec(`INSERT INTO schema_migrations (version, file) VALUES (999, '999_future.sql')`); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+
+	if _, err := New(path); err == nil || !strings.Contains(err.Error(), "newer than this ccmanager") {
+		t.Errorf("New() = %v, want a newer schema error", err)
+	}
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	db := openDB(t, filepath.Join(t.TempDir(), "test.db"))
+	fsys := fstest.MapFS{
+		"001_a.sql": {Data: []byte(`CREATE TABLE a (x INTEGER);`)},
+		"002_b.sql": {Data: []byte(`CREATE TABLE b (x INTEGER); INSERT INTO missing VALUES (1);`)},
+	}
+	if err := runMigrations(db, fsys); err == nil || !strings.Contains(err.Error(), "002_b.sql") {
+		t.Fatalf("runMigrations() = %v, want 002_b.sql to fail", err)
+	}
+	var tables []string
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name IN ('a', 'b') ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var name string
+		_ = rows.Scan(&name)
+		tables = append(tables, name)
+	}
+	_ = rows.Close()
+	if !reflect.DeepEqual(tables, []string{"a"}) {
+		t.Errorf("tables = %v, want only a", tables)
+	}
+	if versions, _ := appliedVersions(db); !reflect.DeepEqual(versions, []int{1}) {
+		t.Errorf("applied versions = %v, want [1]", versions)
+	}
+
+	// Fixed, it runs on the next open
+	fsys["002_b.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE b (x INTEGER);`)}
+	if err := runMigrations(db, fsys); err != nil {
+		t.Fatal(err)
+	}
+	if versions, _ := appliedVersions(db); !reflect.DeepEqual(versions, []int{1, 2}) {
+		t.Errorf("applied versions = %v, want [1 2]", versions)
+	}
+}
+
+func TestLegacyVersion(t *testing.T) {
+	fsys, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each migration from before the version table must leave a trace older
+	// databases can be placed by
+	legacy := legacySchema[0].version
+	for n := 0; n <= legacy; n++ {
+		db := openDB(t, filepath.Join(t.TempDir(), "legacy.db"))
+		for _, m := range migrations[:n] {
+			if _, err := db.Exec(m.sql); err != nil {
+				t.Fatalf("%s: %v", m.file, err)
+			}
+		}
+		want := 0
+		if n > 0 {
+			want = migrations[n-1].version
+		}
+		if got, err := legacyVersion(db); err != nil || got != want {
+			t.Errorf("after %d migrations: legacyVersion() = %d, %v, want %d", n, got, err, want)
+		}
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no version": {"initial.sql": {}},
+		"duplicate":  {"001_a.sql": {}, "001_b.sql": {}},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := loadMigrations(fsys); err == nil {
+				t.Error("loadMigrations() succeeded, want error")
+			}
+		})
+	}
+}
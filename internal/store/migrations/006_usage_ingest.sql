@@ -0,0 +1,30 @@
+-- Incremental usage ingestion from Claude JSONL transcripts
+
+-- Usage files: how far each transcript has been read
+CREATE TABLE IF NOT EXISTS usage_files (
+    path TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    project_dir TEXT NOT NULL,
+    inode INTEGER NOT NULL DEFAULT 0,
+    size INTEGER NOT NULL DEFAULT 0,
+    byte_offset INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Usage messages: token usage of each assistant message read so far
+CREATE TABLE IF NOT EXISTS usage_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    project_dir TEXT NOT NULL,
+    line_offset INTEGER NOT NULL,
+    timestamp DATETIME,
+    model TEXT NOT NULL DEFAULT '',
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_creation_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_read_input_tokens INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_messages_session ON usage_messages(session_id);
+CREATE INDEX IF NOT EXISTS idx_usage_messages_path ON usage_messages(path);
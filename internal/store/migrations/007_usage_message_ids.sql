@@ -0,0 +1,9 @@
+-- Count each API response once: streamed responses repeat their usage on
+-- every line. Rows read before the IDs were kept are dropped so transcripts
+-- are read again.
+ALTER TABLE usage_messages ADD COLUMN message_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE usage_messages ADD COLUMN request_id TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_messages_response
+    ON usage_messages(message_id, request_id) WHERE message_id != '';
+DELETE FROM usage_messages;
+DELETE FROM usage_files;
@@ -0,0 +1,15 @@
+-- Price each message when it is read: 1-hour cache writes cost more than
+-- 5-minute ones, and long requests cost more per token. Rows read before the
+-- 1-hour split was kept are dropped so transcripts are read again.
+ALTER TABLE usage_messages ADD COLUMN cache_creation_1h_input_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE usage_messages ADD COLUMN cost REAL NOT NULL DEFAULT 0;
+ALTER TABLE usage_messages ADD COLUMN priced INTEGER NOT NULL DEFAULT 1;
+
+-- Usage pricing: the price table the saved costs were calculated with
+CREATE TABLE IF NOT EXISTS usage_pricing (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    version TEXT NOT NULL
+);
+
+DELETE FROM usage_messages;
+DELETE FROM usage_files;
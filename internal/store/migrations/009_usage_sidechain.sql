@@ -0,0 +1,7 @@
+-- Mark usage spent by Task subagents, whose transcripts are attributed to
+-- their parent session. Rows read before subagents were told apart are
+-- dropped so transcripts are read again.
+ALTER TABLE usage_messages ADD COLUMN sidechain INTEGER NOT NULL DEFAULT 0;
+
+DELETE FROM usage_messages;
+DELETE FROM usage_files;
@@ -0,0 +1,2 @@
+-- Usage windows read recent messages by time
+CREATE INDEX IF NOT EXISTS idx_usage_messages_timestamp ON usage_messages(substr(timestamp, 1, 19));
@@ -0,0 +1,12 @@
+-- Usage events: the ledger of every API response read from transcripts. Rows
+-- are kept after their transcripts and tmux sessions are gone.
+ALTER TABLE usage_messages RENAME TO usage_events;
+
+CREATE INDEX IF NOT EXISTS idx_usage_events_project ON usage_events(project_dir);
+
+-- Usage sessions: the tmux session each Claude session ran in
+CREATE TABLE IF NOT EXISTS usage_sessions (
+    session_id TEXT PRIMARY KEY,
+    tmux_session TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
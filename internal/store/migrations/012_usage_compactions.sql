@@ -0,0 +1,15 @@
+-- Usage compactions: where Claude Code summarized a conversation to free its
+-- context window
+CREATE TABLE IF NOT EXISTS usage_compactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    project_dir TEXT NOT NULL,
+    line_offset INTEGER NOT NULL,
+    timestamp DATETIME,
+    trigger TEXT NOT NULL DEFAULT '',
+    pre_tokens INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(path, line_offset)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_compactions_session ON usage_compactions(project_dir, session_id);
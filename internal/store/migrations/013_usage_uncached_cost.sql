@@ -0,0 +1,6 @@
+-- What each response would have cost without the prompt cache, to show what
+-- caching saved. Forgetting the pricing version reprices every row, which
+-- fills it in.
+ALTER TABLE usage_events ADD COLUMN uncached_cost REAL NOT NULL DEFAULT 0;
+
+DELETE FROM usage_pricing;
@@ -0,0 +1,7 @@
+-- Budget alerts: the highest threshold reported per limit and period, so a
+-- restart doesn't report them, or stop sessions for them, again
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    key TEXT PRIMARY KEY,
+    threshold REAL NOT NULL,
+    reported_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -2,7 +2,6 @@ package store
 
 import (
 	"database/sql"
-	"embed"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,9 +10,6 @@ import (
 	_ "modernc.org/sqlite"
 )
 
-//go:embed migrations/*.sql
-var migrationsFS embed.FS
-
 // Store handles SQLite persistence
 type Store struct {
 	db *sql.DB
@@ -65,7 +61,9 @@ func New(dbPath string) (*Store, error) {
 		return nil, fmt.Errorf("create db directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	// The daemon, an attached dashboard and CLI commands share the
+	// database: wait for each other's writes rather than failing at once
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
@@ -85,49 +83,6 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
-func (s *Store) migrate() error {
-	schema, err := migrationsFS.ReadFile("migrations/001_initial.sql")
-	if err != nil {
-		return fmt.Errorf("read migration: %w", err)
-	}
-
-	_, err = s.db.Exec(string(schema))
-	if err != nil {
-		return fmt.Errorf("exec migration: %w", err)
-	}
-
-	schema2, err := migrationsFS.ReadFile("migrations/002_workspaces.sql")
-	if err != nil {
-		return fmt.Errorf("read migration 002: %w", err)
-	}
-
-	_, err = s.db.Exec(string(schema2))
-	if err != nil {
-		return fmt.Errorf("exec migration 002: %w", err)
-	}
-
-	schema3, err := migrationsFS.ReadFile("migrations/003_last_score_date.sql")
-	if err != nil {
-		return fmt.Errorf("read migration 003: %w", err)
-	}
-
-	_, _ = s.db.Exec(string(schema3))
-
-	schema4, err := migrationsFS.ReadFile("migrations/004_claude_session_id.sql")
-	if err != nil {
-		return fmt.Errorf("read migration 004: %w", err)
-	}
-	_, _ = s.db.Exec(string(schema4))
-
-	schema5, err := migrationsFS.ReadFile("migrations/005_daily_cost.sql")
-	if err != nil {
-		return fmt.Errorf("read migration 005: %w", err)
-	}
-	_, _ = s.db.Exec(string(schema5))
-
-	return nil
-}
-
 // GetGameState retrieves the current game state
 func (s *Store) GetGameState() (*GameState, error) {
 	var state GameState
@@ -189,6 +144,25 @@ func (s *Store) UpdateGameState(state *GameState) error {
 	return nil
 }
 
+// UpdateScore updates only the score columns of the game state, leaving
+// pomodoro state untouched for the TUI that owns it
+func (s *Store) UpdateScore(score int, lastScoreDate string) error {
+	_, err := s.db.Exec(`
+		UPDATE game_state SET
+			current_score = ?,
+			last_score_date = ?,
+			last_action_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = 1
+	`, score, lastScoreDate)
+
+	if err != nil {
+		return fmt.Errorf("update score: %w", err)
+	}
+
+	return nil
+}
+
 // GetControlGroups retrieves all control group assignments
 func (s *Store) GetControlGroups() (map[int]string, error) {
 	rows, err := s.db.Query(`
@@ -307,6 +281,76 @@ func (s *Store) UpdateTodayStats(stats *DailyStats) error {
 	return nil
 }
 
+// AddDailyStats adds a day's actions, pomodoros and flow time to its
+// statistics and raises its score and max streak to those given. Adding lets
+// the daemon and a dashboard both record the same day.
+func (s *Store) AddDailyStats(stats *DailyStats) error {
+	_, err := s.db.Exec(`
+		INSERT INTO daily_stats (date, total_score, total_actions, max_streak,
+		                         pomodoros_completed, flow_time_seconds)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(date) DO UPDATE SET
+			total_score = MAX(total_score, excluded.total_score),
+			total_actions = total_actions + excluded.total_actions,
+			max_streak = MAX(max_streak, excluded.max_streak),
+			pomodoros_completed = pomodoros_completed + excluded.pomodoros_completed,
+			flow_time_seconds = flow_time_seconds + excluded.flow_time_seconds,
+			updated_at = CURRENT_TIMESTAMP
+	`,
+		stats.Date,
+		stats.TotalScore,
+		stats.TotalActions,
+		stats.MaxStreak,
+		stats.PomodorosCompleted,
+		stats.FlowTimeSeconds,
+	)
+
+	if err != nil {
+		return fmt.Errorf("add daily stats: %w", err)
+	}
+
+	return nil
+}
+
+// GetDailyStatsSince retrieves the statistics of the days from date on,
+// oldest first. Days without a row are left out.
+func (s *Store) GetDailyStatsSince(date string) ([]DailyStats, error) {
+	rows, err := s.db.Query(`
+		SELECT date, total_score, total_actions, max_streak,
+		       pomodoros_completed, flow_time_seconds, COALESCE(daily_cost, 0)
+		FROM daily_stats WHERE date >= ?
+		ORDER BY date
+	`, date)
+	if err != nil {
+		return nil, fmt.Errorf("get daily stats: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var days []DailyStats
+	for rows.Next() {
+		var d DailyStats
+		if err := rows.Scan(&d.Date, &d.TotalScore, &d.TotalActions, &d.MaxStreak,
+			&d.PomodorosCompleted, &d.FlowTimeSeconds, &d.DailyCost); err != nil {
+			return nil, fmt.Errorf("scan daily stats: %w", err)
+		}
+		days = append(days, d)
+	}
+
+	return days, rows.Err()
+}
+
+// GetScoreBefore returns the sum of the daily scores of the days before date
+func (s *Store) GetScoreBefore(date string) (int, error) {
+	var score int
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(total_score), 0) FROM daily_stats WHERE date < ?
+	`, date).Scan(&score)
+	if err != nil {
+		return 0, fmt.Errorf("get score before %s: %w", date, err)
+	}
+	return score, nil
+}
+
 // AddActivityLog adds an entry to the activity log
 func (s *Store) AddActivityLog(sessionName, eventType, message string) error {
 	_, err := s.db.Exec(`
@@ -535,6 +579,19 @@ func (s *Store) AddToDailyCost(amount float64) error {
 	return nil
 }
 
+// GetCostSince returns the daily costs recorded from date on, inclusive,
+// with date as YYYY-MM-DD
+func (s *Store) GetCostSince(date string) (float64, error) {
+	var cost float64
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(daily_cost), 0) FROM daily_stats WHERE date >= ?
+	`, date).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("get cost since: %w", err)
+	}
+	return cost, nil
+}
+
 func (s *Store) GetClaudeSessionID(sessionName string) (string, error) {
 	var id sql.NullString
 	err := s.db.QueryRow(`
@@ -548,3 +605,28 @@ func (s *Store) GetClaudeSessionID(sessionName string) (string, error) {
 	}
 	return id.String, nil
 }
+
+// ReportBudgetThreshold records that a budget threshold was reported for a
+// limit and period. It returns false if that threshold, or a higher one, was
+// already reported, by this process or another.
+func (s *Store) ReportBudgetThreshold(key string, threshold float64) (bool, error) {
+	res, err := s.db.Exec(`
+		INSERT INTO budget_alerts (key, threshold) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			threshold = excluded.threshold,
+			reported_at = CURRENT_TIMESTAMP
+		WHERE threshold < excluded.threshold
+	`, key, threshold)
+	if err != nil {
+		return false, fmt.Errorf("report budget threshold: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("report budget threshold: %w", err)
+	}
+	if n > 0 {
+		// Periods are at most a week, bar long sessions; forget old ones
+		_, _ = s.db.Exec(`DELETE FROM budget_alerts WHERE reported_at < datetime('now', '-30 days')`)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestAddDailyStats(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "ccmanager.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	// The daemon and a dashboard flush the same day
+	flushes := []DailyStats{
+		{Date: "2026-06-01", TotalScore: 500, MaxStreak: 3},
+		{Date: "2026-06-01", TotalScore: 300, TotalActions: 40, MaxStreak: 1, PomodorosCompleted: 1, FlowTimeSeconds: 600},
+		{Date: "2026-06-01", TotalScore: 900, TotalActions: 10, FlowTimeSeconds: 60},
+		{Date: "2026-06-03", TotalActions: 5},
+	}
+	for _, f := range flushes {
+		if err := s.AddDailyStats(&f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	days, err := s.GetDailyStatsSince("2026-06-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("GetDailyStatsSince() = %+v, want 2 days", days)
+	}
+	want := DailyStats{Date: "2026-06-01", TotalScore: 900, TotalActions: 50, MaxStreak: 3, PomodorosCompleted: 1, FlowTimeSeconds: 660}
+	if days[0] != want {
+		t.Errorf("day 1 = %+v, want %+v", days[0], want)
+	}
+	if days[1].Date != "2026-06-03" || days[1].TotalActions != 5 {
+		t.Errorf("day 2 = %+v, want 2026-06-03 with 5 actions", days[1])
+	}
+	if later, _ := s.GetDailyStatsSince("2026-06-02"); len(later) != 1 {
+		t.Errorf("GetDailyStatsSince(2026-06-02) = %+v, want only June 3", later)
+	}
+}
+
+func TestGetScoreBefore(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "ccmanager.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	if score, err := s.GetScoreBefore("2026-06-03"); err != nil || score != 0 {
+		t.Fatalf("GetScoreBefore() = %d, %v on an empty database", score, err)
+	}
+	for _, d := range []DailyStats{
+		{Date: "2026-06-01", TotalScore: 500},
+		{Date: "2026-06-02", TotalScore: 1200},
+		{Date: "2026-06-03", TotalScore: 9000},
+	} {
+		if err := s.AddDailyStats(&d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if score, err := s.GetScoreBefore("2026-06-03"); err != nil || score != 1700 {
+		t.Errorf("GetScoreBefore(2026-06-03) = %d, %v; want 1700", score, err)
+	}
+}
+
+func TestReportBudgetThreshold(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "ccmanager.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	steps := []struct {
+		key       string
+		threshold float64
+		want      bool
+	}{
+		{"daily::2026-06-01", 0.5, true},
+		{"daily::2026-06-01", 0.5, false}, // after a restart, or by another process
+		{"daily::2026-06-01", 0.8, true},
+		{"daily::2026-06-01", 0.5, false},
+		{"daily::2026-06-02", 0.5, true},
+	}
+	for _, st := range steps {
+		got, err := s.ReportBudgetThreshold(st.key, st.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != st.want {
+			t.Errorf("ReportBudgetThreshold(%s, %v) = %v, want %v", st.key, st.threshold, got, st.want)
+		}
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	const writers, writes = 3, 50
+
+	stores := make([]*Store, writers)
+	for i := range stores {
+		st, err := New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = st.Close() }()
+		stores[i] = st
+	}
+
+	// Like the daemon, a dashboard and a CLI command adding costs at once
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*writes)
+	for _, st := range stores {
+		wg.Add(1)
+		go func(st *Store) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				errs <- st.AddToDailyCost(1)
+			}
+		}(st)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("AddToDailyCost() error = %v", err)
+		}
+	}
+
+	stats, err := stores[0].GetTodayStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.DailyCost != writers*writes {
+		t.Errorf("DailyCost = %v, want %d", stats.DailyCost, writers*writes)
+	}
+}
@@ -0,0 +1,609 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUsageConflict is returned when a usage file was ingested by someone
+// else since it was read
+var ErrUsageConflict = errors.New("usage file changed since it was read")
+
+// UsageFile records how far a JSONL transcript has been ingested
+type UsageFile struct {
+	Path       string
+	SessionID  string // the parent session, for subagent transcripts
+	ProjectDir string
+	Inode      uint64
+	Size       int64
+	Offset     int64 // end of the last complete line read
+}
+
+// UsageEvent is the token usage of one API response, a row of the
+// usage_events ledger
+type UsageEvent struct {
+	MessageID                string // "" for transcripts that don't record it
+	RequestID                string
+	LineOffset               int64
+	Timestamp                time.Time
+	Model                    string
+	InputTokens              int64
+	OutputTokens             int64
+	CacheCreationInputTokens int64
+	// CacheCreation1hInputTokens is the part of CacheCreationInputTokens
+	// written to the 1-hour cache
+	CacheCreation1hInputTokens int64
+	CacheReadInputTokens       int64
+	Cost                       float64
+	// UncachedCost is what the response would have cost with every input
+	// token at the plain input price
+	UncachedCost float64
+	Priced       bool // false when the model had no price
+	Sidechain    bool // spent by a Task subagent
+}
+
+// UsageCompaction is where a transcript's conversation was compacted
+type UsageCompaction struct {
+	LineOffset int64
+	Timestamp  time.Time
+	Trigger    string
+	PreTokens  int64
+}
+
+// ModelUsage is the summed usage of one model
+type ModelUsage struct {
+	Model                      string
+	Messages                   int
+	InputTokens                int64
+	OutputTokens               int64
+	CacheCreationInputTokens   int64
+	CacheCreation1hInputTokens int64
+	CacheReadInputTokens       int64
+	Cost                       float64
+	Unpriced                   int  // messages whose model had no price
+	Sidechain                  bool // spent by Task subagents
+}
+
+// GetUsageFile returns the ingest state of a transcript, or nil if it hasn't
+// been read yet
+func (s *Store) GetUsageFile(path string) (*UsageFile, error) {
+	return getUsageFile(s.db, path)
+}
+
+func getUsageFile(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, path string) (*UsageFile, error) {
+	var f UsageFile
+	err := q.QueryRow(`
+		SELECT path, session_id, project_dir, inode, size, byte_offset
+		FROM usage_files WHERE path = ?
+	`, path).Scan(&f.Path, &f.SessionID, &f.ProjectDir, &f.Inode, &f.Size, &f.Offset)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get usage file: %w", err)
+	}
+	return &f, nil
+}
+
+// IngestUsage saves the messages and compactions read from a transcript and
+// its new state.
+// A message whose ID was already saved, from this file or a resumed copy of
+// it, updates that row with the larger counts instead of adding another.
+// prev is the state the read started from; if the stored state no longer
+// matches it, nothing is saved and ErrUsageConflict is returned. With replace
+// set, the file's earlier messages are dropped first, for files that were
+// truncated or replaced.
+func (s *Store) IngestUsage(prev *UsageFile, next UsageFile, replace bool, msgs []UsageEvent, compactions []UsageCompaction) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ingest usage: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	current, err := getUsageFile(tx, next.Path)
+	if err != nil {
+		return err
+	}
+	if !sameUsageFile(current, prev) {
+		return ErrUsageConflict
+	}
+
+	if replace {
+		if _, err := tx.Exec(`DELETE FROM usage_events WHERE path = ?`, next.Path); err != nil {
+			return fmt.Errorf("ingest usage: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM usage_compactions WHERE path = ?`, next.Path); err != nil {
+			return fmt.Errorf("ingest usage: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO usage_files (path, session_id, project_dir, inode, size, byte_offset)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			session_id = excluded.session_id,
+			project_dir = excluded.project_dir,
+			inode = excluded.inode,
+			size = excluded.size,
+			byte_offset = excluded.byte_offset,
+			updated_at = CURRENT_TIMESTAMP
+	`, next.Path, next.SessionID, next.ProjectDir, next.Inode, next.Size, next.Offset)
+	if err != nil {
+		return fmt.Errorf("ingest usage: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO usage_events (
+			path, session_id, project_dir, message_id, request_id, line_offset, timestamp, model,
+			input_tokens, output_tokens, cache_creation_input_tokens, cache_creation_1h_input_tokens,
+			cache_read_input_tokens, cost, uncached_cost, priced, sidechain
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(message_id, request_id) WHERE message_id != '' DO UPDATE SET
+			input_tokens = MAX(input_tokens, excluded.input_tokens),
+			output_tokens = MAX(output_tokens, excluded.output_tokens),
+			cache_creation_input_tokens = MAX(cache_creation_input_tokens, excluded.cache_creation_input_tokens),
+			cache_creation_1h_input_tokens = MAX(cache_creation_1h_input_tokens, excluded.cache_creation_1h_input_tokens),
+			cache_read_input_tokens = MAX(cache_read_input_tokens, excluded.cache_read_input_tokens),
+			cost = MAX(cost, excluded.cost),
+			uncached_cost = MAX(uncached_cost, excluded.uncached_cost)
+	`)
+	if err != nil {
+		return fmt.Errorf("ingest usage: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, m := range msgs {
+		var ts interface{}
+		if !m.Timestamp.IsZero() {
+			ts = m.Timestamp.UTC()
+		}
+		_, err := stmt.Exec(next.Path, next.SessionID, next.ProjectDir, m.MessageID, m.RequestID, m.LineOffset, ts, m.Model,
+			m.InputTokens, m.OutputTokens, m.CacheCreationInputTokens, m.CacheCreation1hInputTokens,
+			m.CacheReadInputTokens, m.Cost, m.UncachedCost, m.Priced, m.Sidechain)
+		if err != nil {
+			return fmt.Errorf("ingest usage: %w", err)
+		}
+	}
+
+	for _, c := range compactions {
+		var ts interface{}
+		if !c.Timestamp.IsZero() {
+			ts = c.Timestamp.UTC()
+		}
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO usage_compactions (path, session_id, project_dir, line_offset, timestamp, trigger, pre_tokens)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, next.Path, next.SessionID, next.ProjectDir, c.LineOffset, ts, c.Trigger, c.PreTokens)
+		if err != nil {
+			return fmt.Errorf("ingest usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ingest usage: %w", err)
+	}
+	return nil
+}
+
+func sameUsageFile(a, b *UsageFile) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Inode == b.Inode && a.Size == b.Size && a.Offset == b.Offset
+}
+
+// FileUsageByModel sums the usage ingested from one transcript per model,
+// ordered so the most recently used model comes last
+func (s *Store) FileUsageByModel(path string) ([]ModelUsage, error) {
+	return s.usageByModel(`WHERE path = ?`, path)
+}
+
+// SessionUsageByModel sums the usage of a session and its subagents per
+// model, like FileUsageByModel
+func (s *Store) SessionUsageByModel(projectDir, sessionID string) ([]ModelUsage, error) {
+	return s.usageByModel(`WHERE project_dir = ? AND session_id = ?`, projectDir, sessionID)
+}
+
+// LatestSessionUsage returns the latest main-conversation response with
+// input tokens in a session's transcript, or nil if there is none
+func (s *Store) LatestSessionUsage(projectDir, sessionID string) (*UsageEvent, error) {
+	var m UsageEvent
+	var ts sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT timestamp, model, input_tokens, output_tokens,
+			cache_creation_input_tokens, cache_creation_1h_input_tokens, cache_read_input_tokens
+		FROM usage_events
+		WHERE project_dir = ? AND session_id = ? AND sidechain = 0
+			AND input_tokens + cache_creation_input_tokens + cache_read_input_tokens > 0
+		ORDER BY timestamp DESC, id DESC LIMIT 1
+	`, projectDir, sessionID).Scan(&ts, &m.Model, &m.InputTokens, &m.OutputTokens,
+		&m.CacheCreationInputTokens, &m.CacheCreation1hInputTokens, &m.CacheReadInputTokens)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("latest session usage: %w", err)
+	}
+	m.Timestamp = ts.Time
+	return &m, nil
+}
+
+// LatestCompaction returns the last compaction of a session's conversation,
+// or nil if it was never compacted
+func (s *Store) LatestCompaction(projectDir, sessionID string) (*UsageCompaction, error) {
+	var c UsageCompaction
+	var ts sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT line_offset, timestamp, trigger, pre_tokens FROM usage_compactions
+		WHERE project_dir = ? AND session_id = ?
+		ORDER BY timestamp DESC, id DESC LIMIT 1
+	`, projectDir, sessionID).Scan(&c.LineOffset, &ts, &c.Trigger, &c.PreTokens)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("latest compaction: %w", err)
+	}
+	c.Timestamp = ts.Time
+	return &c, nil
+}
+
+// UsageByModel sums all ingested usage per model. Each model is split into
+// rows for the main conversation and for subagents.
+func (s *Store) UsageByModel() ([]ModelUsage, error) {
+	return s.usageByModel("")
+}
+
+func (s *Store) usageByModel(where string, args ...interface{}) ([]ModelUsage, error) {
+	rows, err := s.db.Query(`
+		SELECT model, COUNT(*),
+			COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+			COALESCE(SUM(cache_creation_input_tokens), 0), COALESCE(SUM(cache_creation_1h_input_tokens), 0),
+			COALESCE(SUM(cache_read_input_tokens), 0), COALESCE(SUM(cost), 0), COALESCE(SUM(1 - priced), 0),
+			sidechain
+		FROM usage_events `+where+`
+		GROUP BY model, sidechain
+		ORDER BY MAX(id)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("usage by model: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []ModelUsage
+	for rows.Next() {
+		var m ModelUsage
+		if err := rows.Scan(&m.Model, &m.Messages, &m.InputTokens, &m.OutputTokens,
+			&m.CacheCreationInputTokens, &m.CacheCreation1hInputTokens,
+			&m.CacheReadInputTokens, &m.Cost, &m.Unpriced, &m.Sidechain); err != nil {
+			return nil, fmt.Errorf("usage by model: %w", err)
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// CountUsageFiles returns how many sessions and project directories have
+// been ingested. Subagent transcripts count towards their parent session.
+func (s *Store) CountUsageFiles() (sessions, projects int, err error) {
+	err = s.db.QueryRow(`
+		SELECT COUNT(DISTINCT project_dir || '/' || session_id), COUNT(DISTINCT project_dir) FROM usage_files
+	`).Scan(&sessions, &projects)
+	if err != nil {
+		return 0, 0, fmt.Errorf("count usage files: %w", err)
+	}
+	return sessions, projects, nil
+}
+
+// UsagePricingVersion returns the version of the price table saved costs
+// were calculated with, or "" if none
+func (s *Store) UsagePricingVersion() (string, error) {
+	var version string
+	err := s.db.QueryRow(`SELECT version FROM usage_pricing WHERE id = 1`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get usage pricing version: %w", err)
+	}
+	return version, nil
+}
+
+// RepriceUsage recalculates the cost and uncached cost of every saved message
+// with price and records the price table's version
+func (s *Store) RepriceUsage(version string, price func(UsageEvent) (cost, uncached float64, priced bool)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("reprice usage: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query(`
+		SELECT id, timestamp, model, input_tokens, output_tokens,
+			cache_creation_input_tokens, cache_creation_1h_input_tokens, cache_read_input_tokens
+		FROM usage_events
+	`)
+	if err != nil {
+		return fmt.Errorf("reprice usage: %w", err)
+	}
+	type repriced struct {
+		id       int64
+		cost     float64
+		uncached float64
+		priced   bool
+	}
+	var updates []repriced
+	for rows.Next() {
+		var id int64
+		var ts sql.NullTime
+		var m UsageEvent
+		if err := rows.Scan(&id, &ts, &m.Model, &m.InputTokens, &m.OutputTokens,
+			&m.CacheCreationInputTokens, &m.CacheCreation1hInputTokens, &m.CacheReadInputTokens); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("reprice usage: %w", err)
+		}
+		m.Timestamp = ts.Time
+		cost, uncached, priced := price(m)
+		updates = append(updates, repriced{id, cost, uncached, priced})
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reprice usage: %w", err)
+	}
+
+	for _, u := range updates {
+		if _, err := tx.Exec(`UPDATE usage_events SET cost = ?, uncached_cost = ?, priced = ? WHERE id = ?`,
+			u.cost, u.uncached, u.priced, u.id); err != nil {
+			return fmt.Errorf("reprice usage: %w", err)
+		}
+	}
+	_, err = tx.Exec(`
+		INSERT INTO usage_pricing (id, version) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET version = excluded.version
+	`, version)
+	if err != nil {
+		return fmt.Errorf("reprice usage: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("reprice usage: %w", err)
+	}
+	return nil
+}
+
+// ProjectCostSince returns the cost of the messages made in a Claude project
+// directory at or after since
+func (s *Store) ProjectCostSince(projectDir string, since time.Time) (float64, error) {
+	var cost float64
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(cost), 0) FROM usage_events
+		WHERE project_dir = ? AND timestamp IS NOT NULL AND substr(timestamp, 1, 19) >= ?
+	`, projectDir, since.UTC().Format("2006-01-02 15:04:05")).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("project cost since: %w", err)
+	}
+	return cost, nil
+}
+
+// UsageSince returns the messages made at or after since, oldest first.
+// Messages without a timestamp are left out.
+func (s *Store) UsageSince(since time.Time) ([]UsageEvent, error) {
+	// Timestamps are saved in UTC, so their date and time sort as text
+	rows, err := s.db.Query(`
+		SELECT timestamp, model, input_tokens, output_tokens,
+			cache_creation_input_tokens, cache_creation_1h_input_tokens, cache_read_input_tokens,
+			cost, priced, sidechain
+		FROM usage_events
+		WHERE timestamp IS NOT NULL AND substr(timestamp, 1, 19) >= ?
+	`, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("usage since: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []UsageEvent
+	for rows.Next() {
+		var m UsageEvent
+		if err := rows.Scan(&m.Timestamp, &m.Model, &m.InputTokens, &m.OutputTokens,
+			&m.CacheCreationInputTokens, &m.CacheCreation1hInputTokens, &m.CacheReadInputTokens,
+			&m.Cost, &m.Priced, &m.Sidechain); err != nil {
+			return nil, fmt.Errorf("usage since: %w", err)
+		}
+		if !m.Timestamp.Before(since) {
+			result = append(result, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("usage since: %w", err)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	return result, nil
+}
+
+// LinkUsageSession records the tmux session a Claude session runs in, so its
+// usage can be found by tmux session name after either is gone
+func (s *Store) LinkUsageSession(claudeSessionID, tmuxSession string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO usage_sessions (session_id, tmux_session) VALUES (?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET
+			tmux_session = excluded.tmux_session,
+			updated_at = CURRENT_TIMESTAMP
+	`, claudeSessionID, tmuxSession)
+	if err != nil {
+		return fmt.Errorf("link usage session: %w", err)
+	}
+	return nil
+}
+
+// UsageFilter narrows the usage ledger queries. Zero fields match everything.
+type UsageFilter struct {
+	Since       time.Time // inclusive
+	Until       time.Time // exclusive
+	ProjectDir  string
+	TmuxSession string
+}
+
+// UsageTotal is the summed usage of one group of usage events
+type UsageTotal struct {
+	Key                        string
+	Messages                   int
+	InputTokens                int64
+	OutputTokens               int64
+	CacheCreationInputTokens   int64
+	CacheCreation1hInputTokens int64
+	CacheReadInputTokens       int64
+	Cost                       float64
+	UncachedCost               float64
+	// CacheUnreadTokens is how many of the cache writes no later response
+	// read back
+	CacheUnreadTokens int64
+}
+
+// CacheHitRatio returns the share of input tokens read from the prompt
+// cache, from 0 to 1
+func (t UsageTotal) CacheHitRatio() float64 {
+	input := t.InputTokens + t.CacheCreationInputTokens + t.CacheReadInputTokens
+	if input == 0 {
+		return 0
+	}
+	return float64(t.CacheReadInputTokens) / float64(input)
+}
+
+// CacheSavings returns how much less the usage cost than it would have with
+// no prompt cache. It is negative when cache writes cost more than reads
+// saved.
+func (t UsageTotal) CacheSavings() float64 {
+	return t.UncachedCost - t.Cost
+}
+
+// CostByDay sums usage per local day, keyed YYYY-MM-DD, oldest first. Events
+// without a timestamp are left out.
+func (s *Store) CostByDay(f UsageFilter) ([]UsageTotal, error) {
+	// SQLite's localtime follows the same TZ as Go's time.Local
+	totals, err := s.usageTotals(f, `date(substr(e.timestamp, 1, 19), 'localtime')`)
+	if err != nil {
+		return nil, fmt.Errorf("cost by day: %w", err)
+	}
+	return totals, nil
+}
+
+// CostByProject sums usage per Claude project directory, most expensive first
+func (s *Store) CostByProject(f UsageFilter) ([]UsageTotal, error) {
+	totals, err := s.usageTotals(f, `e.project_dir`)
+	if err != nil {
+		return nil, fmt.Errorf("cost by project: %w", err)
+	}
+	sortByCost(totals)
+	return totals, nil
+}
+
+// CostByModel sums usage per model, most expensive first
+func (s *Store) CostByModel(f UsageFilter) ([]UsageTotal, error) {
+	totals, err := s.usageTotals(f, `e.model`)
+	if err != nil {
+		return nil, fmt.Errorf("cost by model: %w", err)
+	}
+	sortByCost(totals)
+	return totals, nil
+}
+
+// CostBySession sums usage per tmux session name, most expensive first.
+// Usage of Claude sessions never seen in tmux is left out.
+func (s *Store) CostBySession(f UsageFilter) ([]UsageTotal, error) {
+	totals, err := s.usageTotals(f, `s.tmux_session`)
+	if err != nil {
+		return nil, fmt.Errorf("cost by session: %w", err)
+	}
+	sortByCost(totals)
+	return totals, nil
+}
+
+func sortByCost(totals []UsageTotal) {
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].Cost > totals[j].Cost })
+}
+
+// usageTotals sums the events matching f per value of the SQL expression key,
+// skipping events whose key is NULL or "". Totals come ordered by key.
+//
+// The cache writes no later response read back are estimated per stream, one
+// transcript's side and model sharing a prompt cache: the next response of the
+// stream reads the cached prefix back, so whatever part of the write it didn't
+// read was wasted. The writes of a stream's last response count once the cache
+// has expired.
+func (s *Store) usageTotals(f UsageFilter, key string) ([]UsageTotal, error) {
+	var where []string
+	var args []interface{}
+	// Timestamps are saved in UTC, so their date and time sort as text
+	if !f.Since.IsZero() {
+		where = append(where, `substr(e.timestamp, 1, 19) >= ?`)
+		args = append(args, f.Since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, `substr(e.timestamp, 1, 19) < ?`)
+		args = append(args, f.Until.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if f.ProjectDir != "" {
+		where = append(where, `e.project_dir = ?`)
+		args = append(args, f.ProjectDir)
+	}
+	if f.TmuxSession != "" {
+		where = append(where, `s.tmux_session = ?`)
+		args = append(args, f.TmuxSession)
+	}
+	query := `
+		WITH events AS (
+			SELECT ` + key + ` AS key, e.timestamp, e.input_tokens, e.output_tokens,
+				e.cache_creation_input_tokens, e.cache_creation_1h_input_tokens, e.cache_read_input_tokens,
+				e.cost, e.uncached_cost,
+				LEAD(e.cache_read_input_tokens) OVER (
+					PARTITION BY e.path, e.sidechain, e.model ORDER BY e.line_offset
+				) AS next_read
+			FROM usage_events e
+			LEFT JOIN usage_sessions s ON s.session_id = e.session_id`
+	if len(where) > 0 {
+		query += "\n\t\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	query += `
+		)
+		SELECT key, COUNT(*), SUM(input_tokens), SUM(output_tokens),
+			SUM(cache_creation_input_tokens), SUM(cache_creation_1h_input_tokens), SUM(cache_read_input_tokens),
+			SUM(cost), SUM(uncached_cost),
+			SUM(CASE
+				WHEN cache_creation_input_tokens = 0 THEN 0
+				WHEN next_read IS NULL THEN CASE
+					WHEN substr(timestamp, 1, 19) > CASE WHEN cache_creation_1h_input_tokens > 0 THEN ? ELSE ? END THEN 0
+					ELSE cache_creation_input_tokens
+				END
+				ELSE MAX(0, cache_creation_input_tokens - MAX(0, next_read - cache_read_input_tokens))
+			END)
+		FROM events
+		WHERE key IS NOT NULL AND key <> ''
+		GROUP BY key
+		ORDER BY key`
+	now := time.Now().UTC()
+	args = append(args, now.Add(-time.Hour).Format("2006-01-02 15:04:05"), now.Add(-5*time.Minute).Format("2006-01-02 15:04:05"))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var totals []UsageTotal
+	for rows.Next() {
+		var t UsageTotal
+		if err := rows.Scan(&t.Key, &t.Messages, &t.InputTokens, &t.OutputTokens,
+			&t.CacheCreationInputTokens, &t.CacheCreation1hInputTokens, &t.CacheReadInputTokens,
+			&t.Cost, &t.UncachedCost, &t.CacheUnreadTokens); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
@@ -17,11 +17,18 @@ type Session struct {
 
 // Pane represents a tmux pane
 type Pane struct {
+	ID          string // tmux pane ID, e.g. "%3"
 	WindowIndex int
 	PaneIndex   int
 	Active      bool
 }
 
+// Formats shared by the exec and control-mode backends
+const (
+	sessionFormat = "#{session_name}:#{session_created}:#{session_attached}"
+	paneFormat    = "#{pane_id}:#{window_index}:#{pane_index}:#{pane_active}"
+)
+
 // Client wraps tmux commands
 type Client struct{}
 
@@ -39,8 +46,7 @@ func (c *Client) IsRunning() bool {
 
 // ListSessions returns all tmux sessions
 func (c *Client) ListSessions() ([]Session, error) {
-	cmd := exec.Command("tmux", "list-sessions", "-F",
-		"#{session_name}:#{session_created}:#{session_attached}")
+	cmd := exec.Command("tmux", "list-sessions", "-F", sessionFormat)
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -50,8 +56,12 @@ func (c *Client) ListSessions() ([]Session, error) {
 		return nil, fmt.Errorf("list sessions: %w", err)
 	}
 
+	return parseSessions(strings.Split(strings.TrimSpace(string(output)), "\n")), nil
+}
+
+func parseSessions(lines []string) []Session {
 	var sessions []Session
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+	for _, line := range lines {
 		if line == "" {
 			continue
 		}
@@ -61,7 +71,8 @@ func (c *Client) ListSessions() ([]Session, error) {
 		}
 
 		created, _ := strconv.ParseInt(parts[1], 10, 64)
-		attached := parts[2] == "1"
+		// session_attached counts clients, so anything but 0 is attached
+		attached := parts[2] != "0"
 
 		sessions = append(sessions, Session{
 			Name:     parts[0],
@@ -69,42 +80,44 @@ func (c *Client) ListSessions() ([]Session, error) {
 			Attached: attached,
 		})
 	}
-
-	return sessions, nil
+	return sessions
 }
 
 // ListPanes returns all panes in a session
 func (c *Client) ListPanes(session string) ([]Pane, error) {
-	cmd := exec.Command("tmux", "list-panes", "-t", session, "-a",
-		"-F", "#{window_index}:#{pane_index}:#{pane_active}")
+	cmd := exec.Command("tmux", "list-panes", "-t", session, "-a", "-F", paneFormat)
 
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("list panes: %w", err)
 	}
 
+	return parsePanes(strings.Split(strings.TrimSpace(string(output)), "\n")), nil
+}
+
+func parsePanes(lines []string) []Pane {
 	var panes []Pane
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+	for _, line := range lines {
 		if line == "" {
 			continue
 		}
 		parts := strings.Split(line, ":")
-		if len(parts) < 3 {
+		if len(parts) < 4 {
 			continue
 		}
 
-		windowIdx, _ := strconv.Atoi(parts[0])
-		paneIdx, _ := strconv.Atoi(parts[1])
-		active := parts[2] == "1"
+		windowIdx, _ := strconv.Atoi(parts[1])
+		paneIdx, _ := strconv.Atoi(parts[2])
+		active := parts[3] == "1"
 
 		panes = append(panes, Pane{
+			ID:          parts[0],
 			WindowIndex: windowIdx,
 			PaneIndex:   paneIdx,
 			Active:      active,
 		})
 	}
-
-	return panes, nil
+	return panes
 }
 
 // CapturePane captures the content of a tmux pane
@@ -149,6 +162,16 @@ func (c *Client) NewSession(name, path string) error {
 	return cmd.Run()
 }
 
+// PaneSession returns the name of the session owning a pane ID such as "%3"
+func (c *Client) PaneSession(paneID string) (string, error) {
+	cmd := exec.Command("tmux", "display-message", "-p", "-t", paneID, "#{session_name}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pane session %s: %w", paneID, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // KillSession kills a tmux session
 func (c *Client) KillSession(name string) error {
 	cmd := exec.Command("tmux", "kill-session", "-t", name)
@@ -0,0 +1,549 @@
+package tmux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// resyncInterval bounds how stale the session list can get when tmux
+	// sends no notification (e.g. a terminal client attaching)
+	resyncInterval = 5 * time.Second
+	commandTimeout = 2 * time.Second
+)
+
+var errControlClosed = errors.New("tmux control client closed")
+
+// ControlMode serves the read side of Client from tmux control-mode clients
+// (tmux -C), one per session, since tmux only sends a client %output for
+// panes in its own session. Pane captures are cached and only re-run after
+// tmux reports output for that pane, so steady-state polling spawns no
+// processes. Sessions without a control client, and all write operations,
+// go through the embedded Client.
+type ControlMode struct {
+	*Client
+
+	mu            sync.Mutex
+	conns         map[string]*controlConn
+	sessions      []Session
+	sessionsSync  time.Time
+	sessionsDirty bool
+	panes         map[string][]Pane      // by session; missing when stale
+	buffers       map[string]*paneBuffer // by pane ID, or defaultKey(session)
+	closed        bool
+}
+
+// paneBuffer is the last capture of a pane, valid until the pane prints again
+type paneBuffer struct {
+	session string
+	content string
+	dirty   bool
+}
+
+// NewControlMode starts a control-mode backend. It fails if tmux can't run
+// read-only control clients (tmux < 3.2), so callers can fall back to Client.
+func NewControlMode() (*ControlMode, error) {
+	cm := &ControlMode{
+		Client:  NewClient(),
+		conns:   make(map[string]*controlConn),
+		panes:   make(map[string][]Pane),
+		buffers: make(map[string]*paneBuffer),
+	}
+
+	sessions, err := cm.Client.ListSessions()
+	if err != nil && !errors.Is(err, ErrNoServer) {
+		return nil, err
+	}
+	if len(sessions) > 0 {
+		conn, err := cm.attach(sessions[0].Name)
+		if err != nil {
+			return nil, fmt.Errorf("control mode unavailable: %w", err)
+		}
+		cm.conns[sessions[0].Name] = conn
+	}
+	return cm, nil
+}
+
+// Close detaches every control client
+func (cm *ControlMode) Close() {
+	cm.mu.Lock()
+	cm.closed = true
+	conns := cm.conns
+	cm.conns = make(map[string]*controlConn)
+	cm.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.close()
+	}
+}
+
+// IsRunning checks if tmux server is running
+func (cm *ControlMode) IsRunning() bool {
+	cm.mu.Lock()
+	attached := len(cm.conns) > 0
+	cm.mu.Unlock()
+	return attached || cm.Client.IsRunning()
+}
+
+// ListSessions returns all tmux sessions, re-querying tmux only after a
+// %sessions-changed notification or every resyncInterval. It also attaches
+// control clients to new sessions and detaches from closed ones.
+func (cm *ControlMode) ListSessions() ([]Session, error) {
+	cm.mu.Lock()
+	if cm.sessions != nil && !cm.sessionsDirty && time.Since(cm.sessionsSync) < resyncInterval {
+		sessions := append([]Session(nil), cm.sessions...)
+		cm.mu.Unlock()
+		return sessions, nil
+	}
+	cm.sessionsDirty = false
+	cm.mu.Unlock()
+
+	lines, err := cm.query("", "list-sessions", "-F", sessionFormat)
+	if err != nil {
+		if errors.Is(err, ErrNoServer) {
+			cm.sync(nil)
+		}
+		return nil, err
+	}
+	sessions := parseSessions(lines)
+
+	// session_attached counts our own control clients; only terminals count
+	if clients, err := cm.query("", "list-clients", "-F", "#{client_control_mode}:#{client_session}"); err == nil {
+		terminals := make(map[string]bool)
+		for _, line := range clients {
+			if mode, session, ok := strings.Cut(line, ":"); ok && mode == "0" {
+				terminals[session] = true
+			}
+		}
+		for i := range sessions {
+			sessions[i].Attached = terminals[sessions[i].Name]
+		}
+	}
+
+	cm.sync(sessions)
+	return append([]Session(nil), sessions...), nil
+}
+
+// sync records the session list and matches control clients to it
+func (cm *ControlMode) sync(sessions []Session) {
+	cm.mu.Lock()
+	cm.sessions = sessions
+	cm.sessionsSync = time.Now()
+
+	live := make(map[string]bool, len(sessions))
+	var missing []string
+	for _, s := range sessions {
+		live[s.Name] = true
+		if _, ok := cm.conns[s.Name]; !ok {
+			missing = append(missing, s.Name)
+		}
+	}
+	var stale []*controlConn
+	for name, conn := range cm.conns {
+		if !live[name] {
+			stale = append(stale, conn)
+			cm.dropSessionLocked(name)
+		}
+	}
+	cm.mu.Unlock()
+
+	for _, conn := range stale {
+		conn.close()
+	}
+	for _, name := range missing {
+		conn, err := cm.attach(name)
+		if err != nil {
+			continue // exec fallback; retried on the next resync
+		}
+		cm.mu.Lock()
+		if _, exists := cm.conns[name]; exists || cm.closed {
+			cm.mu.Unlock()
+			conn.close()
+			continue
+		}
+		cm.conns[name] = conn
+		cm.mu.Unlock()
+	}
+}
+
+// ListPanes returns all panes in a session
+func (cm *ControlMode) ListPanes(session string) ([]Pane, error) {
+	cm.mu.Lock()
+	if panes, ok := cm.panes[session]; ok {
+		panes = append([]Pane(nil), panes...)
+		cm.mu.Unlock()
+		return panes, nil
+	}
+	_, cached := cm.conns[session]
+	cm.mu.Unlock()
+
+	if !cached {
+		return cm.Client.ListPanes(session)
+	}
+
+	lines, err := cm.query(session, "list-panes", "-s", "-t", "="+session, "-F", paneFormat)
+	if err != nil {
+		return nil, fmt.Errorf("list panes: %w", err)
+	}
+	panes := parsePanes(lines)
+
+	cm.mu.Lock()
+	if _, ok := cm.conns[session]; ok {
+		cm.panes[session] = panes
+	}
+	cm.mu.Unlock()
+	return append([]Pane(nil), panes...), nil
+}
+
+// CapturePane captures the content of a tmux pane
+func (cm *ControlMode) CapturePane(session string, window, pane int) (string, error) {
+	panes, err := cm.ListPanes(session)
+	if err != nil {
+		return cm.Client.CapturePane(session, window, pane)
+	}
+	for _, p := range panes {
+		if p.WindowIndex == window && p.PaneIndex == pane && p.ID != "" {
+			return cm.capture(session, p.ID, p.ID)
+		}
+	}
+	return cm.Client.CapturePane(session, window, pane)
+}
+
+// CapturePaneDefault captures the active pane of a session
+func (cm *ControlMode) CapturePaneDefault(session string) (string, error) {
+	return cm.capture(session, defaultKey(session), "="+session+":")
+}
+
+// GetSessionPath returns the working directory of a session
+func (cm *ControlMode) GetSessionPath(session string) (string, error) {
+	lines, err := cm.query(session, "display-message", "-p", "-t", "="+session+":", "#{pane_current_path}")
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(lines[0]), nil
+}
+
+// capture returns the buffered content for key, re-capturing target only if
+// tmux reported output since the last capture
+func (cm *ControlMode) capture(session, key, target string) (string, error) {
+	cm.mu.Lock()
+	if _, ok := cm.conns[session]; !ok {
+		cm.mu.Unlock()
+		return cm.captureTarget(session, target)
+	}
+	buf := cm.buffers[key]
+	if buf != nil && !buf.dirty {
+		content := buf.content
+		cm.mu.Unlock()
+		return content, nil
+	}
+	if buf == nil {
+		buf = &paneBuffer{session: session}
+		cm.buffers[key] = buf
+	}
+	// Clear before capturing so output racing the capture marks it again
+	buf.dirty = false
+	cm.mu.Unlock()
+
+	content, err := cm.captureTarget(session, target)
+
+	cm.mu.Lock()
+	if err != nil {
+		buf.dirty = true
+	} else {
+		buf.content = content
+	}
+	cm.mu.Unlock()
+	return content, err
+}
+
+func (cm *ControlMode) captureTarget(session, target string) (string, error) {
+	lines, err := cm.query(session, "capture-pane", "-e", "-p", "-t", target, "-S", "-50")
+	if err != nil {
+		return "", fmt.Errorf("capture pane %s: %w", target, err)
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// query runs a tmux command over the session's control client (any client
+// when session is empty), or by exec when there is none
+func (cm *ControlMode) query(session string, args ...string) ([]string, error) {
+	cm.mu.Lock()
+	conn := cm.conns[session]
+	if conn == nil && session == "" {
+		for _, c := range cm.conns {
+			conn = c
+			break
+		}
+	}
+	cm.mu.Unlock()
+
+	if conn != nil {
+		lines, err := conn.command(commandLine(args))
+		if !errors.Is(err, errControlClosed) {
+			return lines, err
+		}
+	}
+
+	output, err := exec.Command("tmux", args...).Output()
+	if err != nil {
+		if strings.Contains(err.Error(), "no server running") {
+			return nil, ErrNoServer
+		}
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(output), "\n"), "\n"), nil
+}
+
+// Notifications from control clients
+
+func (cm *ControlMode) paneOutput(session, paneID string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if buf := cm.buffers[paneID]; buf != nil {
+		buf.dirty = true
+	}
+	if buf := cm.buffers[defaultKey(session)]; buf != nil {
+		buf.dirty = true
+	}
+}
+
+func (cm *ControlMode) layoutChanged(session string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	delete(cm.panes, session)
+	if buf := cm.buffers[defaultKey(session)]; buf != nil {
+		buf.dirty = true
+	}
+}
+
+func (cm *ControlMode) sessionsChanged() {
+	cm.mu.Lock()
+	cm.sessionsDirty = true
+	cm.mu.Unlock()
+}
+
+func (cm *ControlMode) connClosed(conn *controlConn) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.conns[conn.session] == conn {
+		cm.dropSessionLocked(conn.session)
+		cm.sessionsDirty = true
+	}
+}
+
+// dropSessionLocked forgets a session's client and cached state. Caller must
+// hold cm.mu.
+func (cm *ControlMode) dropSessionLocked(session string) {
+	delete(cm.conns, session)
+	delete(cm.panes, session)
+	for key, buf := range cm.buffers {
+		if buf.session == session {
+			delete(cm.buffers, key)
+		}
+	}
+}
+
+func defaultKey(session string) string {
+	return "=" + session + ":"
+}
+
+// controlConn is one `tmux -C attach-session` client
+type controlConn struct {
+	session string
+	owner   *ControlMode
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	done    chan struct{}
+
+	mu      sync.Mutex
+	pending []chan controlReply
+}
+
+type controlReply struct {
+	lines []string
+	err   error
+}
+
+// attach starts a read-only control client that doesn't resize the session
+func (cm *ControlMode) attach(session string) (*controlConn, error) {
+	cmd := exec.Command("tmux", "-C", "attach-session", "-f", "ignore-size,read-only", "-t", "="+session)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start control client: %w", err)
+	}
+
+	conn := &controlConn{
+		session: session,
+		owner:   cm,
+		cmd:     cmd,
+		stdin:   stdin,
+		done:    make(chan struct{}),
+	}
+	go conn.readLoop(stdout)
+
+	// Round-trip once so a refused attach fails here rather than later
+	if _, err := conn.command("display-message -p ok"); err != nil {
+		conn.close()
+		return nil, fmt.Errorf("attach %s: %w", session, err)
+	}
+	return conn, nil
+}
+
+// command sends one command line and waits for its %begin/%end block
+func (c *controlConn) command(line string) ([]string, error) {
+	reply := make(chan controlReply, 1)
+
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return nil, errControlClosed
+	default:
+	}
+	c.pending = append(c.pending, reply)
+	_, err := io.WriteString(c.stdin, line+"\n")
+	c.mu.Unlock()
+	if err != nil {
+		return nil, errControlClosed
+	}
+
+	select {
+	case r := <-reply:
+		return r.lines, r.err
+	case <-c.done:
+		return nil, errControlClosed
+	case <-time.After(commandTimeout):
+		return nil, fmt.Errorf("tmux command timed out: %s", line)
+	}
+}
+
+func (c *controlConn) close() {
+	_ = c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+		_ = c.cmd.Process.Kill()
+		<-c.done
+	}
+}
+
+func (c *controlConn) readLoop(stdout io.Reader) {
+	defer func() {
+		if c.cmd != nil { // nil when reading a recorded stream
+			_ = c.cmd.Wait()
+		}
+		c.mu.Lock()
+		close(c.done)
+		c.pending = nil
+		c.mu.Unlock()
+		c.owner.connClosed(c)
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var (
+		inBlock bool
+		blockID string // "<time> <number>" shared by %begin and its %end
+		ours    bool   // block answers one of our commands (flags 1)
+		lines   []string
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if inBlock {
+			// Only the matching guard ends the block; pane content may
+			// itself contain lines starting with %end
+			if id, flags, ok := parseGuard(line, "%end "); ok && id == blockID {
+				c.deliver(ours && flags == "1", controlReply{lines: lines})
+				inBlock, lines = false, nil
+				continue
+			}
+			if id, flags, ok := parseGuard(line, "%error "); ok && id == blockID {
+				err := fmt.Errorf("tmux: %s", strings.Join(lines, "; "))
+				c.deliver(ours && flags == "1", controlReply{err: err})
+				inBlock, lines = false, nil
+				continue
+			}
+			lines = append(lines, line)
+			continue
+		}
+
+		if id, flags, ok := parseGuard(line, "%begin "); ok {
+			inBlock, blockID, ours, lines = true, id, flags == "1", nil
+			continue
+		}
+		c.notify(line)
+	}
+}
+
+// deliver hands a reply to the oldest waiting command
+func (c *controlConn) deliver(ours bool, reply controlReply) {
+	if !ours {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) == 0 {
+		return
+	}
+	c.pending[0] <- reply
+	c.pending = c.pending[1:]
+}
+
+func (c *controlConn) notify(line string) {
+	name, rest, _ := strings.Cut(line, " ")
+	switch name {
+	case "%output", "%extended-output":
+		paneID, _, _ := strings.Cut(rest, " ")
+		c.owner.paneOutput(c.session, paneID)
+	case "%window-add", "%window-close", "%unlinked-window-close",
+		"%layout-change", "%window-pane-changed", "%session-window-changed":
+		c.owner.layoutChanged(c.session)
+	case "%sessions-changed", "%session-renamed", "%session-changed":
+		c.owner.sessionsChanged()
+	}
+}
+
+// parseGuard splits "%begin|%end|%error <time> <number> <flags>"
+func parseGuard(line, prefix string) (id, flags string, ok bool) {
+	if !strings.HasPrefix(line, prefix) {
+		return "", "", false
+	}
+	fields := strings.Fields(line[len(prefix):])
+	if len(fields) != 3 {
+		return "", "", false
+	}
+	for _, f := range fields {
+		if strings.Trim(f, "0123456789") != "" {
+			return "", "", false
+		}
+	}
+	return fields[0] + " " + fields[1], fields[2], true
+}
+
+// commandLine quotes args for the tmux command parser
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
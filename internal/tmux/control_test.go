@@ -0,0 +1,261 @@
+package tmux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGuard(t *testing.T) {
+	tests := []struct {
+		line      string
+		prefix    string
+		wantID    string
+		wantFlags string
+		wantOK    bool
+	}{
+		{"%begin 1700000000 12 1", "%begin ", "1700000000 12", "1", true},
+		{"%end 1700000000 12 0", "%end ", "1700000000 12", "0", true},
+		{"%error 1700000000 13 1", "%error ", "1700000000 13", "1", true},
+		{"%end 1700000000 12 1", "%begin ", "", "", false},
+		{"%end of the story", "%end ", "", "", false},
+		{"%end 1700000000 12", "%end ", "", "", false},
+		{"%output %1 hello", "%begin ", "", "", false},
+	}
+	for _, tt := range tests {
+		id, flags, ok := parseGuard(tt.line, tt.prefix)
+		if id != tt.wantID || flags != tt.wantFlags || ok != tt.wantOK {
+			t.Errorf("parseGuard(%q, %q) = %q, %q, %v; want %q, %q, %v",
+				tt.line, tt.prefix, id, flags, ok, tt.wantID, tt.wantFlags, tt.wantOK)
+		}
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"list-sessions"}, `'list-sessions'`},
+		{[]string{"display-message", "-p", "#{pane_current_path}"}, `'display-message' '-p' '#{pane_current_path}'`},
+		{[]string{"-t", "=my session:"}, `'-t' '=my session:'`},
+		{[]string{"it's"}, `'it'\''s'`},
+		{[]string{"a;b", `"c"`, "$d", ""}, `'a;b' '"c"' '$d' ''`},
+	}
+	for _, tt := range tests {
+		if got := commandLine(tt.args); got != tt.want {
+			t.Errorf("commandLine(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+// fakeServer plays tmux's side of a control client: it reads the commands
+// the client writes and replies with a recorded stream
+type fakeServer struct {
+	t    *testing.T
+	in   *bufio.Reader
+	out  io.WriteCloser
+	conn *controlConn
+	n    int // command number, as in %begin <time> <number> <flags>
+}
+
+func newFakeServer(t *testing.T, cm *ControlMode, session string) *fakeServer {
+	t.Helper()
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	conn := &controlConn{session: session, owner: cm, stdin: stdinW, done: make(chan struct{})}
+	cm.conns[session] = conn
+	go conn.readLoop(stdoutR)
+	t.Cleanup(func() {
+		_ = stdoutW.Close()
+		_ = stdinR.Close()
+	})
+	return &fakeServer{t: t, in: bufio.NewReader(stdinR), out: stdoutW, conn: conn, n: 100}
+}
+
+func newTestControlMode() *ControlMode {
+	return &ControlMode{
+		conns:   make(map[string]*controlConn),
+		panes:   make(map[string][]Pane),
+		buffers: make(map[string]*paneBuffer),
+	}
+}
+
+// send writes lines to the client as tmux would
+func (f *fakeServer) send(lines ...string) {
+	f.t.Helper()
+	for _, line := range lines {
+		if _, err := io.WriteString(f.out, line+"\n"); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+}
+
+// expect reads the next command, checks it is want and answers it with
+// lines, or with an %error block when fail is set
+func (f *fakeServer) expect(want string, fail bool, lines ...string) {
+	f.t.Helper()
+	got, err := f.in.ReadString('\n')
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if got = strings.TrimSuffix(got, "\n"); got != want {
+		f.t.Fatalf("command = %s, want %s", got, want)
+	}
+	f.n++
+	end := "%end"
+	if fail {
+		end = "%error"
+	}
+	f.send(fmt.Sprintf("%%begin 1700000000 %d 1", f.n))
+	f.send(lines...)
+	f.send(fmt.Sprintf("%s 1700000000 %d 1", end, f.n))
+}
+
+type result struct {
+	value interface{}
+	err   error
+}
+
+// call runs fn, which sends commands the server must answer, in the
+// background
+func call(fn func() (interface{}, error)) <-chan result {
+	ch := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		ch <- result{v, err}
+	}()
+	return ch
+}
+
+func wait(t *testing.T, ch <-chan result) result {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("call didn't return")
+		return result{}
+	}
+}
+
+// roundTrip makes sure the client read everything sent before it
+func (f *fakeServer) roundTrip() {
+	f.t.Helper()
+	ch := call(func() (interface{}, error) { return f.conn.command("display-message -p ok") })
+	f.expect("display-message -p ok", false, "ok")
+	if r := wait(f.t, ch); r.err != nil {
+		f.t.Fatal(r.err)
+	}
+}
+
+func TestControlConnReplies(t *testing.T) {
+	cm := newTestControlMode()
+	f := newFakeServer(t, cm, "api")
+
+	// tmux answers the attach itself with a block of its own (flags 0)
+	f.send("%begin 1700000000 1 0", "%end 1700000000 1 0", "%session-changed $1 api")
+
+	ch := call(func() (interface{}, error) { return f.conn.command("'list-panes'") })
+	f.expect("'list-panes'", false,
+		"0 0 %1 1",
+		"%end 1700000000 99 1", // pane content, not this block's guard
+		"0 1 %2 0")
+	r := wait(t, ch)
+	want := []string{"0 0 %1 1", "%end 1700000000 99 1", "0 1 %2 0"}
+	if r.err != nil || fmt.Sprint(r.value) != fmt.Sprint(want) {
+		t.Errorf("command() = %q, %v; want %q", r.value, r.err, want)
+	}
+
+	ch = call(func() (interface{}, error) { return f.conn.command("'bogus'") })
+	f.expect("'bogus'", true, "unknown command: bogus")
+	if r := wait(t, ch); r.err == nil || r.err.Error() != "tmux: unknown command: bogus" {
+		t.Errorf("command() error = %v, want tmux's message", r.err)
+	}
+
+	// Replies go to commands in the order they were sent
+	first := call(func() (interface{}, error) { return f.conn.command("'one'") })
+	f.expect("'one'", false, "1")
+	second := call(func() (interface{}, error) { return f.conn.command("'two'") })
+	f.expect("'two'", false, "2")
+	if a, b := wait(t, first), wait(t, second); fmt.Sprint(a.value, b.value) != "[1] [2]" {
+		t.Errorf("replies = %v, %v; want [1], [2]", a.value, b.value)
+	}
+}
+
+func TestControlModeNotifications(t *testing.T) {
+	cm := newTestControlMode()
+	f := newFakeServer(t, cm, "api")
+	capture := "'capture-pane' '-e' '-p' '-t' '=api:' '-S' '-50'"
+	captureDefault := func() (interface{}, error) { return cm.CapturePaneDefault("api") }
+
+	ch := call(captureDefault)
+	f.expect(capture, false, "$ go test", "ok")
+	if r := wait(t, ch); r.err != nil || r.value != "$ go test\nok\n" {
+		t.Fatalf("CapturePaneDefault() = %q, %v", r.value, r.err)
+	}
+
+	// Without output since, the capture is served from the buffer
+	if r := wait(t, call(captureDefault)); r.value != "$ go test\nok\n" {
+		t.Errorf("cached CapturePaneDefault() = %q", r.value)
+	}
+
+	// Notifications arrive between replies, and in any order
+	cm.panes["api"] = []Pane{{ID: "%1"}, {ID: "%2"}}
+	cm.buffers["%2"] = &paneBuffer{session: "api", content: "vim"}
+	f.send("%output %1 PASS\\015\\012",
+		"%layout-change @1 b25f,80x24,0,0,1 b25f,80x24,0,0,1 *",
+		"%sessions-changed")
+	f.roundTrip()
+
+	cm.mu.Lock()
+	dirty := cm.buffers[defaultKey("api")].dirty
+	otherDirty := cm.buffers["%2"].dirty
+	_, panesCached := cm.panes["api"]
+	sessionsDirty := cm.sessionsDirty
+	cm.mu.Unlock()
+	if !dirty || otherDirty || panesCached || !sessionsDirty {
+		t.Errorf("after notifications: buffer dirty %v, other pane's %v, panes cached %v, sessions dirty %v; want true, false, false, true",
+			dirty, otherDirty, panesCached, sessionsDirty)
+	}
+
+	ch = call(captureDefault)
+	f.expect(capture, false, "PASS")
+	if r := wait(t, ch); r.value != "PASS\n" {
+		t.Errorf("CapturePaneDefault() after output = %q, want a new capture", r.value)
+	}
+
+}
+
+func TestControlConnDropped(t *testing.T) {
+	cm := newTestControlMode()
+	f := newFakeServer(t, cm, "api")
+	cm.panes["api"] = []Pane{{ID: "%1"}}
+	cm.buffers["%1"] = &paneBuffer{session: "api", content: "x"}
+
+	ch := call(func() (interface{}, error) { return f.conn.command("'list-panes'") })
+	if _, err := f.in.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	// tmux exits mid-reply
+	f.send("%begin 1700000000 5 1", "0 0 %1 1")
+	_ = f.out.Close()
+
+	if r := wait(t, ch); !errors.Is(r.err, errControlClosed) {
+		t.Errorf("command() error = %v, want errControlClosed", r.err)
+	}
+	if _, err := f.conn.command("'list-panes'"); !errors.Is(err, errControlClosed) {
+		t.Errorf("command() on a closed client = %v, want errControlClosed", err)
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if _, ok := cm.conns["api"]; ok || len(cm.panes) != 0 || len(cm.buffers) != 0 || !cm.sessionsDirty {
+		t.Errorf("after drop: conns %v, panes %v, buffers %v, sessions dirty %v; want the session forgotten",
+			cm.conns, cm.panes, cm.buffers, cm.sessionsDirty)
+	}
+}
@@ -0,0 +1,30 @@
+package tmux
+
+// Multiplexer is the set of tmux operations ccmanager uses. Client shells out
+// for each call, ControlMode serves reads from control-mode clients, and
+// tmuxtest.Fake keeps everything in memory for tests.
+type Multiplexer interface {
+	IsRunning() bool
+	ListSessions() ([]Session, error)
+	ListPanes(session string) ([]Pane, error)
+	CapturePane(session string, window, pane int) (string, error)
+	CapturePaneDefault(session string) (string, error)
+	GetSessionPath(session string) (string, error)
+	PaneSession(paneID string) (string, error)
+
+	SwitchClient(session string) error
+	NewSession(name, path string) error
+	KillSession(name string) error
+	RenameSession(oldName, newName string) error
+	NewWindow(session, name, path, command string) error
+
+	SendKeys(session, keys string) error
+	SendKeysToPane(session string, pane *Pane, keys string) error
+	SendKeysRaw(session, keys string) error
+	SendKeysToPaneRaw(session string, pane *Pane, keys string) error
+}
+
+var (
+	_ Multiplexer = (*Client)(nil)
+	_ Multiplexer = (*ControlMode)(nil)
+)
@@ -0,0 +1,363 @@
+// Package tmuxtest provides an in-memory tmux.Multiplexer for tests.
+package tmuxtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/tmux"
+)
+
+// Keys records one SendKeys* call
+type Keys struct {
+	Session string
+	Pane    *tmux.Pane // nil when sent to the session's active pane
+	Keys    string
+	Raw     bool // sent without a trailing Enter
+}
+
+// Fake is a scriptable tmux.Multiplexer. Tests set up sessions and pane
+// contents, run the code under test, then inspect Sent and Focused.
+type Fake struct {
+	mu       sync.Mutex
+	running  bool
+	sessions []*fakeSession
+	nextPane int
+
+	sent    []Keys
+	focused string
+}
+
+type fakeSession struct {
+	info  tmux.Session
+	path  string
+	panes []*fakePane
+}
+
+type fakePane struct {
+	pane    tmux.Pane
+	content string
+}
+
+var _ tmux.Multiplexer = (*Fake)(nil)
+
+// New returns a fake with a running server and no sessions
+func New() *Fake {
+	return &Fake{running: true}
+}
+
+// SetRunning simulates the tmux server starting or stopping
+func (f *Fake) SetRunning(running bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = running
+}
+
+// AddSession adds a session with a single active pane 0.0 showing content
+func (f *Fake) AddSession(name, path, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addSessionLocked(name, path)
+	f.addPaneLocked(name, 0, 0, content)
+}
+
+// AddPane adds a pane to a session. The first pane of a session is active.
+func (f *Fake) AddPane(session string, window, pane int, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addPaneLocked(session, window, pane, content)
+}
+
+// RemovePane closes a pane
+func (f *Fake) RemovePane(session string, window, pane int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.findLocked(session)
+	if s == nil {
+		return
+	}
+	for i, p := range s.panes {
+		if p.pane.WindowIndex == window && p.pane.PaneIndex == pane {
+			s.panes = append(s.panes[:i], s.panes[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetContent replaces what a pane shows
+func (f *Fake) SetContent(session string, window, pane int, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p := f.paneLocked(session, window, pane); p != nil {
+		p.content = content
+	}
+}
+
+// SetActive makes a pane the active one in its session
+func (f *Fake) SetActive(session string, window, pane int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.findLocked(session)
+	if s == nil {
+		return
+	}
+	for _, p := range s.panes {
+		p.pane.Active = p.pane.WindowIndex == window && p.pane.PaneIndex == pane
+	}
+}
+
+// SetAttached marks a session as having a terminal attached
+func (f *Fake) SetAttached(session string, attached bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s := f.findLocked(session); s != nil {
+		s.info.Attached = attached
+	}
+}
+
+// Sent returns every SendKeys* call so far
+func (f *Fake) Sent() []Keys {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Keys(nil), f.sent...)
+}
+
+// Focused returns the session of the last SwitchClient call
+func (f *Fake) Focused() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.focused
+}
+
+// IsRunning checks if tmux server is running
+func (f *Fake) IsRunning() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+// ListSessions returns all sessions in creation order
+func (f *Fake) ListSessions() ([]tmux.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.running {
+		return nil, tmux.ErrNoServer
+	}
+	sessions := make([]tmux.Session, 0, len(f.sessions))
+	for _, s := range f.sessions {
+		sessions = append(sessions, s.info)
+	}
+	return sessions, nil
+}
+
+// ListPanes returns the panes of a session
+func (f *Fake) ListPanes(session string) ([]tmux.Pane, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.findLocked(session)
+	if s == nil {
+		return nil, fmt.Errorf("list panes: can't find session %s", session)
+	}
+	panes := make([]tmux.Pane, 0, len(s.panes))
+	for _, p := range s.panes {
+		panes = append(panes, p.pane)
+	}
+	return panes, nil
+}
+
+// CapturePane returns a pane's content
+func (f *Fake) CapturePane(session string, window, pane int) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := f.paneLocked(session, window, pane)
+	if p == nil {
+		return "", fmt.Errorf("capture pane %s:%d.%d: no such pane", session, window, pane)
+	}
+	return p.content, nil
+}
+
+// CapturePaneDefault returns the active pane's content
+func (f *Fake) CapturePaneDefault(session string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.findLocked(session)
+	if s == nil || len(s.panes) == 0 {
+		return "", fmt.Errorf("capture pane %s: no such session", session)
+	}
+	for _, p := range s.panes {
+		if p.pane.Active {
+			return p.content, nil
+		}
+	}
+	return s.panes[0].content, nil
+}
+
+// GetSessionPath returns the path the session was created with
+func (f *Fake) GetSessionPath(session string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.findLocked(session)
+	if s == nil {
+		return "", fmt.Errorf("can't find session %s", session)
+	}
+	return s.path, nil
+}
+
+// PaneSession returns the session owning a pane ID
+func (f *Fake) PaneSession(paneID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.sessions {
+		for _, p := range s.panes {
+			if p.pane.ID == paneID {
+				return s.info.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("pane session %s: no such pane", paneID)
+}
+
+// SwitchClient records the focused session
+func (f *Fake) SwitchClient(session string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.findLocked(session) == nil {
+		return fmt.Errorf("can't find session %s", session)
+	}
+	f.focused = session
+	return nil
+}
+
+// NewSession creates a session with one empty pane
+func (f *Fake) NewSession(name, path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.findLocked(name) != nil {
+		return fmt.Errorf("duplicate session: %s", name)
+	}
+	f.addSessionLocked(name, path)
+	f.addPaneLocked(name, 0, 0, "")
+	return nil
+}
+
+// KillSession removes a session
+func (f *Fake) KillSession(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, s := range f.sessions {
+		if s.info.Name == name {
+			f.sessions = append(f.sessions[:i], f.sessions[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("can't find session %s", name)
+}
+
+// RenameSession renames a session
+func (f *Fake) RenameSession(oldName, newName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.findLocked(oldName)
+	if s == nil {
+		return fmt.Errorf("can't find session %s", oldName)
+	}
+	s.info.Name = newName
+	return nil
+}
+
+// NewWindow adds an empty pane in the next free window
+func (f *Fake) NewWindow(session, name, path, command string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.findLocked(session)
+	if s == nil {
+		return fmt.Errorf("can't find session %s", session)
+	}
+	window := 0
+	for _, p := range s.panes {
+		if p.pane.WindowIndex >= window {
+			window = p.pane.WindowIndex + 1
+		}
+	}
+	f.addPaneLocked(session, window, 0, "")
+	return nil
+}
+
+// SendKeys records keys followed by Enter
+func (f *Fake) SendKeys(session, keys string) error {
+	return f.record(session, nil, keys, false)
+}
+
+// SendKeysToPane records keys followed by Enter
+func (f *Fake) SendKeysToPane(session string, pane *tmux.Pane, keys string) error {
+	return f.record(session, pane, keys, false)
+}
+
+// SendKeysRaw records keys without Enter
+func (f *Fake) SendKeysRaw(session, keys string) error {
+	return f.record(session, nil, keys, true)
+}
+
+// SendKeysToPaneRaw records keys without Enter
+func (f *Fake) SendKeysToPaneRaw(session string, pane *tmux.Pane, keys string) error {
+	return f.record(session, pane, keys, true)
+}
+
+func (f *Fake) record(session string, pane *tmux.Pane, keys string, raw bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.findLocked(session) == nil {
+		return fmt.Errorf("can't find session %s", session)
+	}
+	f.sent = append(f.sent, Keys{Session: session, Pane: pane, Keys: keys, Raw: raw})
+	return nil
+}
+
+func (f *Fake) addSessionLocked(name, path string) {
+	f.sessions = append(f.sessions, &fakeSession{
+		// Distinct creation times keep Monitor's ordering deterministic
+		info: tmux.Session{Name: name, Created: time.Unix(int64(1700000000+len(f.sessions)), 0)},
+		path: path,
+	})
+}
+
+func (f *Fake) addPaneLocked(session string, window, pane int, content string) {
+	s := f.findLocked(session)
+	if s == nil {
+		return
+	}
+	p := &fakePane{
+		pane: tmux.Pane{
+			ID:          fmt.Sprintf("%%%d", f.nextPane),
+			WindowIndex: window,
+			PaneIndex:   pane,
+			Active:      len(s.panes) == 0,
+		},
+		content: content,
+	}
+	f.nextPane++
+	s.panes = append(s.panes, p)
+}
+
+func (f *Fake) findLocked(name string) *fakeSession {
+	for _, s := range f.sessions {
+		if s.info.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (f *Fake) paneLocked(session string, window, pane int) *fakePane {
+	s := f.findLocked(session)
+	if s == nil {
+		return nil
+	}
+	for _, p := range s.panes {
+		if p.pane.WindowIndex == window && p.pane.PaneIndex == pane {
+			return p
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"os"
@@ -16,8 +17,10 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/valentindosimont/ccmanager/internal/claude"
 	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/control"
 	"github.com/valentindosimont/ccmanager/internal/daemon"
 	"github.com/valentindosimont/ccmanager/internal/game"
+	"github.com/valentindosimont/ccmanager/internal/policy"
 	"github.com/valentindosimont/ccmanager/internal/store"
 	"github.com/valentindosimont/ccmanager/internal/tmux"
 	"github.com/valentindosimont/ccmanager/internal/tui/messages"
@@ -31,7 +34,8 @@ type Model struct {
 	monitor *daemon.Monitor
 	engine  *game.Engine
 	store   *store.Store
-	tmux    *tmux.Client
+	tmux    tmux.Multiplexer
+	ctrl    *control.Controller
 	config  *config.Config
 
 	// UI state
@@ -42,6 +46,7 @@ type Model struct {
 	focused     string
 	showHelp    bool
 	showStats   bool
+	statsDays   []store.DailyStats // history shown in the stats overlay
 	activityLog []ActivityEntry
 
 	// Input mode
@@ -65,15 +70,21 @@ type Model struct {
 	// Activity overlay
 	showActivity bool
 
+	// Achievements overlay, and when each achievement was unlocked by ID
+	showAchievements bool
+	achievements     map[string]time.Time
+
 	// Usage overlay
 	showUsage   bool
 	globalUsage *usage.GlobalUsage
+	usageWindow *usage.WindowStatus
 
 	// Game state (cached for display)
 	apm            int
 	streakMult     float64
 	streakCount    int
 	score          int
+	level          game.Level
 	pomodoroState  game.PomodoroState
 	pomodoroRemain time.Duration
 	dailyCost      float64
@@ -105,6 +116,10 @@ type Model struct {
 	showNotification bool
 	msgChan          chan tea.Msg
 
+	// Toast shown in place of the help bar until toastUntil
+	toast      string
+	toastUntil time.Time
+
 	// Preview cache (by session name, avoids stale pointer issues)
 	previewCache           map[string]string
 	previewHashes          map[string]uint64
@@ -115,8 +130,16 @@ type Model struct {
 	// Pending urgent session to switch to after prompt sent
 	pendingUrgent string
 
+	// Urgent sessions left for the user to answer, worth PointsUrgentHandled,
+	// and since when
+	awaitingAnswer map[string]time.Time
+
 	// Workspace repo cache (session name → source repo basename)
 	workspaceRepos map[string]string
+
+	// Attached to a running daemon: it owns sessions, scoring and the store's
+	// session rows; the dashboard follows it and keeps the pomodoro and groups
+	attached bool
 }
 
 // ActivityEntry represents a log entry
@@ -127,7 +150,7 @@ type ActivityEntry struct {
 }
 
 // New creates a new TUI model
-func New(monitor *daemon.Monitor, engine *game.Engine, store *store.Store, cfg *config.Config, wsMgr *workspace.Manager) *Model {
+func New(monitor *daemon.Monitor, engine *game.Engine, store *store.Store, cfg *config.Config, wsMgr *workspace.Manager, ctrl *control.Controller, attached bool) *Model {
 	ti := textinput.New()
 	ti.Placeholder = "session-name"
 	ti.CharLimit = 64
@@ -146,6 +169,7 @@ func New(monitor *daemon.Monitor, engine *game.Engine, store *store.Store, cfg *
 		engine:           engine,
 		store:            store,
 		tmux:             tmux.NewClient(),
+		ctrl:             ctrl,
 		config:           cfg,
 		workspaceManager: wsMgr,
 		activityLog:      make([]ActivityEntry, 0, 100),
@@ -158,6 +182,21 @@ func New(monitor *daemon.Monitor, engine *game.Engine, store *store.Store, cfg *
 		previewScrollPos: make(map[string]int),
 		autoScroll:       make(map[string]bool),
 		workspaceRepos:   make(map[string]string),
+		awaitingAnswer:   make(map[string]time.Time),
+		achievements:     make(map[string]time.Time),
+		attached:         attached,
+	}
+
+	if store != nil {
+		if unlocked, err := store.GetAchievements(); err == nil {
+			for _, a := range unlocked {
+				m.achievements[a.ID] = a.UnlockedAt
+			}
+		}
+	}
+
+	if attached && store != nil {
+		m.loadDaemonActivity()
 	}
 
 	engine.Pomodoro().OnComplete(func() {
@@ -372,32 +411,28 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						path, _ = os.Getwd()
 					}
 
-					if m.workspaceMode && m.workspaceManager != nil {
-						wsPath, err := m.workspaceManager.CreateWorkspace(path, name)
-						if err != nil {
-							m.lastError = fmt.Errorf("workspace creation failed: %w", err)
-							m.addActivity("", "Workspace creation failed: %v", err)
-						} else {
-							m.workspaceRepos[name] = filepath.Base(path)
-							if m.store != nil {
-								_ = m.store.SaveSessionWorkspace(name, wsPath, path)
-							}
-							path = wsPath
-						}
-					}
+					useWorkspace := m.workspaceMode && m.workspaceManager != nil
 					m.workspaceMode = false
 
-					if err := m.tmux.NewSession(name, path); err != nil {
-						m.lastError = fmt.Errorf("failed to create session: %w", err)
-						m.addActivity("", "Session creation failed: %v", err)
+					created, err := m.ctrl.CreateSession(control.CreateOptions{
+						Name:      name,
+						Path:      path,
+						Workspace: useWorkspace,
+					})
+					var warning *control.CreateWarning
+					if errors.As(err, &warning) {
+						m.lastError = err
+						m.addActivity("", "%s", err.Error())
+					} else if err != nil {
+						m.lastError = err
+						m.addActivity("", "Session creation failed: %s", err.Error())
 						m.inputMode = false
 						m.inputField.Blur()
 						m.selectedPath = ""
 						return m, tea.Batch(cmds...)
 					}
-					time.Sleep(100 * time.Millisecond)
-					if err := m.tmux.SendKeys(name, "claude"); err != nil {
-						m.addActivity("", "Failed to send claude command: %v", err)
+					if useWorkspace && created != path {
+						m.workspaceRepos[name] = filepath.Base(path)
 					}
 					m.addActivity("", "Created session: %s", name)
 
@@ -412,10 +447,6 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 					}
 
-					if m.store != nil {
-						_ = m.store.AddRecentPath(path)
-					}
-
 					m.sessions = m.monitor.Sessions()
 				}
 				m.inputMode = false
@@ -497,8 +528,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				if text != "" && m.selected < len(m.sessions) {
 					session := m.sessions[m.selected]
+					if session.BudgetStop != "" {
+						// Keep the prompt until the stop is acknowledged
+						m.showToast("⛔ " + session.BudgetStop + " — press B to acknowledge")
+						return m, tea.Batch(cmds...)
+					}
 					if targetMode := m.config.UI.DefaultMode; targetMode != "" {
-						if m.switchToMode(session, targetMode) {
+						if switched, _ := m.ctrl.SwitchMode(session.Name, targetMode); switched {
 							m.addActivity(session.Name, "Switched to %s mode", targetMode)
 						}
 					}
@@ -606,11 +642,12 @@ func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
 	}
 
 	// Handle overlays first
-	if m.showHelp || m.showStats || m.showActivity || m.showUsage {
+	if m.showHelp || m.showStats || m.showActivity || m.showUsage || m.showAchievements {
 		m.showHelp = false
 		m.showStats = false
 		m.showActivity = false
 		m.showUsage = false
+		m.showAchievements = false
 		return nil
 	}
 
@@ -655,11 +692,18 @@ func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
 
 	case "s":
 		m.showStats = true
+		if m.store != nil {
+			since := time.Now().AddDate(0, 0, -(statsHistoryDays - 1)).Format("2006-01-02")
+			m.statsDays, _ = m.store.GetDailyStatsSince(since)
+		}
+
+	case "A":
+		m.showAchievements = true
 
 	case "u":
 		m.showUsage = true
 		go func() {
-			global, _ := usage.GetGlobalUsage()
+			global, _ := m.monitor.Usage().GlobalUsage()
 			m.msgChan <- messages.GlobalUsageMsg{Usage: global}
 		}()
 
@@ -750,6 +794,19 @@ func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
 	case "D":
 		m.showActivity = true
 
+	case "B":
+		if m.selected < len(m.sessions) {
+			session := m.sessions[m.selected]
+			acknowledged, err := m.ctrl.AcknowledgeBudgetStop(session.Name)
+			switch {
+			case err != nil:
+				m.addActivity(session.Name, fmt.Sprintf("Budget stop not acknowledged: %v", err))
+			case acknowledged:
+				m.addActivity(session.Name, "Budget stop acknowledged")
+				m.sessions = m.monitor.Sessions()
+			}
+		}
+
 	case "i", "/":
 		if m.selected < len(m.sessions) {
 			m.promptMode = true
@@ -776,7 +833,9 @@ func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
 			now := time.Now()
 			if m.deletePressed && now.Sub(m.deleteTime) < 500*time.Millisecond {
 				session := m.sessions[m.selected]
-				_ = m.tmux.KillSession(session.Name)
+				if err := m.ctrl.KillSession(session.Name); err != nil {
+					m.addActivity(session.Name, "%s", err.Error())
+				}
 				m.addActivity(session.Name, "Session deleted")
 				m.sessions = m.monitor.Sessions()
 				m.deletePressed = false
@@ -840,20 +899,11 @@ func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
 }
 
 func (m *Model) generateSessionNameFromPath(dir string) string {
-	base := filepath.Base(dir)
-	for i := 1; ; i++ {
-		name := fmt.Sprintf("%s-%d", base, i)
-		exists := false
-		for _, s := range m.sessions {
-			if s.Name == name {
-				exists = true
-				break
-			}
-		}
-		if !exists {
-			return name
-		}
+	names := make([]string, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		names = append(names, s.Name)
 	}
+	return control.NextSessionName(dir, names)
 }
 
 func (m *Model) buildPathList() list.Model {
@@ -959,7 +1009,9 @@ func (m *Model) handleSessionEvent(event daemon.Event) {
 		m.addActivity(event.Session, "Session discovered")
 		m.sessions = m.monitor.Sessions()
 		if m.store != nil {
-			_ = m.store.CreateSession(event.Session)
+			if !m.attached {
+				_ = m.store.CreateSession(event.Session)
+			}
 			if _, sourceRepo, err := m.store.GetSessionWorkspace(event.Session); err == nil && sourceRepo != "" {
 				m.workspaceRepos[event.Session] = filepath.Base(sourceRepo)
 			}
@@ -971,16 +1023,13 @@ func (m *Model) handleSessionEvent(event daemon.Event) {
 		m.engine.ControlGroups().RemoveSession(event.Session)
 		m.engine.RemoveSession(event.Session)
 		delete(m.workspaceRepos, event.Session)
+		delete(m.awaitingAnswer, event.Session)
 		if m.selected >= len(m.sessions) {
 			m.selected = max(0, len(m.sessions)-1)
 		}
-		if m.store != nil {
-			wsPath, sourceRepo, err := m.store.GetSessionWorkspace(event.Session)
-			if err == nil && wsPath != "" && m.workspaceManager != nil {
-				if delErr := m.workspaceManager.DeleteWorkspace(sourceRepo, wsPath); delErr != nil {
-					m.addActivity(event.Session, "Workspace cleanup failed: %v", delErr)
-				}
-				_ = m.store.DeleteSessionWorkspace(event.Session)
+		if m.store != nil && !m.attached {
+			if err := m.ctrl.CleanupWorkspace(event.Session); err != nil {
+				m.addActivity(event.Session, "%s", err.Error())
 			}
 			_ = m.store.DeleteSession(event.Session)
 		}
@@ -990,46 +1039,183 @@ func (m *Model) handleSessionEvent(event daemon.Event) {
 		m.sessions = m.monitor.Sessions()
 		isActive := event.State == claude.StateThinking || event.State == claude.StateActive
 		m.engine.SetSessionActivity(event.Session, isActive)
+		m.engine.SetSessionThinking(event.Session, event.State == claude.StateThinking)
 		if m.interactiveMode && m.focused == event.Session && event.State != claude.StateUrgent {
 			m.interactiveMode = false
 		}
-		if m.store != nil {
+		if since, ok := m.awaitingAnswer[event.Session]; ok && event.State != claude.StateUrgent {
+			delete(m.awaitingAnswer, event.Session)
+			points := m.engine.RecordUrgentHandled(time.Since(since))
+			m.addActivity(event.Session, "Urgent handled (+%d)", points)
+		}
+		if m.store != nil && !m.attached {
 			_ = m.store.UpdateSessionLastSeen(event.Session)
 		}
 
 	case daemon.EventTaskCompleted:
+		if m.attached {
+			// The daemon awards the points; the score syncs from the store
+			m.addActivity(event.Session, "Task completed")
+			break
+		}
 		points := m.engine.RecordTaskComplete()
 		m.addActivity(event.Session, "Task completed (+%d)", points)
 
 	case daemon.EventUrgent:
-		m.addActivity(event.Session, "⚠ URGENT: %s", event.Message)
+		if event.Pattern != "" {
+			m.addActivity(event.Session, "⚠ URGENT [%s]: %s", event.Pattern, event.Message)
+		} else {
+			m.addActivity(event.Session, "⚠ URGENT: %s", event.Message)
+		}
+		if m.store != nil && !m.attached {
+			_ = m.store.UpdateSessionLastSeen(event.Session)
+		}
+		if m.attached {
+			// The daemon's policy may have answered it already, which only
+			// the daemon knows, so the selection is left alone
+			break
+		}
+		if m.applyPolicy(event.Session) {
+			break // answered, nothing for the user to do
+		}
 		if m.promptMode {
 			m.pendingUrgent = event.Session
 		} else {
 			m.selectByName(event.Session)
+			// The focused session is asking: answer it with y/n/a
+			if m.focused == event.Session && m.config != nil && m.config.UI.InteractiveOnUrgent {
+				m.interactiveMode = true
+			}
 		}
-		if m.store != nil {
-			_ = m.store.UpdateSessionLastSeen(event.Session)
-		}
+
+	case daemon.EventBudget:
+		m.addActivity(event.Session, "💰 %s", event.Message)
+		m.showToast("💰 " + event.Message)
+		m.sessions = m.monitor.Sessions()
+
+	case daemon.EventCompaction:
+		m.addActivity(event.Session, "%s", event.Message)
 
 	case daemon.EventDebug:
 		m.addActivity("DEBUG", event.Message)
 	}
 }
 
+// toastDuration is how long a toast stays in the help bar
+const toastDuration = 8 * time.Second
+
+// showToast shows msg in place of the help bar for a few seconds
+func (m *Model) showToast(msg string) {
+	m.toast = msg
+	m.toastUntil = time.Now().Add(toastDuration)
+}
+
+// applyPolicy runs the auto-approval policy on an urgent session and reports
+// whether it answered the prompt. Unanswered prompts earn points once the
+// user answers them; the daemon does the same when attached.
+func (m *Model) applyPolicy(session string) bool {
+	decision, perm, err := m.ctrl.ApplyPolicy(session)
+	if err != nil {
+		m.addActivity(session, "%s", err.Error())
+	}
+	if perm != nil {
+		m.addActivity(session, "Policy %s: %s", decision.String(), perm.Summary())
+	}
+	if err == nil && perm != nil && decision.Outcome != policy.Ask && !decision.DryRun {
+		return true
+	}
+	m.awaitingAnswer[session] = time.Now()
+	return false
+}
+
 func (m *Model) updateGameState() {
 	m.apm = m.engine.APM()
 	m.streakMult = m.engine.StreakMultiplier()
 	m.streakCount = m.engine.StreakCount()
 	m.score = m.engine.Score()
+	m.level = game.LevelFor(m.engine.XP())
 	m.pomodoroState = m.engine.Pomodoro().State()
 	m.pomodoroRemain = m.engine.Pomodoro().Remaining()
+	m.usageWindow = m.monitor.UsageWindow()
+	m.unlockAchievements()
+	m.announceLevelUp()
 	m.costPollTick++
 	if m.costPollTick >= 25 && m.store != nil {
 		m.costPollTick = 0
 		if stats, err := m.store.GetTodayStats(); err == nil {
 			m.dailyCost = stats.DailyCost
 		}
+		m.syncAchievements()
+		if m.attached {
+			if gameState, err := m.store.GetGameState(); err == nil {
+				m.engine.SyncScore(gameState.CurrentScore, gameState.LastScoreDate)
+			}
+		}
+	}
+}
+
+// unlockAchievements saves and announces the achievements the engine
+// unlocked. One another process saved first is announced by
+// syncAchievements instead.
+func (m *Model) unlockAchievements() {
+	for _, a := range m.engine.TakeUnlocked() {
+		if m.store != nil {
+			if isNew, err := m.store.UnlockAchievement(a.ID, a.Name, a.Description); err != nil || !isNew {
+				continue
+			}
+		}
+		m.announceAchievement(a.ID, a.Name, time.Now())
+	}
+}
+
+// syncAchievements announces achievements unlocked by another process, such
+// as the daemon
+func (m *Model) syncAchievements() {
+	unlocked, err := m.store.GetAchievements()
+	if err != nil {
+		return
+	}
+	for _, a := range unlocked {
+		if _, ok := m.achievements[a.ID]; !ok {
+			m.engine.LoadAchievements([]string{a.ID})
+			m.announceAchievement(a.ID, a.Name, a.UnlockedAt)
+		}
+	}
+}
+
+func (m *Model) announceAchievement(id, name string, at time.Time) {
+	m.achievements[id] = at
+	m.addActivity("", "🏆 Achievement unlocked: %s", name)
+	m.showToast("🏆 Achievement unlocked: " + name)
+}
+
+// announceLevelUp announces the level the engine's XP reached, if any
+func (m *Model) announceLevelUp() {
+	l, ok := m.engine.TakeLevelUp()
+	if !ok {
+		return
+	}
+	msg := fmt.Sprintf("⭐ Level %d (%s)", l.Number, l.Rank.Name)
+	if l.Promoted() {
+		msg = fmt.Sprintf("⭐ Level %d — promoted to %s", l.Number, l.Rank.Name)
+	}
+	m.addActivity("", "%s", msg)
+	m.showToast(msg)
+}
+
+// loadDaemonActivity seeds the activity log with what the daemon recorded
+// while no dashboard was open
+func (m *Model) loadDaemonActivity() {
+	entries, err := m.store.GetRecentActivity(100)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		m.activityLog = append(m.activityLog, ActivityEntry{
+			Time:    e.Timestamp.Local(),
+			Session: e.SessionName,
+			Message: e.Message,
+		})
 	}
 }
 
@@ -1170,6 +1356,13 @@ func (m *Model) isInteractiveEligible() bool {
 	return false
 }
 
+// permissionAnswers maps interactive-mode keys to permission dialog answers
+var permissionAnswers = map[string]claude.Answer{
+	"y": claude.AnswerYes,
+	"a": claude.AnswerAlways,
+	"n": claude.AnswerNo,
+}
+
 func (m *Model) handleInteractiveKey(msg tea.KeyMsg) tea.Cmd {
 	var focusedSession *daemon.SessionState
 	for _, sess := range m.sessions {
@@ -1189,19 +1382,19 @@ func (m *Model) handleInteractiveKey(msg tea.KeyMsg) tea.Cmd {
 		m.focused = ""
 		return nil
 	case "up", "k":
-		_ = m.tmux.SendKeysToPaneRaw(focusedSession.Name, focusedSession.ClaudePane, "Up")
+		_ = m.ctrl.SendKey(focusedSession.Name, control.KeyUp)
 		return nil
 	case "down", "j":
-		_ = m.tmux.SendKeysToPaneRaw(focusedSession.Name, focusedSession.ClaudePane, "Down")
+		_ = m.ctrl.SendKey(focusedSession.Name, control.KeyDown)
 		return nil
 	case "enter":
-		_ = m.tmux.SendKeysToPaneRaw(focusedSession.Name, focusedSession.ClaudePane, "Enter")
-		return nil
-	case "y":
-		_ = m.tmux.SendKeysToPaneRaw(focusedSession.Name, focusedSession.ClaudePane, "y")
+		_ = m.ctrl.SendKey(focusedSession.Name, control.KeyEnter)
 		return nil
-	case "n":
-		_ = m.tmux.SendKeysToPaneRaw(focusedSession.Name, focusedSession.ClaudePane, "n")
+	case "y", "n", "a":
+		// Pick the dialog's numbered option; y/n still work for [y/N] prompts
+		if err := m.ctrl.Answer(focusedSession.Name, permissionAnswers[msg.String()]); err != nil {
+			m.addActivity(focusedSession.Name, "%s", err.Error())
+		}
 		return nil
 	case "i":
 		m.promptMode = true
@@ -1237,30 +1430,3 @@ func max(a, b int) int {
 	}
 	return b
 }
-
-func (m *Model) switchToMode(session *daemon.SessionState, targetMode string) bool {
-	if targetMode == "" || session.State == claude.StateUrgent {
-		return false
-	}
-
-	content := m.previewCache[session.Name]
-	detector := claude.NewDetector()
-	currentMode := detector.DetectMode(content)
-
-	if strings.EqualFold(currentMode, targetMode) {
-		return false
-	}
-
-	const maxCycles = 3
-	for i := 0; i < maxCycles; i++ {
-		_ = m.tmux.SendKeysToPaneRaw(session.Name, session.ClaudePane, "BTab")
-		time.Sleep(50 * time.Millisecond)
-
-		newContent, _ := m.tmux.CapturePaneDefault(session.Name)
-		if strings.EqualFold(detector.DetectMode(newContent), targetMode) {
-			time.Sleep(50 * time.Millisecond)
-			return true
-		}
-	}
-	return false
-}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/charmbracelet/x/ansi"
 	"github.com/valentindosimont/ccmanager/internal/claude"
 	"github.com/valentindosimont/ccmanager/internal/game"
+	"github.com/valentindosimont/ccmanager/internal/store"
+	"github.com/valentindosimont/ccmanager/internal/usage"
 )
 
 // Colors
@@ -79,6 +82,14 @@ func (m *Model) View() string {
 		return m.viewActivityOverlay()
 	}
 
+	if m.showUsage {
+		return m.viewUsage()
+	}
+
+	if m.showAchievements {
+		return m.viewAchievements()
+	}
+
 	// Calculate layout dimensions
 	innerWidth := m.width - 2 // account for outer border
 
@@ -175,8 +186,17 @@ func (m *Model) viewHeader(width int) string {
 	}
 
 	score := statStyle.Render(fmt.Sprintf("SCORE: %s", formatScore(m.score)))
+	level := statStyle.Render(fmt.Sprintf("%s %d %s", strings.ToUpper(m.level.Rank.Name), m.level.Number, levelBar(m.level, levelBarCells)))
 	cost := statStyle.Render(fmt.Sprintf("COST: $%.2f", m.dailyCost))
 
+	var window string
+	if w := m.usageWindow; w != nil && (w.Block != nil || w.WeekLimit > 0) {
+		window = statStyle.Render(formatWindow(w))
+		if w.BlockPercent() >= 90 || w.WeekPercent() >= 90 || !w.Exhausts.IsZero() {
+			window = urgentStyle.Render(formatWindow(w))
+		}
+	}
+
 	pomodoroStr := m.formatPomodoro()
 	pomodoro := statStyle.Render(pomodoroStr)
 	if m.pomodoroState == game.PomodoroWork {
@@ -188,7 +208,10 @@ func (m *Model) viewHeader(width int) string {
 	if usageStr != "" {
 		statParts = append(statParts, statStyle.Render(usageStr))
 	}
-	statParts = append(statParts, cost, apm, streak, score, pomodoro)
+	if window != "" {
+		statParts = append(statParts, window)
+	}
+	statParts = append(statParts, cost, apm, streak, score, level, pomodoro)
 	stats := strings.Join(statParts, "  │  ")
 	statsWidth := lipgloss.Width(stats)
 	titleWidth := lipgloss.Width(title)
@@ -201,6 +224,29 @@ func (m *Model) viewHeader(width int) string {
 	return " " + title + strings.Repeat(" ", padding) + stats + " "
 }
 
+// formatWindow summarises the 5-hour block and weekly limit, e.g.
+// "block: 62% used, resets 14:05"
+func formatWindow(w *usage.WindowStatus) string {
+	var parts []string
+	if b := w.Block; b != nil {
+		switch {
+		case w.BlockLimit <= 0:
+			parts = append(parts, fmt.Sprintf("block: %s, resets %s", formatTokensLarge(b.Tokens()), b.End.Format("15:04")))
+		case w.BlockMax:
+			parts = append(parts, fmt.Sprintf("block: %.0f%% of max, resets %s", w.BlockPercent(), b.End.Format("15:04")))
+		default:
+			parts = append(parts, fmt.Sprintf("block: %.0f%% used, resets %s", w.BlockPercent(), b.End.Format("15:04")))
+		}
+		if !w.Exhausts.IsZero() {
+			parts[0] += ", out " + w.Exhausts.Format("15:04")
+		}
+	}
+	if w.WeekLimit > 0 {
+		parts = append(parts, fmt.Sprintf("week: %.0f%%", w.WeekPercent()))
+	}
+	return strings.Join(parts, " · ")
+}
+
 func (m *Model) formatPomodoro() string {
 	if m.pomodoroState == game.PomodoroStopped {
 		return "🍅 --:--"
@@ -355,14 +401,23 @@ func (m *Model) viewSessionList(width, height int) string {
 		elapsed := time.Since(sess.Created)
 		elapsedStr := formatDuration(elapsed)
 
-		// Format cost if available
+		// Format cost and context fill if available
 		costStr := ""
+		contextStr := strings.Repeat(" ", contextBarCells+1)
 		if sess.Usage != nil && sess.Usage.EstimatedCost > 0 {
 			costStr = fmt.Sprintf("$%.2f", sess.Usage.EstimatedCost)
 		}
+		if sess.Usage != nil && sess.Usage.Context.Limit > 0 {
+			contextStr = contextBar(sess.Usage.Context, contextBarCells)
+			if sess.Usage.Context.NearCompaction() {
+				contextStr += "!"
+			} else {
+				contextStr += " "
+			}
+		}
 
 		// Calculate available width for session name
-		nameWidth := width - 32 // cursor(2) + group(4) + icon(2) + state(8) + elapsed(6) + cost(7) + padding
+		nameWidth := width - 42 // cursor(2) + badge(3) + group(4) + icon(2) + state(8) + elapsed(6) + context(7) + cost(7) + padding
 		if nameWidth < 8 {
 			nameWidth = 8
 		}
@@ -372,14 +427,16 @@ func (m *Model) viewSessionList(width, height int) string {
 			displayName = fmt.Sprintf("%s (%s)", sess.Name, repoName)
 		}
 
-		line := fmt.Sprintf("%s%-*s %s %s%-8s %5s %6s",
+		line := fmt.Sprintf("%s%s %-*s %s %s%-8s %5s %s %6s",
 			cursor,
+			sess.Agent.Badge(),
 			nameWidth,
 			truncate(displayName, nameWidth),
 			groupStr,
 			stateIcon,
 			stateStr,
 			elapsedStr,
+			contextStr,
 			costStr,
 		)
 
@@ -457,8 +514,42 @@ func (m *Model) viewPreview(width, height int) string {
 	if sess.Usage != nil {
 		usageStr := formatUsageCompact(sess.Usage.TotalUsage.TotalInput(), sess.Usage.TotalUsage.OutputTokens, sess.Usage.EstimatedCost)
 		statusLine += " · " + usageStr
+		if sub := sess.Usage.Subagents; sub.Cost > 0 {
+			statusLine += fmt.Sprintf(" (subagents $%.2f)", sub.Cost)
+		}
+		if len(sess.Usage.Unpriced) > 0 {
+			statusLine += " ⚠ unpriced model"
+		}
 	}
 	lines = append(lines, statStyle.Render(statusLine))
+	if sess.Usage != nil && sess.Usage.Context.Limit > 0 {
+		c := sess.Usage.Context
+		contextLine := fmt.Sprintf(" context %s %.0f%% (%s of %s)", contextBar(c, 20), c.Percent(),
+			formatTokensLarge(c.Used), formatTokensLarge(c.Limit))
+		if c.NearCompaction() {
+			lines = append(lines, urgentStyle.Render(contextLine+" · auto-compact soon"))
+		} else {
+			lines = append(lines, mutedStyle.Render(contextLine))
+		}
+	}
+	if sess.BudgetStop != "" {
+		lines = append(lines, urgentStyle.Render(" ⛔ "+truncate(sess.BudgetStop+" · prompts held, B to acknowledge", width-4)))
+	}
+
+	// Permission dialog, with the interactive-mode key for each option
+	if p := sess.Permission; p != nil {
+		lines = append(lines, urgentStyle.Render(" ⚠ "+truncate(p.Summary(), width-4)))
+		for _, opt := range p.Options {
+			key := "   "
+			for k, answer := range permissionAnswers {
+				if o, ok := p.Option(answer); ok && o.Number == opt.Number {
+					key = "[" + k + "]"
+				}
+			}
+			line := fmt.Sprintf("   %s %d. %s", key, opt.Number, opt.Label)
+			lines = append(lines, mutedStyle.Render(truncate(line, width-1)))
+		}
+	}
 
 	// Get content: use direct capture for selected (with cache fallback), cache for others
 	var content string
@@ -630,9 +721,12 @@ func (m *Model) viewActivityOverlay() string {
 }
 
 func (m *Model) viewHelpBar(width int) string {
+	if m.toast != "" && time.Now().Before(m.toastUntil) {
+		return urgentStyle.Render(" " + truncate(m.toast, width-2))
+	}
 	var help string
 	if m.interactiveMode {
-		help = "[↑↓/jk] select  [Enter] confirm  [y/n] quick  [i] text  [Esc] exit"
+		help = "[↑↓/jk] select  [Enter] confirm  [y/n/a] answer  [i] text  [Esc] exit"
 	} else {
 		help = "[↑↓] nav  [i] prompt  [x] cancel  [c] int  [dd] del  [n] new  [e] editor  [?] help  [q] quit"
 	}
@@ -672,6 +766,7 @@ PROMPT
   x           Cancel Claude task (Escape)
   c           Interrupt Claude (Ctrl+C)
   D           Show activity overlay
+  B           Acknowledge budget stop
 
 CONTROL GROUPS
   1-9, 0      Tap: cycle, Double-tap: focus
@@ -681,6 +776,8 @@ GAME
   p           Start/pause pomodoro
   P           Stop pomodoro
   s           Show statistics
+  u           Show token usage by model
+  A           Show achievements
 
 GENERAL
   ?           Toggle help
@@ -695,6 +792,9 @@ GENERAL
 		Render(help)
 }
 
+// statsHistoryDays is how many days the stats overlay charts
+const statsHistoryDays = 30
+
 func (m *Model) viewStats() string {
 	stats := fmt.Sprintf(`
             SESSION STATISTICS
@@ -704,8 +804,10 @@ Today
   APM (current):  %d
   Best Streak:    x%.1f
 
+%s
+%s
          Press any key to close
-`, formatScore(m.score), m.apm, m.streakMult)
+`, formatScore(m.score), m.apm, m.streakMult, statsHistory(m.statsDays, time.Now()), levelLines(m.level))
 
 	return lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
@@ -714,6 +816,259 @@ Today
 		Render(stats)
 }
 
+// levelLines describes the lifetime level and the rank ladder, marking the
+// rank reached
+func levelLines(l game.Level) string {
+	ranks := make([]string, len(game.Ranks))
+	for i, r := range game.Ranks {
+		ranks[i] = fmt.Sprintf("%s %d", r.Name, r.MinLevel)
+		if r == l.Rank {
+			ranks[i] = "[" + ranks[i] + "]"
+		}
+	}
+	return fmt.Sprintf(`Level
+  %-16s%s XP, %s to level %d
+  Ladder:         %s
+`, fmt.Sprintf("%s %d:", l.Rank.Name, l.Number), formatScore(l.XP), formatScore(l.Next-l.XP), l.Number+1,
+		strings.Join(ranks, " · "))
+}
+
+// statsHistory charts the daily stats of the statsHistoryDays days up to
+// today, one sparkline per stat, followed by the total
+func statsHistory(days []store.DailyStats, today time.Time) string {
+	byDate := make(map[string]store.DailyStats, len(days))
+	for _, d := range days {
+		byDate[d.Date] = d
+	}
+	first := today.AddDate(0, 0, -(statsHistoryDays - 1))
+	series := func(value func(store.DailyStats) float64) []float64 {
+		values := make([]float64, statsHistoryDays)
+		for i := range values {
+			values[i] = value(byDate[first.AddDate(0, 0, i).Format("2006-01-02")])
+		}
+		return values
+	}
+	sum := func(values []float64) (total float64) {
+		for _, v := range values {
+			total += v
+		}
+		return total
+	}
+
+	score := series(func(d store.DailyStats) float64 { return float64(d.TotalScore) })
+	actions := series(func(d store.DailyStats) float64 { return float64(d.TotalActions) })
+	pomodoros := series(func(d store.DailyStats) float64 { return float64(d.PomodorosCompleted) })
+	flow := series(func(d store.DailyStats) float64 { return float64(d.FlowTimeSeconds) })
+	streak := series(func(d store.DailyStats) float64 { return d.MaxStreak })
+	cost := series(func(d store.DailyStats) float64 { return d.DailyCost })
+
+	todayStats := byDate[today.Format("2006-01-02")]
+	var b strings.Builder
+	fmt.Fprintf(&b, "  Actions %d  Pomodoros %d  Flow %s\n", todayStats.TotalActions, todayStats.PomodorosCompleted,
+		formatDuration(time.Duration(todayStats.FlowTimeSeconds)*time.Second))
+	fmt.Fprintf(&b, "\nLast %d days\n", statsHistoryDays)
+	row := func(label string, values []float64, total string) {
+		fmt.Fprintf(&b, "  %-10s %s  %s\n", label, sparkline(values), total)
+	}
+	row("Score", score, formatScore(int(sum(score))))
+	row("Actions", actions, fmt.Sprintf("%d", int(sum(actions))))
+	row("Pomodoros", pomodoros, fmt.Sprintf("%d", int(sum(pomodoros))))
+	row("Flow", flow, formatDuration(time.Duration(sum(flow))*time.Second))
+	row("Streak", streak, fmt.Sprintf("best x%.1f", slices.Max(streak)))
+	row("Cost", cost, fmt.Sprintf("$%.2f", sum(cost)))
+	fmt.Fprintf(&b, "  %-10s %-*s%s\n", "", statsHistoryDays-6, first.Format("Jan 2"), today.Format("Jan 2"))
+	return b.String()
+}
+
+// sparkBars are the bar heights of a sparkline, lowest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as bars scaled to the largest. Zero is a gap.
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	top := slices.Max(values)
+	line := make([]rune, len(values))
+	for i, v := range values {
+		if v <= 0 || top == 0 {
+			line[i] = ' '
+			continue
+		}
+		line[i] = sparkBars[int(v/top*float64(len(sparkBars)-1)+0.5)]
+	}
+	return string(line)
+}
+
+func (m *Model) viewAchievements() string {
+	lines := []string{titleStyle.Render("ACHIEVEMENTS"), ""}
+	unlocked := 0
+	for _, a := range game.Achievements {
+		line := fmt.Sprintf("%-12s %s", a.Name, a.Description)
+		if at, ok := m.achievements[a.ID]; ok {
+			unlocked++
+			lines = append(lines, "🏆 "+statStyle.Render(line)+mutedStyle.Render("  "+at.Local().Format("Jan 2")))
+		} else {
+			lines = append(lines, mutedStyle.Render("·  "+line))
+		}
+	}
+	lines = append(lines, "", fmt.Sprintf("%d of %d unlocked", unlocked, len(game.Achievements)),
+		"", helpStyle.Render("Press any key to close"))
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorPrimary).
+		Padding(1, 2).
+		Render(strings.Join(lines, "\n"))
+}
+
+func (m *Model) viewUsage() string {
+	var lines []string
+	lines = append(lines, titleStyle.Render("TOKEN USAGE"), "")
+
+	if w := m.usageWindow; w != nil {
+		lines = append(lines, usageWindowLines(w)...)
+		lines = append(lines, "")
+	}
+
+	if len(m.sessions) > 0 {
+		if sess := m.sessions[m.selected]; sess != nil && sess.Usage != nil {
+			u := sess.Usage
+			lines = append(lines, fmt.Sprintf("%s  %s", sess.Name,
+				formatUsageCompact(u.TotalUsage.TotalInput(), u.TotalUsage.OutputTokens, u.EstimatedCost)))
+			if sub := u.Subagents; sub.Usage != (usage.TokenUsage{}) {
+				parent := u.Parent()
+				lines = append(lines, mutedStyle.Render(fmt.Sprintf("  main %s  ·  subagents %s",
+					formatUsageCompact(parent.Usage.TotalInput(), parent.Usage.OutputTokens, parent.Cost),
+					formatUsageCompact(sub.Usage.TotalInput(), sub.Usage.OutputTokens, sub.Cost))))
+			}
+			lines = append(lines, usageModelLines(&u.ModelBreakdown)...)
+			if m.globalUsage != nil {
+				if c := m.globalUsage.Cache.Session(sess.Name); c != nil {
+					lines = append(lines, mutedStyle.Render("  cache "+formatCacheUse(*c)))
+				}
+			}
+			lines = append(lines, "")
+		}
+	}
+
+	if m.globalUsage == nil {
+		lines = append(lines, mutedStyle.Render("Reading transcripts…"))
+	} else {
+		g := m.globalUsage
+		lines = append(lines, fmt.Sprintf("All projects  sessions: %d  projects: %d", g.SessionCount, g.ProjectCount))
+		lines = append(lines, fmt.Sprintf("  %s  cache write %s read %s",
+			formatUsageCompact(g.TotalUsage.TotalInput(), g.TotalUsage.OutputTokens, g.EstimatedCost),
+			formatTokensLarge(g.TotalUsage.CacheCreationInputTokens),
+			formatTokensLarge(g.TotalUsage.CacheReadInputTokens)))
+		lines = append(lines, usageModelLines(&g.ModelBreakdown)...)
+		if g.Cache != nil {
+			lines = append(lines, "", "Prompt cache  "+formatCacheUse(g.Cache.Total))
+			lines = append(lines, cacheProjectLines(g.Cache.Projects)...)
+		}
+	}
+
+	lines = append(lines, "", helpStyle.Render("Press any key to close"))
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorPrimary).
+		Padding(1, 2).
+		Render(strings.Join(lines, "\n"))
+}
+
+// usageWindowLines describes the 5-hour block and week in progress, with
+// how fast they are being used up
+func usageWindowLines(w *usage.WindowStatus) []string {
+	var lines []string
+	if b := w.Block; b != nil {
+		line := fmt.Sprintf("5-hour block  %s–%s  %s  $%.2f", b.Start.Format("15:04"), b.End.Format("15:04"),
+			formatTokensLarge(b.Tokens()), b.Cost)
+		if w.BlockLimit > 0 {
+			limit := formatTokensLarge(w.BlockLimit)
+			if w.BlockMax {
+				limit = "max " + limit
+			}
+			line += fmt.Sprintf("  %.0f%% of %s", w.BlockPercent(), limit)
+		}
+		lines = append(lines, line)
+		burn := fmt.Sprintf("  burn %s/min  $%.2f/h", formatTokensLarge(int64(w.BurnRate)), w.CostPerHour)
+		if !w.Exhausts.IsZero() {
+			burn += urgentStyle.Render("  runs out at " + w.Exhausts.Format("15:04"))
+		}
+		lines = append(lines, mutedStyle.Render(burn))
+	} else {
+		lines = append(lines, mutedStyle.Render("5-hour block  none in progress"))
+	}
+
+	week := "Last 7 days"
+	if !w.WeekEnd.IsZero() {
+		week = "Week until " + w.WeekEnd.Format("Mon 15:04")
+	}
+	line := fmt.Sprintf("%s  %s  $%.2f", week, formatTokensLarge(w.WeekUsage.Total()), w.WeekCost)
+	if w.WeekLimit > 0 {
+		line += fmt.Sprintf("  %.0f%% of %s", w.WeekPercent(), formatTokensLarge(w.WeekLimit))
+	}
+	lines = append(lines, line)
+	if !w.WeekExhausts.IsZero() {
+		lines = append(lines, urgentStyle.Render("  runs out "+w.WeekExhausts.Format("Mon 15:04")+" at this rate"))
+	}
+	return lines
+}
+
+// usageModelLines lists each model's share of usage, most expensive first,
+// and warns about models without a price
+func usageModelLines(b *usage.ModelBreakdown) []string {
+	var lines []string
+	for _, c := range b.ByModel() {
+		line := fmt.Sprintf("  %-28s %s", truncate(modelName(c.Model), 28),
+			formatUsageCompact(c.Usage.TotalInput(), c.Usage.OutputTokens, c.Cost))
+		if !c.Priced {
+			line += urgentStyle.Render(" no price")
+		}
+		lines = append(lines, line)
+	}
+	if len(b.Unpriced) > 0 {
+		names := make([]string, len(b.Unpriced))
+		for i, model := range b.Unpriced {
+			names[i] = modelName(model)
+		}
+		lines = append(lines, urgentStyle.Render("  ⚠ No price for "+strings.Join(names, ", ")+"; counted as $0"))
+		lines = append(lines, mutedStyle.Render("    add them under pricing: in config.yaml or pricing.yaml"))
+	}
+	return lines
+}
+
+// cacheProjectLines lists the projects wasting the most cache writes
+func cacheProjectLines(projects []store.UsageTotal) []string {
+	var lines []string
+	for _, p := range projects {
+		if p.CacheUnreadTokens == 0 || len(lines) == 5 {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %-28s %s", truncate(p.Key, 28), formatCacheUse(p)))
+	}
+	return lines
+}
+
+// formatCacheUse describes how well usage hit the prompt cache, what that
+// saved and how much written to it was never read
+func formatCacheUse(t store.UsageTotal) string {
+	saved := fmt.Sprintf("saved $%.2f", t.CacheSavings())
+	if t.CacheSavings() < 0 {
+		saved = fmt.Sprintf("lost $%.2f", -t.CacheSavings())
+	}
+	return fmt.Sprintf("hit %.0f%%  %s  unread writes %s", t.CacheHitRatio()*100, saved,
+		formatTokensLarge(t.CacheUnreadTokens))
+}
+
+func modelName(model string) string {
+	if model == "" {
+		return "unknown"
+	}
+	return model
+}
+
 func (m *Model) viewInputOverlay() string {
 	inputBox := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
@@ -808,7 +1163,7 @@ func formatScore(n int) string {
 	if n < 1000 {
 		return fmt.Sprintf("%d", n)
 	}
-	return fmt.Sprintf("%d,%03d", n/1000, n%1000)
+	return fmt.Sprintf("%s,%03d", formatScore(n/1000), n%1000)
 }
 
 func formatDuration(d time.Duration) string {
@@ -844,6 +1199,26 @@ func formatUsageCompact(inputTokens, outputTokens int64, cost float64) string {
 	return fmt.Sprintf("↓%s ↑%s $%.2f", inStr, outStr, cost)
 }
 
+// levelBarCells is the width of the level progress bar in the header
+const levelBarCells = 5
+
+// levelBar draws the progress towards the next level in cells characters
+func levelBar(l game.Level, cells int) string {
+	filled := int(l.Progress() * float64(cells))
+	filled = max(0, min(filled, cells))
+	return strings.Repeat("▰", filled) + strings.Repeat("▱", cells-filled)
+}
+
+// contextBarCells is the width of the context fill bar in the session list
+const contextBarCells = 5
+
+// contextBar draws how full a context window is in cells characters
+func contextBar(c usage.ContextWindow, cells int) string {
+	filled := int(c.Percent()/100*float64(cells) + 0.5)
+	filled = max(0, min(filled, cells))
+	return strings.Repeat("▰", filled) + strings.Repeat("▱", cells-filled)
+}
+
 func formatTokensLarge(n int64) string {
 	if n < 1000 {
 		return fmt.Sprintf("%d", n)
@@ -0,0 +1,61 @@
+package usage
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/valentindosimont/ccmanager/internal/store"
+)
+
+// CacheReport is how well the prompt cache was used overall, per project and
+// per tmux session
+type CacheReport struct {
+	Total    store.UsageTotal
+	Projects []store.UsageTotal // keyed by directory name, most unread cache writes first
+	Sessions []store.UsageTotal // keyed by tmux session name
+}
+
+// Session returns the cache use of a tmux session, or nil if it has none
+func (c *CacheReport) Session(name string) *store.UsageTotal {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Sessions {
+		if c.Sessions[i].Key == name {
+			return &c.Sessions[i]
+		}
+	}
+	return nil
+}
+
+// cacheReport sums the ledger's cache use. The caller has backfilled it.
+func (i *Ingester) cacheReport() (*CacheReport, error) {
+	projects, err := i.store.CostByProject(store.UsageFilter{})
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := i.store.CostBySession(store.UsageFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CacheReport{Total: store.UsageTotal{Key: "total"}, Projects: projects, Sessions: sessions}
+	for j := range projects {
+		p := &projects[j]
+		p.Key = filepath.Base(p.Key)
+		t := &report.Total
+		t.Messages += p.Messages
+		t.InputTokens += p.InputTokens
+		t.OutputTokens += p.OutputTokens
+		t.CacheCreationInputTokens += p.CacheCreationInputTokens
+		t.CacheCreation1hInputTokens += p.CacheCreation1hInputTokens
+		t.CacheReadInputTokens += p.CacheReadInputTokens
+		t.Cost += p.Cost
+		t.UncachedCost += p.UncachedCost
+		t.CacheUnreadTokens += p.CacheUnreadTokens
+	}
+	sort.SliceStable(projects, func(a, b int) bool {
+		return projects[a].CacheUnreadTokens > projects[b].CacheUnreadTokens
+	})
+	return report, nil
+}
@@ -0,0 +1,84 @@
+package usage
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/store"
+)
+
+func TestCacheReport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := filepath.Join(home, ".claude", "projects", "-src-api")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	const model = "claude-sonnet-4-20250514"
+	start := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	response := func(minute int, input, write, read int64) string {
+		return fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":%q,"usage":{"input_tokens":%d,"cache_creation_input_tokens":%d,"cache_read_input_tokens":%d,"output_tokens":100}}}`+"\n",
+			start.Add(time.Duration(minute)*time.Minute).Format(time.RFC3339), model, input, write, read)
+	}
+	appendFile(t, filepath.Join(project, "s1.jsonl"),
+		response(0, 100, 10_000, 0)+ // read back next
+			response(1, 100, 2_000, 10_000)+ // never read: the next prefix misses it
+			response(2, 100, 0, 5_000)+
+			response(3, 100, 1_000, 5_000)) // expired without being read
+
+	ing := newTestIngester(t)
+	if err := ing.Backfill(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ing.store.LinkUsageSession("s1", "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	global, err := ing.GlobalUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := global.Cache
+	if c == nil {
+		t.Fatal("Cache = nil with the database")
+	}
+
+	total := c.Total
+	if total.CacheUnreadTokens != 3_000 {
+		t.Errorf("CacheUnreadTokens = %d, want 3000", total.CacheUnreadTokens)
+	}
+	if got, want := total.CacheHitRatio(), 20_000.0/33_400; math.Abs(got-want) > 1e-9 {
+		t.Errorf("CacheHitRatio() = %v, want %v", got, want)
+	}
+	uncached := DefaultPrices().UncachedCost(model, start, TokenUsage{InputTokens: 33_400, OutputTokens: 400})
+	if math.Abs(total.UncachedCost-uncached) > 1e-9 || total.CacheSavings() <= 0 {
+		t.Errorf("UncachedCost = %v, want %v with savings, got %v", total.UncachedCost, uncached, total.CacheSavings())
+	}
+	if len(c.Projects) != 1 || c.Projects[0].Key != "-src-api" {
+		t.Errorf("Projects = %+v, want -src-api", c.Projects)
+	}
+	if s := c.Session("api-1"); s == nil || s.CacheUnreadTokens != 3_000 {
+		t.Errorf("Session(api-1) = %+v, want 3000 unread", s)
+	}
+
+	// Only responses in the range are read, so its last one's writes count as
+	// expired ones
+	totals, err := ing.store.CostByProject(store.UsageFilter{Until: start.Add(30 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 1 || totals[0].Messages != 1 || totals[0].CacheUnreadTokens != 10_000 {
+		t.Errorf("totals until 10:00:30 = %+v, want 1 message with 10000 unread", totals)
+	}
+	totals, err = ing.store.CostByProject(store.UsageFilter{Since: start.Add(30 * time.Second), Until: start.Add(150 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 1 || totals[0].Messages != 2 || totals[0].CacheUnreadTokens != 2_000 {
+		t.Errorf("totals from 10:00:30 until 10:02:30 = %+v, want 2 messages with 2000 unread", totals)
+	}
+}
@@ -0,0 +1,68 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Context window sizes. Sonnet can run with a 1M-token window; transcripts
+// don't record which, so a context past the standard size implies it.
+const (
+	contextLimit     = 200_000
+	longContextLimit = 1_000_000
+)
+
+// ContextWarnPercent is how full a context window gets before Claude Code's
+// auto-compaction is close
+const ContextWarnPercent = 80
+
+// ContextWindow is how full a session's context window is, as of the latest
+// main-conversation response
+type ContextWindow struct {
+	Used  int64 // input tokens, cached or not, sent with the latest response
+	Limit int64
+	Model string
+}
+
+// newContextWindow sizes the window of a response that used u
+func newContextWindow(model string, u TokenUsage) ContextWindow {
+	used := u.TotalInput()
+	limit := int64(contextLimit)
+	if used > contextLimit {
+		limit = longContextLimit
+	}
+	return ContextWindow{Used: used, Limit: limit, Model: model}
+}
+
+// Percent returns how much of the window is used, or 0 if unknown
+func (c ContextWindow) Percent() float64 {
+	if c.Limit <= 0 {
+		return 0
+	}
+	return float64(c.Used) / float64(c.Limit) * 100
+}
+
+// NearCompaction reports whether auto-compaction is imminent
+func (c ContextWindow) NearCompaction() bool {
+	return c.Percent() >= ContextWarnPercent
+}
+
+// Compaction is a point where Claude Code summarized the conversation to
+// free its context window
+type Compaction struct {
+	Time      time.Time
+	Trigger   string // "auto" or "manual"
+	PreTokens int64  // context size before compacting
+}
+
+// String describes the compaction for the activity log
+func (c Compaction) String() string {
+	s := "Context compacted"
+	switch {
+	case c.Trigger != "" && c.PreTokens > 0:
+		s += fmt.Sprintf(" (%s, from %dk tokens)", c.Trigger, c.PreTokens/1000)
+	case c.Trigger != "":
+		s += " (" + c.Trigger + ")"
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionContextWindow(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := filepath.Join(home, ".claude", "projects", "-home-dev-app")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(project, "s1.jsonl")
+
+	at := time.Date(2026, 6, 1, 10, 0, 0, 0, time.UTC)
+	response := func(id string, sidechain bool, input, cacheRead int64) string {
+		at = at.Add(time.Minute)
+		return fmt.Sprintf(`{"type":"assistant","timestamp":%q,"isSidechain":%v,"message":{"id":%q,"model":"claude-sonnet-4-20250514","usage":{"input_tokens":%d,"cache_read_input_tokens":%d,"output_tokens":500}}}`+"\n",
+			at.Format(time.RFC3339), sidechain, id, input, cacheRead)
+	}
+	compact := func(trigger string, preTokens int64) string {
+		at = at.Add(time.Minute)
+		return fmt.Sprintf(`{"type":"system","subtype":"compact_boundary","content":"Conversation compacted","timestamp":%q,"compactMetadata":{"trigger":%q,"preTokens":%d}}`+"\n",
+			at.Format(time.RFC3339), trigger, preTokens)
+	}
+
+	sources := map[string]*Ingester{
+		"parse":  NewIngester(nil),
+		"ingest": newTestIngester(t),
+	}
+	for name, ing := range sources {
+		t.Run(name, func(t *testing.T) {
+			_ = os.Remove(path)
+			appendFile(t, path, response(name+"1", false, 2_000, 100_000)+response(name+"2", false, 1_000, 164_000)+
+				response(name+"3", true, 90_000, 0)) // a subagent's context is its own
+
+			got, err := ing.SessionUsage("/home/dev/app", "s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Context.Used != 165_000 || got.Context.Limit != 200_000 || !got.Context.NearCompaction() {
+				t.Errorf("Context = %+v, want 165000 of 200000, near compaction", got.Context)
+			}
+			if got.Compaction != nil {
+				t.Errorf("Compaction = %+v before compacting", got.Compaction)
+			}
+
+			appendFile(t, path, compact("auto", 168_000)+response(name+"4", false, 500, 20_000))
+			got, err = ing.SessionUsage("/home/dev/app", "s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Context.Used != 20_500 || got.Context.NearCompaction() {
+				t.Errorf("Context = %+v after compacting, want 20500", got.Context)
+			}
+			if c := got.Compaction; c == nil || c.Trigger != "auto" || c.PreTokens != 168_000 || !c.Time.Equal(at.Add(-time.Minute)) {
+				t.Fatalf("Compaction = %+v, want the auto compaction", c)
+			}
+			if s := got.Compaction.String(); s != "Context compacted (auto, from 168k tokens)" {
+				t.Errorf("String() = %q", s)
+			}
+		})
+	}
+}
+
+func TestContextWindowLimit(t *testing.T) {
+	tests := []struct {
+		used      int64
+		wantLimit int64
+	}{
+		{150_000, 200_000},
+		{200_000, 200_000},
+		{350_000, 1_000_000}, // only fits the long-context window
+	}
+	for _, tt := range tests {
+		c := newContextWindow("claude-sonnet-4-20250514", TokenUsage{CacheReadInputTokens: tt.used})
+		if c.Limit != tt.wantLimit {
+			t.Errorf("%d tokens: Limit = %d, want %d", tt.used, c.Limit, tt.wantLimit)
+		}
+	}
+}
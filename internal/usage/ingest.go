@@ -0,0 +1,453 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/store"
+)
+
+// Ingester reads Claude transcripts into the store incrementally. Each file's
+// offset, inode and size are remembered, so only lines appended since the last
+// read are parsed and totals become queries.
+type Ingester struct {
+	mu       sync.Mutex
+	store    *store.Store
+	prices   *PriceTable
+	repriced bool // saved costs match prices
+
+	// windowRead is when WindowStatus last backfilled; transcripts not
+	// modified since are skipped
+	windowRead time.Time
+}
+
+// NewIngester creates an ingester backed by st. Without a store every call
+// falls back to parsing whole files.
+func NewIngester(st *store.Store) *Ingester {
+	return &Ingester{store: st, prices: DefaultPrices()}
+}
+
+// SetPrices sets the price table messages are costed with. Costs saved
+// under a different table are recalculated on the next read.
+func (i *Ingester) SetPrices(prices *PriceTable) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.prices = prices
+	i.repriced = false
+}
+
+// reprice recalculates saved costs if they were made with other prices
+func (i *Ingester) reprice() error {
+	if i.repriced {
+		return nil
+	}
+	version, err := i.store.UsagePricingVersion()
+	if err != nil {
+		return err
+	}
+	if version != i.prices.Version() {
+		err := i.store.RepriceUsage(i.prices.Version(), func(m store.UsageEvent) (float64, float64, bool) {
+			tokens := messageTokens(m)
+			cost, priced := i.prices.Cost(m.Model, m.Timestamp, tokens)
+			return cost, i.prices.UncachedCost(m.Model, m.Timestamp, tokens), priced
+		})
+		if err != nil {
+			return err
+		}
+	}
+	i.repriced = true
+	return nil
+}
+
+// IngestFile reads the complete lines appended to a transcript since the last
+// call. A file that shrank, was replaced, or no longer matches the saved
+// offset is read again from the start.
+func (i *Ingester) IngestFile(path string) error {
+	if i == nil || i.store == nil {
+		return nil
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.reprice(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	prev, err := i.store.GetUsageFile(path)
+	if err != nil {
+		return err
+	}
+
+	next := store.UsageFile{
+		Path:  path,
+		Inode: inode(info),
+		Size:  info.Size(),
+	}
+	replace := false
+	if prev == nil {
+		next.ProjectDir, next.SessionID = transcriptOwner(path)
+	} else {
+		if prev.Inode == next.Inode && prev.Size == next.Size {
+			return nil
+		}
+		next.ProjectDir, next.SessionID = prev.ProjectDir, prev.SessionID
+		next.Offset = prev.Offset
+		if prev.Inode != next.Inode || next.Size < prev.Offset || !endsLine(file, prev.Offset) {
+			next.Offset = 0
+			replace = true
+		}
+	}
+
+	// Stop at the size seen above so a line written meanwhile waits for the
+	// next call
+	section := io.NewSectionReader(file, next.Offset, next.Size-next.Offset)
+	msgs, compactions, offset, err := readMessages(section, next.Offset, i.prices, IsSubagentFile(path))
+	if err != nil {
+		return err
+	}
+	next.Offset = offset
+
+	err = i.store.IngestUsage(prev, next, replace, msgs, compactions)
+	if errors.Is(err, store.ErrUsageConflict) {
+		// Another process read the file first; its rows are as good as ours
+		return nil
+	}
+	return err
+}
+
+// FileUsage ingests a transcript and returns its totals
+func (i *Ingester) FileUsage(path string) (*SessionUsage, error) {
+	if i == nil {
+		return ParseSessionFile(path)
+	}
+	if i.store == nil {
+		return parseSessionFile(path, i.prices)
+	}
+
+	if err := i.IngestFile(path); err != nil {
+		return nil, err
+	}
+	models, err := i.store.FileUsageByModel(path)
+	if err != nil {
+		return nil, err
+	}
+	return sessionUsageFromModels(strings.TrimSuffix(filepath.Base(path), ".jsonl"), filepath.Dir(path), models), nil
+}
+
+// SessionUsage returns usage for a Claude session ID, including the Task
+// subagents it spawned, reading only what was appended since the last call
+func (i *Ingester) SessionUsage(workingDir, sessionID string) (*SessionUsage, error) {
+	if sessionID == "" {
+		return nil, nil
+	}
+
+	projectDir, err := FindProjectDir(workingDir)
+	if err != nil {
+		return nil, err
+	}
+	return i.projectSessionUsage(projectDir, sessionID)
+}
+
+// projectSessionUsage returns usage for a session in a Claude project
+// directory and its subagents, or nil if it has no transcript
+func (i *Ingester) projectSessionUsage(projectDir, sessionID string) (*SessionUsage, error) {
+	sessionFile := filepath.Join(projectDir, sessionID+".jsonl")
+	if _, err := os.Stat(sessionFile); err != nil {
+		return nil, nil // File doesn't exist, return nil without error
+	}
+	subagents, err := findSubagentFiles(projectDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if i == nil || i.store == nil {
+		usage, err := i.FileUsage(sessionFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range subagents {
+			if _, owner := transcriptOwner(path); owner != sessionID {
+				continue
+			}
+			sub, err := i.FileUsage(path)
+			if err != nil {
+				continue
+			}
+			usage.merge(sub)
+		}
+		return usage, nil
+	}
+
+	if err := i.IngestFile(sessionFile); err != nil {
+		return nil, err
+	}
+	for _, path := range subagents {
+		if err := i.IngestFile(path); err != nil {
+			return nil, fmt.Errorf("ingest %s: %w", path, err)
+		}
+	}
+	models, err := i.store.SessionUsageByModel(projectDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	usage := sessionUsageFromModels(sessionID, projectDir, models)
+
+	latest, err := i.store.LatestSessionUsage(projectDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		usage.Context = newContextWindow(latest.Model, messageTokens(*latest))
+	}
+	compaction, err := i.store.LatestCompaction(projectDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if compaction != nil {
+		usage.Compaction = &Compaction{Time: compaction.Timestamp, Trigger: compaction.Trigger, PreTokens: compaction.PreTokens}
+	}
+	return usage, nil
+}
+
+// sessionUsageFromModels totals per-model usage read from the store
+func sessionUsageFromModels(sessionID, projectDir string, models []store.ModelUsage) *SessionUsage {
+	usage := &SessionUsage{
+		SessionID:   sessionID,
+		ProjectPath: projectDir,
+		LastUpdated: time.Now(),
+	}
+	for _, m := range models {
+		if m.Sidechain {
+			usage.AddSubagentUsage(m.Model, modelTokens(m), m.Cost, m.Unpriced == 0)
+			continue
+		}
+		usage.AddModelUsage(m.Model, modelTokens(m), m.Cost, m.Unpriced == 0)
+		if m.Model != "" && m.Model != syntheticModel {
+			usage.Model = m.Model
+		}
+	}
+	return usage
+}
+
+// GlobalUsage ingests every Claude project and returns total historical
+// usage, including transcripts deleted since they were read
+func (i *Ingester) GlobalUsage() (*GlobalUsage, error) {
+	if i == nil || i.store == nil {
+		return GetGlobalUsage()
+	}
+
+	if err := i.Backfill(); err != nil {
+		return nil, err
+	}
+
+	models, err := i.store.UsageByModel()
+	if err != nil {
+		return nil, err
+	}
+	global := &GlobalUsage{}
+	for _, m := range models {
+		global.AddModelUsage(m.Model, modelTokens(m), m.Cost, m.Unpriced == 0)
+	}
+	global.SessionCount, global.ProjectCount, err = i.store.CountUsageFiles()
+	if err != nil {
+		return nil, err
+	}
+	if global.Cache, err = i.cacheReport(); err != nil {
+		return nil, err
+	}
+	return global, nil
+}
+
+// WindowStatus ingests every Claude project and works out how much of a
+// subscription plan's block and weekly limits is used at now
+func (i *Ingester) WindowStatus(limits PlanLimits, now time.Time) (*WindowStatus, error) {
+	since := now.Add(-windowHistory)
+	if i == nil || i.store == nil {
+		prices := DefaultPrices()
+		if i != nil {
+			prices = i.prices
+		}
+		entries, err := parseEntries(since, prices)
+		if err != nil {
+			return nil, err
+		}
+		return NewWindowStatus(entries, limits, now), nil
+	}
+
+	// A minute's slack covers a write landing as the last read started
+	from := since
+	i.mu.Lock()
+	if last := i.windowRead.Add(-time.Minute); last.After(from) {
+		from = last
+	}
+	i.mu.Unlock()
+	started := time.Now()
+	if err := i.BackfillSince(from); err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	i.windowRead = started
+	i.mu.Unlock()
+	msgs, err := i.store.UsageSince(since)
+	if err != nil {
+		return nil, err
+	}
+	return NewWindowStatus(entriesFromMessages(msgs), limits, now), nil
+}
+
+// Backfill reads what was appended to every transcript of every project
+// into the usage ledger, including sessions that were never monitored.
+// Transcripts that couldn't be read are skipped and reported together.
+func (i *Ingester) Backfill() error {
+	return i.BackfillSince(time.Time{})
+}
+
+// BackfillSince is Backfill for the transcripts modified since t. Older ones
+// hold no usage from t on, so reports from t don't need them.
+func (i *Ingester) BackfillSince(t time.Time) error {
+	if i == nil || i.store == nil {
+		return nil
+	}
+	projects, err := ListAllProjects()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, projectPath := range projects {
+		files, err := FindSessionFiles(projectPath)
+		if err != nil {
+			continue
+		}
+		subagents, _ := FindSubagentFiles(projectPath)
+		for _, file := range append(files, subagents...) {
+			if info, err := os.Stat(file); err == nil && info.ModTime().Before(t) {
+				continue
+			}
+			// A transcript deleted since it was listed has nothing to add
+			if err := i.IngestFile(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("ingest %s: %w", file, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readMessages parses the assistant messages and main-conversation
+// compactions in r, which starts at byte start of the file. It returns the
+// offset after the last complete line; a trailing partial line is left for
+// the next read. Every message of a subagent's transcript is a sidechain
+// message.
+func readMessages(r io.Reader, start int64, prices *PriceTable, subagent bool) ([]store.UsageEvent, []store.UsageCompaction, int64, error) {
+	reader := bufio.NewReaderSize(r, 1024*1024)
+	offset := start
+
+	var msgs []store.UsageEvent
+	var compactions []store.UsageCompaction
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return msgs, compactions, offset, nil
+		}
+		if err != nil {
+			return nil, nil, start, err
+		}
+		lineOffset := offset
+		offset += int64(len(line))
+
+		var msg jsonlMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		if c, ok := msg.compaction(); ok && !subagent {
+			compactions = append(compactions, store.UsageCompaction{
+				LineOffset: lineOffset,
+				Timestamp:  c.Time,
+				Trigger:    c.Trigger,
+				PreTokens:  c.PreTokens,
+			})
+		}
+		if msg.Type != "assistant" {
+			continue
+		}
+		tokens := msg.tokenUsage()
+		timestamp := msg.time()
+		cost, priced := prices.Cost(msg.Message.Model, timestamp, tokens)
+		msgs = append(msgs, store.UsageEvent{
+			MessageID:                  msg.Message.ID,
+			RequestID:                  msg.RequestID,
+			LineOffset:                 lineOffset,
+			Timestamp:                  timestamp,
+			Model:                      msg.Message.Model,
+			InputTokens:                tokens.InputTokens,
+			OutputTokens:               tokens.OutputTokens,
+			CacheCreationInputTokens:   tokens.CacheCreationInputTokens,
+			CacheCreation1hInputTokens: tokens.CacheCreation1hInputTokens,
+			CacheReadInputTokens:       tokens.CacheReadInputTokens,
+			Cost:                       cost,
+			UncachedCost:               prices.UncachedCost(msg.Message.Model, timestamp, tokens),
+			Priced:                     priced,
+			Sidechain:                  subagent || msg.IsSidechain,
+		})
+	}
+}
+
+// endsLine reports whether offset falls just after a newline, as the end of
+// a previous read should unless the file was rewritten
+func endsLine(file *os.File, offset int64) bool {
+	if offset == 0 {
+		return true
+	}
+	b := make([]byte, 1)
+	if _, err := file.ReadAt(b, offset-1); err != nil {
+		return false
+	}
+	return b[0] == '\n'
+}
+
+func inode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
+
+func modelTokens(m store.ModelUsage) TokenUsage {
+	return TokenUsage{
+		InputTokens:                m.InputTokens,
+		OutputTokens:               m.OutputTokens,
+		CacheCreationInputTokens:   m.CacheCreationInputTokens,
+		CacheCreation1hInputTokens: m.CacheCreation1hInputTokens,
+		CacheReadInputTokens:       m.CacheReadInputTokens,
+	}
+}
+
+func messageTokens(m store.UsageEvent) TokenUsage {
+	return TokenUsage{
+		InputTokens:                m.InputTokens,
+		OutputTokens:               m.OutputTokens,
+		CacheCreationInputTokens:   m.CacheCreationInputTokens,
+		CacheCreation1hInputTokens: m.CacheCreation1hInputTokens,
+		CacheReadInputTokens:       m.CacheReadInputTokens,
+	}
+}
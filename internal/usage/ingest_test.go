@@ -0,0 +1,468 @@
+package usage
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/store"
+)
+
+func assistantLine(input, output int64) string {
+	return fmt.Sprintf(`{"type":"assistant","timestamp":"2025-06-01T10:00:00.000Z","message":{"model":"claude-sonnet-4-20250514","usage":{"input_tokens":%d,"output_tokens":%d}}}`+"\n", input, output)
+}
+
+func newTestIngester(t *testing.T) *Ingester {
+	t.Helper()
+	st, err := store.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return NewIngester(st)
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIngesterFileUsage(t *testing.T) {
+	tests := []struct {
+		name       string
+		change     func(t *testing.T, ing *Ingester, path string)
+		wantInput  int64
+		wantOutput int64
+	}{
+		{
+			name: "append",
+			change: func(t *testing.T, ing *Ingester, path string) {
+				appendFile(t, path, assistantLine(200, 20))
+			},
+			wantInput:  300,
+			wantOutput: 30,
+		},
+		{
+			name: "partial line",
+			change: func(t *testing.T, ing *Ingester, path string) {
+				line := assistantLine(200, 20)
+				appendFile(t, path, line[:20])
+				if _, err := ing.FileUsage(path); err != nil {
+					t.Fatalf("FileUsage: %v", err)
+				}
+				appendFile(t, path, line[20:])
+			},
+			wantInput:  300,
+			wantOutput: 30,
+		},
+		{
+			name: "truncate",
+			change: func(t *testing.T, ing *Ingester, path string) {
+				if err := os.WriteFile(path, []byte(assistantLine(5, 1)), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantInput:  5,
+			wantOutput: 1,
+		},
+		{
+			name: "rotate",
+			change: func(t *testing.T, ing *Ingester, path string) {
+				rotated := path + ".new"
+				if err := os.WriteFile(rotated, []byte(assistantLine(7, 2)), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(rotated, path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantInput:  7,
+			wantOutput: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := newTestIngester(t)
+			path := filepath.Join(t.TempDir(), "session-1.jsonl")
+
+			appendFile(t, path, `{"type":"user","message":{"content":"hi"}}`+"\n"+assistantLine(100, 10))
+			if _, err := ing.FileUsage(path); err != nil {
+				t.Fatalf("FileUsage: %v", err)
+			}
+			tt.change(t, ing, path)
+
+			got, err := ing.FileUsage(path)
+			if err != nil {
+				t.Fatalf("FileUsage: %v", err)
+			}
+			if got.TotalUsage.InputTokens != tt.wantInput || got.TotalUsage.OutputTokens != tt.wantOutput {
+				t.Errorf("usage = %d in / %d out, want %d / %d",
+					got.TotalUsage.InputTokens, got.TotalUsage.OutputTokens, tt.wantInput, tt.wantOutput)
+			}
+			if got.Model != "claude-sonnet-4-20250514" {
+				t.Errorf("Model = %q", got.Model)
+			}
+		})
+	}
+}
+
+func TestIngesterReadsOnlyAppendedLines(t *testing.T) {
+	ing := newTestIngester(t)
+	path := filepath.Join(t.TempDir(), "session-1.jsonl")
+	appendFile(t, path, assistantLine(100, 10))
+	if err := ing.IngestFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupting already-read bytes in place must not change the totals,
+	// since they are never read again
+	content, _ := os.ReadFile(path)
+	content[0] = 'x'
+	content = append(content, assistantLine(1, 1)...)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ing.FileUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TotalUsage.InputTokens != 101 {
+		t.Errorf("InputTokens = %d, want 101", got.TotalUsage.InputTokens)
+	}
+
+	state, err := ing.store.GetUsageFile(path)
+	if err != nil || state == nil {
+		t.Fatalf("GetUsageFile = %v, %v", state, err)
+	}
+	if state.Offset != int64(len(content)) || state.SessionID != "session-1" {
+		t.Errorf("state = %+v, want offset %d", state, len(content))
+	}
+}
+
+func TestIngesterGlobalUsage(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	projects := filepath.Join(home, ".claude", "projects")
+	for _, dir := range []string{"-repo-a", "-repo-b"} {
+		if err := os.MkdirAll(filepath.Join(projects, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendFile(t, filepath.Join(projects, "-repo-a", "one.jsonl"), assistantLine(100, 10))
+	appendFile(t, filepath.Join(projects, "-repo-a", "two.jsonl"), assistantLine(200, 20))
+	appendFile(t, filepath.Join(projects, "-repo-b", "three.jsonl"), assistantLine(300, 30))
+
+	ing := newTestIngester(t)
+	for range 2 {
+		global, err := ing.GlobalUsage()
+		if err != nil {
+			t.Fatalf("GlobalUsage: %v", err)
+		}
+		if global.TotalUsage.InputTokens != 600 || global.TotalUsage.OutputTokens != 60 {
+			t.Errorf("usage = %+v", global.TotalUsage)
+		}
+		if global.SessionCount != 3 || global.ProjectCount != 2 {
+			t.Errorf("counts = %d sessions / %d projects, want 3 / 2", global.SessionCount, global.ProjectCount)
+		}
+	}
+}
+
+func TestIngesterCountsResponsesOnce(t *testing.T) {
+	for _, tt := range corpus {
+		t.Run(tt.file, func(t *testing.T) {
+			ing := newTestIngester(t)
+			content, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Feed the transcript a line at a time, as Claude writes it
+			path := filepath.Join(t.TempDir(), tt.file)
+			for _, line := range strings.SplitAfter(string(content), "\n") {
+				appendFile(t, path, line)
+				if err := ing.IngestFile(path); err != nil {
+					t.Fatalf("IngestFile: %v", err)
+				}
+			}
+
+			got, err := ing.FileUsage(path)
+			if err != nil {
+				t.Fatalf("FileUsage: %v", err)
+			}
+			if got.TotalUsage != tt.want {
+				t.Errorf("TotalUsage = %+v, want %+v", got.TotalUsage, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngesterCountsResumedHistoryOnce(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := filepath.Join(home, ".claude", "projects", "-home-dev-app")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A resumed session starts a new transcript holding the old one's history
+	content, err := os.ReadFile(filepath.Join("testdata", "tool_use.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, filepath.Join(project, "first.jsonl"), string(content))
+	appendFile(t, filepath.Join(project, "resumed.jsonl"), string(content)+assistantLine(1, 1))
+
+	global, err := newTestIngester(t).GlobalUsage()
+	if err != nil {
+		t.Fatalf("GlobalUsage: %v", err)
+	}
+	want := corpus[1].want
+	want.Add(TokenUsage{InputTokens: 1, OutputTokens: 1})
+	if global.TotalUsage != want {
+		t.Errorf("TotalUsage = %+v, want %+v", global.TotalUsage, want)
+	}
+}
+
+func TestSessionUsageByModel(t *testing.T) {
+	path := filepath.Join("testdata", "mixed_models.jsonl")
+	wantModels := map[string]TokenUsage{
+		"claude-opus-4-1-20250805":  {InputTokens: 1000, OutputTokens: 2000},
+		"claude-3-5-haiku-20241022": {InputTokens: 5000, OutputTokens: 100},
+		"claude-sonnet-4-20250514":  {InputTokens: 2000, OutputTokens: 1000},
+	}
+	// $0.165 opus + $0.0044 haiku + $0.021 sonnet
+	const wantCost = 0.1904
+
+	sources := map[string]func() (*SessionUsage, error){
+		"parse":  func() (*SessionUsage, error) { return NewIngester(nil).FileUsage(path) },
+		"ingest": func() (*SessionUsage, error) { return newTestIngester(t).FileUsage(path) },
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			got, err := source()
+			if err != nil {
+				t.Fatalf("FileUsage: %v", err)
+			}
+			if len(got.Models) != len(wantModels) {
+				t.Errorf("Models = %v, want %v", got.Models, wantModels)
+			}
+			for model, want := range wantModels {
+				if got.Models[model] != want {
+					t.Errorf("Models[%s] = %+v, want %+v", model, got.Models[model], want)
+				}
+			}
+			if diff := got.EstimatedCost - wantCost; diff > 0.0001 || diff < -0.0001 {
+				t.Errorf("EstimatedCost = %.4f, want %.4f", got.EstimatedCost, wantCost)
+			}
+			if got.Model != "claude-sonnet-4-20250514" {
+				t.Errorf("Model = %q, want the last real model", got.Model)
+			}
+
+			costs := got.ByModel()
+			if len(costs) != 3 || costs[0].Model != "claude-opus-4-1-20250805" || costs[2].Model != "claude-3-5-haiku-20241022" {
+				t.Errorf("ByModel order = %+v", costs)
+			}
+		})
+	}
+}
+
+func TestIngesterRepricesOnNewPrices(t *testing.T) {
+	ing := newTestIngester(t)
+	path := filepath.Join(t.TempDir(), "session-1.jsonl")
+	appendFile(t, path, assistantLine(100_000, 0))
+	appendFile(t, path, `{"type":"assistant","message":{"id":"msg_1","model":"gpt-5","usage":{"input_tokens":100000}}}`+"\n")
+
+	got, err := ing.FileUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.EstimatedCost-0.3) > 1e-9 || len(got.Unpriced) != 1 || got.Unpriced[0] != "gpt-5" {
+		t.Errorf("default prices: cost = %v, unpriced = %v; want 0.3, [gpt-5]", got.EstimatedCost, got.Unpriced)
+	}
+
+	prices, err := NewPriceTable([]config.PriceConfig{
+		{Model: "gpt-5*", Input: 1.25},
+		{Model: "claude-sonnet-4*", Input: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ing.SetPrices(prices)
+
+	got, err = ing.FileUsage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.EstimatedCost-0.525) > 1e-9 || len(got.Unpriced) != 0 {
+		t.Errorf("configured prices: cost = %v, unpriced = %v; want 0.525, none", got.EstimatedCost, got.Unpriced)
+	}
+}
+
+func TestSessionUsageAttributesSubagents(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := filepath.Join(home, ".claude", "projects", "-home-dev-app")
+	if err := os.MkdirAll(filepath.Join(project, "parent", "subagents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	line := func(session, id string, sidechain bool, input int64) string {
+		return fmt.Sprintf(`{"type":"assistant","sessionId":%q,"isSidechain":%v,"message":{"id":%q,"model":"claude-sonnet-4-20250514","usage":{"input_tokens":%d}}}`+"\n",
+			session, sidechain, id, input)
+	}
+	// An inline sidechain, and subagent transcripts in both layouts Claude
+	// Code has used
+	appendFile(t, filepath.Join(project, "parent.jsonl"), line("parent", "msg_main", false, 100)+line("parent", "msg_inline", true, 50))
+	appendFile(t, filepath.Join(project, "parent", "subagents", "agent-a1.jsonl"), line("parent", "msg_nested", true, 200))
+	appendFile(t, filepath.Join(project, "agent-b2.jsonl"), line("parent", "msg_flat", true, 300))
+	appendFile(t, filepath.Join(project, "other.jsonl"), line("other", "msg_other", false, 7))
+	appendFile(t, filepath.Join(project, "agent-c3.jsonl"), line("other", "msg_other_agent", true, 1000))
+
+	sources := map[string]*Ingester{
+		"parse":  NewIngester(nil),
+		"ingest": newTestIngester(t),
+	}
+	for name, ing := range sources {
+		t.Run(name, func(t *testing.T) {
+			got, err := ing.SessionUsage("/home/dev/app", "parent")
+			if err != nil || got == nil {
+				t.Fatalf("SessionUsage = %v, %v", got, err)
+			}
+			if got.TotalUsage.InputTokens != 650 {
+				t.Errorf("InputTokens = %d, want 650", got.TotalUsage.InputTokens)
+			}
+			if got.Subagents.Usage.InputTokens != 550 {
+				t.Errorf("subagent InputTokens = %d, want 550", got.Subagents.Usage.InputTokens)
+			}
+			parent := got.Parent()
+			if parent.Usage.InputTokens != 100 || math.Abs(parent.Cost+got.Subagents.Cost-got.EstimatedCost) > 1e-9 {
+				t.Errorf("Parent() = %+v, subagents %+v, total $%v", parent, got.Subagents, got.EstimatedCost)
+			}
+
+			global, err := ing.GlobalUsage()
+			if err != nil {
+				t.Fatalf("GlobalUsage: %v", err)
+			}
+			if global.TotalUsage.InputTokens != 1657 || global.SessionCount != 2 {
+				t.Errorf("global = %d input over %d sessions, want 1657 over 2",
+					global.TotalUsage.InputTokens, global.SessionCount)
+			}
+		})
+	}
+}
+
+func TestBackfillLedgerQueries(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	projects := filepath.Join(home, ".claude", "projects")
+	for _, dir := range []string{"-repo-a", "-repo-b"} {
+		if err := os.MkdirAll(filepath.Join(projects, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	day1 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	line := func(at time.Time, model string, input int64) string {
+		return fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":%q,"usage":{"input_tokens":%d}}}`+"\n",
+			at.UTC().Format(time.RFC3339Nano), model, input)
+	}
+	appendFile(t, filepath.Join(projects, "-repo-a", "one.jsonl"),
+		line(day1, "claude-sonnet-4-20250514", 1000)+line(day2, "claude-opus-4-20250514", 2000))
+	appendFile(t, filepath.Join(projects, "-repo-b", "two.jsonl"), line(day2, "claude-sonnet-4-20250514", 4000))
+
+	ing := newTestIngester(t)
+	if err := ing.Backfill(); err != nil {
+		t.Fatalf("Backfill: %v", err)
+	}
+	if err := ing.store.LinkUsageSession("one", "api-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := func(totals []store.UsageTotal) map[string]int64 {
+		got := make(map[string]int64)
+		for _, total := range totals {
+			got[total.Key] = total.InputTokens
+		}
+		return got
+	}
+	tests := []struct {
+		name  string
+		query func(store.UsageFilter) ([]store.UsageTotal, error)
+		f     store.UsageFilter
+		want  map[string]int64
+	}{
+		{"by day", ing.store.CostByDay, store.UsageFilter{},
+			map[string]int64{"2026-06-01": 1000, "2026-06-02": 6000}},
+		{"by project", ing.store.CostByProject, store.UsageFilter{},
+			map[string]int64{filepath.Join(projects, "-repo-a"): 3000, filepath.Join(projects, "-repo-b"): 4000}},
+		{"by model since day 2", ing.store.CostByModel, store.UsageFilter{Since: day2},
+			map[string]int64{"claude-opus-4-20250514": 2000, "claude-sonnet-4-20250514": 4000}},
+		{"by session", ing.store.CostBySession, store.UsageFilter{},
+			map[string]int64{"api-1": 3000}},
+		{"one session before day 2", ing.store.CostByDay, store.UsageFilter{Until: day2, TmuxSession: "api-1"},
+			map[string]int64{"2026-06-01": 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals, err := tt.query(tt.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := inputs(totals); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("input tokens = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Opus costs more than Sonnet for fewer tokens
+	models, _ := ing.store.CostByModel(store.UsageFilter{})
+	if len(models) != 2 || models[0].Key != "claude-opus-4-20250514" || models[1].Messages != 2 {
+		t.Errorf("models = %+v, want opus first, then sonnet's 2 messages", models)
+	}
+}
+
+func TestBackfillSince(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := filepath.Join(home, ".claude", "projects", "-repo")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(project, "old.jsonl")
+	recent := filepath.Join(project, "recent.jsonl")
+	appendFile(t, old, assistantLine(100, 10))
+	appendFile(t, recent, assistantLine(200, 20))
+	cutoff := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(old, cutoff.Add(-time.Hour), cutoff.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	ing := newTestIngester(t)
+	if err := ing.BackfillSince(cutoff); err != nil {
+		t.Fatalf("BackfillSince: %v", err)
+	}
+	for path, want := range map[string]bool{old: false, recent: true} {
+		f, err := ing.store.GetUsageFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (f != nil) != want {
+			t.Errorf("%s ingested = %v, want %v", filepath.Base(path), f != nil, want)
+		}
+	}
+}
@@ -11,32 +11,165 @@ import (
 
 // jsonlMessage represents a message in the JSONL file
 type jsonlMessage struct {
-	Type    string `json:"type"`
+	Type            string `json:"type"`
+	Subtype         string `json:"subtype"`
+	Timestamp       string `json:"timestamp"`
+	RequestID       string `json:"requestId"`
+	SessionID       string `json:"sessionId"`
+	Cwd             string `json:"cwd"`
+	IsSidechain     bool   `json:"isSidechain"`
+	CompactMetadata struct {
+		Trigger   string `json:"trigger"`
+		PreTokens int64  `json:"preTokens"`
+	} `json:"compactMetadata"`
 	Message struct {
+		ID    string `json:"id"`
 		Model string `json:"model"`
 		Usage struct {
 			InputTokens              int64 `json:"input_tokens"`
 			OutputTokens             int64 `json:"output_tokens"`
 			CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
 			CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+			CacheCreation            struct {
+				Ephemeral1hInputTokens int64 `json:"ephemeral_1h_input_tokens"`
+			} `json:"cache_creation"`
 		} `json:"usage"`
 	} `json:"message"`
 }
 
+// syntheticModel is the model Claude Code records for messages it writes
+// itself, such as interruptions; they cost nothing
+const syntheticModel = "<synthetic>"
+
+// tokenUsage returns the message's usage as a TokenUsage
+func (m *jsonlMessage) tokenUsage() TokenUsage {
+	return TokenUsage{
+		InputTokens:                m.Message.Usage.InputTokens,
+		OutputTokens:               m.Message.Usage.OutputTokens,
+		CacheCreationInputTokens:   m.Message.Usage.CacheCreationInputTokens,
+		CacheCreation1hInputTokens: m.Message.Usage.CacheCreation.Ephemeral1hInputTokens,
+		CacheReadInputTokens:       m.Message.Usage.CacheReadInputTokens,
+	}
+}
+
+// time returns when the message was written, or the zero time
+func (m *jsonlMessage) time() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, m.Timestamp)
+	return t
+}
+
+// compaction returns the compaction a main-conversation line marks, if any
+func (m *jsonlMessage) compaction() (Compaction, bool) {
+	if m.Type != "system" || m.Subtype != "compact_boundary" || m.IsSidechain {
+		return Compaction{}, false
+	}
+	return Compaction{Time: m.time(), Trigger: m.CompactMetadata.Trigger, PreTokens: m.CompactMetadata.PreTokens}, true
+}
+
+// responseKey identifies the API response a line belongs to. Claude Code
+// writes one line per content block of a response, each repeating its usage;
+// lines without a message ID are counted individually.
+func (m *jsonlMessage) responseKey() string {
+	if m.Message.ID == "" {
+		return ""
+	}
+	return m.Message.ID + ":" + m.RequestID
+}
+
+// responseUsage sums usage counting each API response once. Repeated lines
+// may carry growing output counts while streaming, so the largest is kept.
+type responseUsage struct {
+	seen      map[string]int
+	usage     []TokenUsage
+	models    []string
+	times     []time.Time
+	sidechain []bool
+	model     string // last model seen in the main conversation
+
+	context    ContextWindow // as of the last main-conversation response
+	compaction *Compaction   // the last compaction
+}
+
+func (r *responseUsage) add(key, model string, at time.Time, u TokenUsage, sidechain bool) {
+	if model != "" && model != syntheticModel && !sidechain {
+		r.model = model
+		if u.TotalInput() > 0 {
+			r.context = newContextWindow(model, u)
+		}
+	}
+	if key != "" {
+		if i, ok := r.seen[key]; ok {
+			r.usage[i] = maxUsage(r.usage[i], u)
+			return
+		}
+		if r.seen == nil {
+			r.seen = make(map[string]int)
+		}
+		r.seen[key] = len(r.usage)
+	}
+	r.usage = append(r.usage, u)
+	r.models = append(r.models, model)
+	r.times = append(r.times, at)
+	r.sidechain = append(r.sidechain, sidechain)
+}
+
+// addTo adds each response to s under its model, priced from prices
+func (r *responseUsage) addTo(s *SessionUsage, prices *PriceTable) {
+	for i, u := range r.usage {
+		cost, priced := prices.Cost(r.models[i], r.times[i], u)
+		if r.sidechain[i] {
+			s.AddSubagentUsage(r.models[i], u, cost, priced)
+		} else {
+			s.AddModelUsage(r.models[i], u, cost, priced)
+		}
+	}
+}
+
+func maxUsage(a, b TokenUsage) TokenUsage {
+	return TokenUsage{
+		InputTokens:                max(a.InputTokens, b.InputTokens),
+		OutputTokens:               max(a.OutputTokens, b.OutputTokens),
+		CacheCreationInputTokens:   max(a.CacheCreationInputTokens, b.CacheCreationInputTokens),
+		CacheCreation1hInputTokens: max(a.CacheCreation1hInputTokens, b.CacheCreation1hInputTokens),
+		CacheReadInputTokens:       max(a.CacheReadInputTokens, b.CacheReadInputTokens),
+	}
+}
+
 // ParseSessionFile parses a Claude JSONL session file and returns usage data
+// priced at the built-in prices
 func ParseSessionFile(path string) (*SessionUsage, error) {
-	file, err := os.Open(path)
-	if err != nil {
+	return parseSessionFile(path, DefaultPrices())
+}
+
+func parseSessionFile(path string, prices *PriceTable) (*SessionUsage, error) {
+	responses, err := parseResponses(path)
+	if responses == nil {
 		return nil, err
 	}
-	defer func() { _ = file.Close() }()
 
 	usage := &SessionUsage{
 		SessionID:   filepath.Base(strings.TrimSuffix(path, ".jsonl")),
 		ProjectPath: filepath.Dir(path),
+		Model:       responses.model,
+		Context:     responses.context,
+		Compaction:  responses.compaction,
 		LastUpdated: time.Now(),
 	}
+	responses.addTo(usage, prices)
+	return usage, err
+}
+
+// parseResponses reads the API responses in a transcript. On a read error
+// the responses read so far are returned with it.
+func parseResponses(path string) (*responseUsage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
 
+	subagent := IsSubagentFile(path)
+	responses := &responseUsage{}
 	scanner := bufio.NewScanner(file)
 	buf := make([]byte, 0, 1024*1024)
 	scanner.Buffer(buf, 10*1024*1024)
@@ -53,23 +186,14 @@ func ParseSessionFile(path string) (*SessionUsage, error) {
 		}
 
 		if msg.Type == "assistant" {
-			usage.TotalUsage.Add(TokenUsage{
-				InputTokens:              msg.Message.Usage.InputTokens,
-				OutputTokens:             msg.Message.Usage.OutputTokens,
-				CacheCreationInputTokens: msg.Message.Usage.CacheCreationInputTokens,
-				CacheReadInputTokens:     msg.Message.Usage.CacheReadInputTokens,
-			})
-			if msg.Message.Model != "" {
-				usage.Model = msg.Message.Model
-			}
+			responses.add(msg.responseKey(), msg.Message.Model, msg.time(), msg.tokenUsage(), subagent || msg.IsSidechain)
+		}
+		if c, ok := msg.compaction(); ok && !subagent {
+			responses.compaction = &c
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return usage, err
-	}
-
-	return usage, nil
+	return responses, scanner.Err()
 }
 
 // ParseSessionFileTail parses only the last N bytes of a session file for efficiency
@@ -102,6 +226,7 @@ func ParseSessionFileTail(path string, tailBytes int64) (*SessionUsage, error) {
 		LastUpdated: time.Now(),
 	}
 
+	var responses responseUsage
 	scanner := bufio.NewScanner(file)
 	buf := make([]byte, 0, 1024*1024)
 	scanner.Buffer(buf, 10*1024*1024)
@@ -124,25 +249,107 @@ func ParseSessionFileTail(path string, tailBytes int64) (*SessionUsage, error) {
 		}
 
 		if msg.Type == "assistant" {
-			usage.TotalUsage.Add(TokenUsage{
-				InputTokens:              msg.Message.Usage.InputTokens,
-				OutputTokens:             msg.Message.Usage.OutputTokens,
-				CacheCreationInputTokens: msg.Message.Usage.CacheCreationInputTokens,
-				CacheReadInputTokens:     msg.Message.Usage.CacheReadInputTokens,
-			})
-			if msg.Message.Model != "" {
-				usage.Model = msg.Message.Model
-			}
+			responses.add(msg.responseKey(), msg.Message.Model, msg.time(), msg.tokenUsage(), msg.IsSidechain)
 		}
 	}
 
+	usage.Model = responses.model
+	responses.addTo(usage, DefaultPrices())
 	return usage, nil
 }
 
 // FindSessionFiles finds all JSONL session files for a given project directory
 func FindSessionFiles(projectDir string) ([]string, error) {
 	pattern := filepath.Join(projectDir, "*.jsonl")
-	return filepath.Glob(pattern)
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subagent transcripts belong to a session rather than being one
+	files := matches[:0]
+	for _, f := range matches {
+		if !IsSubagentFile(f) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// FindSubagentFiles returns the Task subagent transcripts in a project
+// directory. Claude Code writes them either next to the sessions as
+// agent-<id>.jsonl or under <session-id>/subagents/.
+func FindSubagentFiles(projectDir string) ([]string, error) {
+	return findSubagentFiles(projectDir, "*")
+}
+
+// findSubagentFiles returns the subagent transcripts that may belong to
+// sessions matching sessionGlob. Those next to the sessions don't name their
+// session in the path, so all of them are returned.
+func findSubagentFiles(projectDir, sessionGlob string) ([]string, error) {
+	flat, err := filepath.Glob(filepath.Join(projectDir, "agent-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	nested, err := filepath.Glob(filepath.Join(projectDir, sessionGlob, "subagents", "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	return append(flat, nested...), nil
+}
+
+// IsSubagentFile reports whether path is a Task subagent's transcript
+func IsSubagentFile(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), "agent-") ||
+		filepath.Base(filepath.Dir(path)) == "subagents"
+}
+
+// transcriptOwner returns the project directory and session a transcript's
+// usage belongs to. Subagent transcripts belong to the session that spawned
+// them, which those next to the sessions only record in their lines.
+func transcriptOwner(path string) (projectDir, sessionID string) {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == "subagents" {
+		sessionDir := filepath.Dir(dir)
+		return filepath.Dir(sessionDir), filepath.Base(sessionDir)
+	}
+	sessionID = strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	if IsSubagentFile(path) {
+		if parent := firstSessionID(path); parent != "" {
+			sessionID = parent
+		}
+	}
+	return dir, sessionID
+}
+
+// firstSessionID returns the session ID recorded by the first lines of a
+// transcript that have one
+func firstSessionID(path string) string {
+	return firstField(path, func(msg jsonlMessage) string { return msg.SessionID })
+}
+
+// firstCwd returns the working directory recorded by the first lines of a
+// transcript that have one
+func firstCwd(path string) string {
+	return firstField(path, func(msg jsonlMessage) string { return msg.Cwd })
+}
+
+func firstField(path string, field func(jsonlMessage) string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for n := 0; n < 10 && scanner.Scan(); n++ {
+		var msg jsonlMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err == nil && field(msg) != "" {
+			return field(msg)
+		}
+	}
+	return ""
 }
 
 // GetClaudeProjectsDir returns the Claude projects directory path
@@ -224,37 +431,27 @@ func FindActiveSessionID(workingDir string) (string, error) {
 	return strings.TrimSuffix(filepath.Base(mostRecent), ".jsonl"), nil
 }
 
-// GetSessionByID returns usage for a specific Claude session ID
+// GetSessionByID returns usage for a specific Claude session ID, including
+// its subagents
 func GetSessionByID(workingDir, sessionID string) (*SessionUsage, error) {
-	if sessionID == "" {
-		return nil, nil
-	}
-
-	projectDir, err := FindProjectDir(workingDir)
-	if err != nil {
-		return nil, err
-	}
-
-	sessionFile := filepath.Join(projectDir, sessionID+".jsonl")
-	if _, err := os.Stat(sessionFile); err != nil {
-		return nil, nil // File doesn't exist, return nil without error
-	}
-
-	usage, err := ParseSessionFile(sessionFile)
-	if err != nil {
-		return nil, err
-	}
-
-	usage.EstimatedCost = CalculateCost(usage.TotalUsage, usage.Model)
-	return usage, nil
+	return NewIngester(nil).SessionUsage(workingDir, sessionID)
 }
 
 // GlobalUsage represents aggregated usage across all projects
 type GlobalUsage struct {
 	TotalUsage    TokenUsage
 	EstimatedCost float64
-	SessionCount  int
-	ProjectCount  int
+	ModelBreakdown
+	SessionCount int
+	ProjectCount int
+	Cache        *CacheReport // nil without the database
+}
+
+// AddModelUsage adds usage of a model and what it cost
+func (g *GlobalUsage) AddModelUsage(model string, u TokenUsage, cost float64, priced bool) {
+	g.TotalUsage.Add(u)
+	g.EstimatedCost += cost
+	g.addModel(model, u, cost, priced)
 }
 
 // GetGlobalUsage scans all Claude projects and returns total historical usage
@@ -286,15 +483,19 @@ func GetGlobalUsage() (*GlobalUsage, error) {
 			global.ProjectCount++
 		}
 
-		for _, file := range files {
+		subagents, _ := FindSubagentFiles(projectPath)
+		for _, file := range append(files, subagents...) {
 			usage, err := ParseSessionFile(file)
 			if err != nil {
 				continue
 			}
 
-			global.TotalUsage.Add(usage.TotalUsage)
-			global.EstimatedCost += CalculateCost(usage.TotalUsage, usage.Model)
-			global.SessionCount++
+			for _, c := range usage.ByModel() {
+				global.AddModelUsage(c.Model, c.Usage, c.Cost, c.Priced)
+			}
+			if !IsSubagentFile(file) {
+				global.SessionCount++
+			}
 		}
 	}
 
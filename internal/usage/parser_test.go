@@ -75,8 +75,34 @@ func TestCalculateCost(t *testing.T) {
 				OutputTokens: 100_000,
 			},
 			model:    "claude-opus-4-5-20251101",
+			wantCost: 7.5, // $5 input + $2.5 output
+		},
+		{
+			name: "opus 4.1",
+			usage: TokenUsage{
+				InputTokens:  1_000_000,
+				OutputTokens: 100_000,
+			},
+			model:    "claude-opus-4-1-20250805",
 			wantCost: 22.5, // $15 input + $7.5 output
 		},
+		{
+			name: "opus 4.5",
+			usage: TokenUsage{
+				InputTokens:  1_000_000,
+				OutputTokens: 100_000,
+			},
+			model:    "claude-opus-4-5",
+			wantCost: 7.5, // $5 input + $2.5 output
+		},
+		{
+			name: "unknown model priced as sonnet",
+			usage: TokenUsage{
+				InputTokens: 1_000_000,
+			},
+			model:    "gpt-5",
+			wantCost: 3.0,
+		},
 		{
 			name: "haiku",
 			usage: TokenUsage{
@@ -162,3 +188,48 @@ func TestTokenUsageTotalInput(t *testing.T) {
 		t.Errorf("TotalInput() = %d, want 600", total)
 	}
 }
+
+// corpus holds real-shaped transcripts in testdata and their usage with each
+// API response counted once
+var corpus = []struct {
+	file string
+	want TokenUsage
+}{
+	{
+		// One response streamed as two lines with growing output
+		file: "streaming.jsonl",
+		want: TokenUsage{InputTokens: 10, OutputTokens: 270, CacheCreationInputTokens: 1300, CacheReadInputTokens: 5000},
+	},
+	{
+		// Thinking, text and tool_use blocks each repeat the usage
+		file: "tool_use.jsonl",
+		want: TokenUsage{InputTokens: 6, OutputTokens: 220, CacheCreationInputTokens: 700, CacheReadInputTokens: 31200},
+	},
+	{
+		// A Task subagent's sidechain responses count alongside the main ones
+		file: "sidechain.jsonl",
+		want: TokenUsage{InputTokens: 27, OutputTokens: 520, CacheCreationInputTokens: 2500, CacheReadInputTokens: 4100},
+	},
+}
+
+func TestParseSessionFileCountsResponsesOnce(t *testing.T) {
+	for _, tt := range corpus {
+		t.Run(tt.file, func(t *testing.T) {
+			usage, err := ParseSessionFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("ParseSessionFile failed: %v", err)
+			}
+			if usage.TotalUsage != tt.want {
+				t.Errorf("TotalUsage = %+v, want %+v", usage.TotalUsage, tt.want)
+			}
+
+			tail, err := ParseSessionFileTail(filepath.Join("testdata", tt.file), 1<<20)
+			if err != nil {
+				t.Fatalf("ParseSessionFileTail failed: %v", err)
+			}
+			if tail.TotalUsage != tt.want {
+				t.Errorf("tail TotalUsage = %+v, want %+v", tail.TotalUsage, tt.want)
+			}
+		})
+	}
+}
@@ -1,35 +1,193 @@
 package usage
 
-import "strings"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+)
 
 // ModelPricing represents pricing for a specific model (per 1M tokens)
 type ModelPricing struct {
-	Input      float64
-	Output     float64
-	CacheWrite float64
-	CacheRead  float64
-}
-
-// Pricing table per 1M tokens
-var modelPricing = map[string]ModelPricing{
-	"opus": {
-		Input:      15.0,
-		Output:     75.0,
-		CacheWrite: 18.75,
-		CacheRead:  1.50,
-	},
-	"sonnet": {
-		Input:      3.0,
-		Output:     15.0,
-		CacheWrite: 3.75,
-		CacheRead:  0.30,
-	},
-	"haiku": {
-		Input:      0.80,
-		Output:     4.0,
-		CacheWrite: 1.0,
-		CacheRead:  0.08,
+	Input        float64
+	Output       float64
+	CacheWrite   float64 // 5-minute cache writes
+	CacheWrite1h float64 // 1-hour cache writes
+	CacheRead    float64
+}
+
+// cost prices usage at these rates
+func (p ModelPricing) cost(usage TokenUsage) float64 {
+	write5m := usage.CacheCreationInputTokens - usage.CacheCreation1hInputTokens
+	return (float64(usage.InputTokens)*p.Input +
+		float64(usage.OutputTokens)*p.Output +
+		float64(write5m)*p.CacheWrite +
+		float64(usage.CacheCreation1hInputTokens)*p.CacheWrite1h +
+		float64(usage.CacheReadInputTokens)*p.CacheRead) / 1_000_000
+}
+
+// PriceEntry prices the models whose ID matches Pattern from From on
+type PriceEntry struct {
+	Pattern string    // glob on the model ID, case-insensitive
+	From    time.Time // zero for always
+	ModelPricing
+
+	// LongContext prices a whole request once its input, cached or not,
+	// exceeds LongContextThreshold tokens
+	LongContextThreshold int64
+	LongContext          *ModelPricing
+}
+
+// defaultLongContextThreshold is where Anthropic's long-context rates start
+const defaultLongContextThreshold = 200_000
+
+// defaultPrices are Anthropic's list prices. Specific model IDs come before
+// the family names they also match.
+var defaultPrices = []PriceEntry{
+	{Pattern: "claude-opus-4-5*", ModelPricing: ModelPricing{Input: 5, Output: 25, CacheWrite: 6.25, CacheWrite1h: 10, CacheRead: 0.50}},
+	{Pattern: "claude-opus-4*", ModelPricing: ModelPricing{Input: 15, Output: 75, CacheWrite: 18.75, CacheWrite1h: 30, CacheRead: 1.50}},
+	{
+		Pattern:              "claude-sonnet-4*",
+		ModelPricing:         ModelPricing{Input: 3, Output: 15, CacheWrite: 3.75, CacheWrite1h: 6, CacheRead: 0.30},
+		LongContextThreshold: defaultLongContextThreshold,
+		LongContext:          &ModelPricing{Input: 6, Output: 22.50, CacheWrite: 7.50, CacheWrite1h: 12, CacheRead: 0.60},
 	},
+	{Pattern: "claude-haiku-4-5*", ModelPricing: ModelPricing{Input: 1, Output: 5, CacheWrite: 1.25, CacheWrite1h: 2, CacheRead: 0.10}},
+	{Pattern: "claude-3-haiku*", ModelPricing: ModelPricing{Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheWrite1h: 0.50, CacheRead: 0.03}},
+
+	// Families, for older and aliased model IDs
+	{Pattern: "*opus*", ModelPricing: ModelPricing{Input: 15, Output: 75, CacheWrite: 18.75, CacheWrite1h: 30, CacheRead: 1.50}},
+	{Pattern: "*sonnet*", ModelPricing: ModelPricing{Input: 3, Output: 15, CacheWrite: 3.75, CacheWrite1h: 6, CacheRead: 0.30}},
+	{Pattern: "*haiku*", ModelPricing: ModelPricing{Input: 0.80, Output: 4, CacheWrite: 1, CacheWrite1h: 1.60, CacheRead: 0.08}},
+}
+
+// PriceTable looks up what a model cost at a point in time. The first entry
+// whose pattern matches and that is in effect wins, so newer prices for a
+// model are listed before older ones.
+type PriceTable struct {
+	entries  []PriceEntry
+	patterns []*regexp.Regexp
+	version  string
+}
+
+var builtinPrices = newPriceTable(defaultPrices)
+
+// DefaultPrices returns the built-in price table
+func DefaultPrices() *PriceTable {
+	return builtinPrices
+}
+
+// NewPriceTable builds a table from configured prices, which are tried
+// before the built-in ones
+func NewPriceTable(cfg []config.PriceConfig) (*PriceTable, error) {
+	var entries []PriceEntry
+	for i, pc := range cfg {
+		field := fmt.Sprintf("pricing[%d]", i)
+		if pc.Model == "" {
+			return nil, fmt.Errorf("%s: model is required", field)
+		}
+
+		entry := PriceEntry{
+			Pattern:      pc.Model,
+			ModelPricing: withCacheDefaults(ModelPricing{pc.Input, pc.Output, pc.CacheWrite, pc.CacheWrite1h, pc.CacheRead}),
+		}
+		if pc.From != "" {
+			from, err := time.ParseInLocation("2006-01-02", pc.From, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("%s (%s): from must be YYYY-MM-DD, got %q", field, pc.Model, pc.From)
+			}
+			entry.From = from
+		}
+		if lc := pc.LongContext; lc != nil {
+			long := withCacheDefaults(ModelPricing{lc.Input, lc.Output, lc.CacheWrite, lc.CacheWrite1h, lc.CacheRead})
+			entry.LongContext = &long
+			entry.LongContextThreshold = lc.Threshold
+			if entry.LongContextThreshold == 0 {
+				entry.LongContextThreshold = defaultLongContextThreshold
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return newPriceTable(append(entries, defaultPrices...)), nil
+}
+
+func newPriceTable(entries []PriceEntry) *PriceTable {
+	t := &PriceTable{entries: entries}
+	for _, e := range entries {
+		t.patterns = append(t.patterns, globRegexp(e.Pattern))
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%+v", entries)))
+	t.version = hex.EncodeToString(sum[:8])
+	return t
+}
+
+// withCacheDefaults fills unset cache rates from the input rate
+func withCacheDefaults(p ModelPricing) ModelPricing {
+	if p.CacheWrite == 0 {
+		p.CacheWrite = p.Input * 1.25
+	}
+	if p.CacheWrite1h == 0 {
+		p.CacheWrite1h = p.Input * 2
+	}
+	if p.CacheRead == 0 {
+		p.CacheRead = p.Input * 0.1
+	}
+	return p
+}
+
+// Version identifies the table's contents, so costs saved under another
+// table can be recalculated
+func (t *PriceTable) Version() string {
+	return t.version
+}
+
+// Lookup returns the entry pricing model at time at; a zero time means now
+func (t *PriceTable) Lookup(model string, at time.Time) (PriceEntry, bool) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	for i, e := range t.entries {
+		if t.patterns[i].MatchString(model) && !e.From.After(at) {
+			return e, true
+		}
+	}
+	return PriceEntry{}, false
+}
+
+// Cost prices one request's usage. It reports false when the model has no
+// price, in which case the cost is 0; usage-free messages are always priced.
+func (t *PriceTable) Cost(model string, at time.Time, usage TokenUsage) (float64, bool) {
+	if usage == (TokenUsage{}) {
+		return 0, true
+	}
+	entry, ok := t.Lookup(model, at)
+	if !ok {
+		return 0, false
+	}
+	if entry.LongContext != nil && usage.TotalInput() > entry.LongContextThreshold {
+		return entry.LongContext.cost(usage), true
+	}
+	return entry.cost(usage), true
+}
+
+// UncachedCost prices usage as if there were no prompt cache, with every
+// input token at the plain input price
+func (t *PriceTable) UncachedCost(model string, at time.Time, usage TokenUsage) float64 {
+	cost, _ := t.Cost(model, at, TokenUsage{InputTokens: usage.TotalInput(), OutputTokens: usage.OutputTokens})
+	return cost
+}
+
+// globRegexp compiles a case-insensitive glob where * matches anything
+func globRegexp(glob string) *regexp.Regexp {
+	parts := strings.Split(glob, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("(?i)^" + strings.Join(parts, ".*") + "$")
 }
 
 // NormalizeModelName converts various model ID formats to our internal name
@@ -50,34 +208,18 @@ func NormalizeModelName(model string) string {
 	return "sonnet"
 }
 
-// CalculateCost calculates the estimated cost for token usage
+// CalculateCost estimates the cost of usage at the built-in prices, treating
+// models without a price as Sonnet
 func CalculateCost(usage TokenUsage, model string) float64 {
-	normalizedModel := NormalizeModelName(model)
-	pricing, ok := modelPricing[normalizedModel]
-	if !ok {
-		pricing = modelPricing["sonnet"]
-	}
-
-	// Convert tokens to millions
-	inputM := float64(usage.InputTokens) / 1_000_000
-	outputM := float64(usage.OutputTokens) / 1_000_000
-	cacheWriteM := float64(usage.CacheCreationInputTokens) / 1_000_000
-	cacheReadM := float64(usage.CacheReadInputTokens) / 1_000_000
-
-	cost := inputM*pricing.Input +
-		outputM*pricing.Output +
-		cacheWriteM*pricing.CacheWrite +
-		cacheReadM*pricing.CacheRead
-
-	return cost
+	return GetPricing(model).cost(usage)
 }
 
-// GetPricing returns the pricing for a given model
+// GetPricing returns the current built-in pricing for a model, or Sonnet's
+// for models without a price
 func GetPricing(model string) ModelPricing {
-	normalizedModel := NormalizeModelName(model)
-	pricing, ok := modelPricing[normalizedModel]
+	entry, ok := builtinPrices.Lookup(model, time.Time{})
 	if !ok {
-		return modelPricing["sonnet"]
+		entry, _ = builtinPrices.Lookup(NormalizeModelName(model), time.Time{})
 	}
-	return pricing
+	return entry.ModelPricing
 }
@@ -0,0 +1,147 @@
+package usage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+)
+
+func TestPriceTableCost(t *testing.T) {
+	prices, err := NewPriceTable([]config.PriceConfig{
+		// A price cut from March, newest first
+		{Model: "claude-opus-4-1*", From: "2026-03-01", Input: 10, Output: 50},
+		{Model: "claude-opus-4-1*", Input: 20, Output: 100},
+		{Model: "gpt-5*", Input: 1.25, Output: 10, CacheRead: 0.125},
+	})
+	if err != nil {
+		t.Fatalf("NewPriceTable: %v", err)
+	}
+
+	feb := time.Date(2026, 2, 15, 12, 0, 0, 0, time.Local)
+	apr := time.Date(2026, 4, 15, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name       string
+		model      string
+		at         time.Time
+		usage      TokenUsage
+		wantCost   float64
+		wantPriced bool
+	}{
+		{
+			name:       "configured price before its date",
+			model:      "claude-opus-4-1-20250805",
+			at:         feb,
+			usage:      TokenUsage{InputTokens: 1_000_000},
+			wantCost:   20,
+			wantPriced: true,
+		},
+		{
+			name:       "configured price after its date",
+			model:      "claude-opus-4-1-20250805",
+			at:         apr,
+			usage:      TokenUsage{InputTokens: 1_000_000},
+			wantCost:   10,
+			wantPriced: true,
+		},
+		{
+			name:       "cache rates default from input",
+			model:      "claude-opus-4-1-20250805",
+			at:         apr,
+			usage:      TokenUsage{CacheCreationInputTokens: 1_000_000, CacheReadInputTokens: 1_000_000},
+			wantCost:   12.5 + 1, // 1.25x and 0.1x of $10
+			wantPriced: true,
+		},
+		{
+			name:       "non-Claude model",
+			model:      "gpt-5-codex",
+			usage:      TokenUsage{InputTokens: 1_000_000, CacheReadInputTokens: 1_000_000},
+			wantCost:   1.375,
+			wantPriced: true,
+		},
+		{
+			name:       "1-hour cache writes cost more",
+			model:      "claude-sonnet-4-5-20250929",
+			usage:      TokenUsage{CacheCreationInputTokens: 100_000, CacheCreation1hInputTokens: 40_000},
+			wantCost:   0.06*3.75 + 0.04*6,
+			wantPriced: true,
+		},
+		{
+			name:       "long context request",
+			model:      "claude-sonnet-4-20250514",
+			usage:      TokenUsage{InputTokens: 10_000, CacheReadInputTokens: 200_000, OutputTokens: 1_000},
+			wantCost:   0.01*6 + 0.2*0.6 + 0.001*22.5,
+			wantPriced: true,
+		},
+		{
+			name:       "request at the long context threshold",
+			model:      "claude-sonnet-4-20250514",
+			usage:      TokenUsage{InputTokens: 200_000},
+			wantCost:   0.6,
+			wantPriced: true,
+		},
+		{
+			name:       "opus 4.5 before the opus 4 family",
+			model:      "claude-opus-4-5-20251101",
+			usage:      TokenUsage{InputTokens: 1_000_000},
+			wantCost:   5,
+			wantPriced: true,
+		},
+		{
+			name:       "unknown model",
+			model:      "gemini-2.5-pro",
+			usage:      TokenUsage{InputTokens: 1_000_000},
+			wantCost:   0,
+			wantPriced: false,
+		},
+		{
+			name:       "unknown model without usage",
+			model:      "<synthetic>",
+			wantCost:   0,
+			wantPriced: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, priced := prices.Cost(tt.model, tt.at, tt.usage)
+			if priced != tt.wantPriced {
+				t.Errorf("priced = %v, want %v", priced, tt.wantPriced)
+			}
+			if math.Abs(cost-tt.wantCost) > 1e-9 {
+				t.Errorf("cost = %v, want %v", cost, tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestNewPriceTableErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.PriceConfig
+	}{
+		{name: "missing model", cfg: config.PriceConfig{Input: 1}},
+		{name: "bad date", cfg: config.PriceConfig{Model: "x", From: "March 2026"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPriceTable([]config.PriceConfig{tt.cfg}); err == nil {
+				t.Error("NewPriceTable() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestPriceTableVersion(t *testing.T) {
+	a, _ := NewPriceTable(nil)
+	b, _ := NewPriceTable([]config.PriceConfig{{Model: "gpt-5*", Input: 1.25}})
+	if a.Version() != DefaultPrices().Version() {
+		t.Error("empty config should match the built-in table")
+	}
+	if a.Version() == b.Version() {
+		t.Error("configured prices should change the version")
+	}
+}
@@ -0,0 +1,101 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/valentindosimont/ccmanager/internal/store"
+)
+
+// Grouping is what a usage report is broken down by
+type Grouping string
+
+const (
+	ByDay     Grouping = "day"
+	ByProject Grouping = "project"
+	ByModel   Grouping = "model"
+	BySession Grouping = "session" // tmux session name
+)
+
+// ParseGrouping validates a grouping name
+func ParseGrouping(s string) (Grouping, error) {
+	switch g := Grouping(s); g {
+	case ByDay, ByProject, ByModel, BySession:
+		return g, nil
+	}
+	return "", fmt.Errorf("unknown grouping %q, want day, project, model or session", s)
+}
+
+// Report reads the transcripts modified since f.Since into the usage ledger,
+// then sums the usage matching f per group. Projects are keyed by their
+// working directory.
+func (i *Ingester) Report(by Grouping, f store.UsageFilter) ([]store.UsageTotal, error) {
+	if i == nil || i.store == nil {
+		return nil, errors.New("usage reports need the database")
+	}
+	if err := i.BackfillSince(f.Since); err != nil {
+		return nil, err
+	}
+
+	switch by {
+	case ByDay:
+		return i.store.CostByDay(f)
+	case ByProject:
+		totals, err := i.store.CostByProject(f)
+		if err != nil {
+			return nil, err
+		}
+		return byWorkingDir(totals), nil
+	case ByModel:
+		return i.store.CostByModel(f)
+	case BySession:
+		return i.store.CostBySession(f)
+	}
+	return nil, fmt.Errorf("unknown grouping %q", by)
+}
+
+// byWorkingDir rekeys totals per Claude project directory by the working
+// directory the project's transcripts record, merging projects that share
+// one, most expensive first
+func byWorkingDir(totals []store.UsageTotal) []store.UsageTotal {
+	var merged []store.UsageTotal
+	index := make(map[string]int)
+	for _, t := range totals {
+		t.Key = projectWorkingDir(t.Key)
+		i, ok := index[t.Key]
+		if !ok {
+			index[t.Key] = len(merged)
+			merged = append(merged, t)
+			continue
+		}
+		m := &merged[i]
+		m.Messages += t.Messages
+		m.InputTokens += t.InputTokens
+		m.OutputTokens += t.OutputTokens
+		m.CacheCreationInputTokens += t.CacheCreationInputTokens
+		m.CacheCreation1hInputTokens += t.CacheCreation1hInputTokens
+		m.CacheReadInputTokens += t.CacheReadInputTokens
+		m.Cost += t.Cost
+		m.UncachedCost += t.UncachedCost
+		m.CacheUnreadTokens += t.CacheUnreadTokens
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Cost > merged[j].Cost })
+	return merged
+}
+
+// projectWorkingDir returns the directory Claude ran in for a project
+// directory, as recorded in its transcripts. Once they are gone, the
+// directory's encoded name is decoded instead, which turns dashes in the
+// original path into slashes.
+func projectWorkingDir(projectDir string) string {
+	files, _ := FindSessionFiles(projectDir)
+	for _, file := range files {
+		if cwd := firstCwd(file); cwd != "" {
+			return cwd
+		}
+	}
+	return strings.ReplaceAll(filepath.Base(projectDir), "-", "/")
+}
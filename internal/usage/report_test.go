@@ -0,0 +1,113 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/store"
+)
+
+func TestReport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	projects := filepath.Join(home, ".claude", "projects")
+
+	line := func(cwd, day, model string, input int64) string {
+		cwdField := ""
+		if cwd != "" {
+			cwdField = fmt.Sprintf(`"cwd":%q,`, cwd)
+		}
+		return fmt.Sprintf(`{"type":"assistant",%s"timestamp":"%sT12:00:00Z","message":{"model":%q,"usage":{"input_tokens":%d}}}`+"\n",
+			cwdField, day, model, input)
+	}
+	const sonnet, haiku = "claude-sonnet-4-20250514", "claude-3-5-haiku-20241022"
+	transcripts := map[string]string{
+		// The dash in my-app is encoded like a slash
+		"-home-me-my-app/s1.jsonl": line("/home/me/my-app", "2026-06-01", sonnet, 1_000_000) +
+			line("/home/me/my-app", "2026-06-02", haiku, 1_000_000),
+		// The same directory under an older encoding
+		"-home-me-my-app-old/s2.jsonl": line("/home/me/my-app", "2026-06-02", sonnet, 1_000_000),
+		// No working directory recorded: the name is decoded
+		"-tmp-scratch/s3.jsonl": line("", "2026-06-03", sonnet, 2_000_000),
+	}
+	for name, content := range transcripts {
+		path := filepath.Join(projects, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		appendFile(t, path, content)
+	}
+
+	ing := newTestIngester(t)
+	day := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+		return d
+	}
+
+	tests := []struct {
+		name   string
+		by     Grouping
+		filter store.UsageFilter
+		want   map[string]int64 // input tokens per key
+		first  string           // most expensive, for groupings sorted by cost
+	}{
+		{
+			name: "project",
+			by:   ByProject,
+			want: map[string]int64{"/home/me/my-app": 3_000_000, "/tmp/scratch": 2_000_000},
+			// $3 + $0.80 + $3 against $6
+			first: "/home/me/my-app",
+		},
+		{
+			name:   "day",
+			by:     ByDay,
+			filter: store.UsageFilter{Since: day("2026-06-02")},
+			want:   map[string]int64{"2026-06-02": 2_000_000, "2026-06-03": 2_000_000},
+		},
+		{
+			name:  "model",
+			by:    ByModel,
+			want:  map[string]int64{sonnet: 4_000_000, haiku: 1_000_000},
+			first: sonnet,
+		},
+		{
+			name:   "until is exclusive",
+			by:     ByProject,
+			filter: store.UsageFilter{Until: day("2026-06-02")},
+			want:   map[string]int64{"/home/me/my-app": 1_000_000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals, err := ing.Report(tt.by, tt.filter)
+			if err != nil {
+				t.Fatalf("Report: %v", err)
+			}
+			got := make(map[string]int64)
+			for _, total := range totals {
+				got[total.Key] = total.InputTokens
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("input per %s = %v, want %v", tt.by, got, tt.want)
+			}
+			if tt.first != "" && (len(totals) == 0 || totals[0].Key != tt.first) {
+				t.Errorf("most expensive %s = %+v, want %s", tt.by, totals, tt.first)
+			}
+		})
+	}
+}
+
+func TestParseGrouping(t *testing.T) {
+	for _, s := range []string{"day", "project", "model", "session"} {
+		if g, err := ParseGrouping(s); err != nil || string(g) != s {
+			t.Errorf("ParseGrouping(%q) = %q, %v", s, g, err)
+		}
+	}
+	if _, err := ParseGrouping("week"); err == nil {
+		t.Error("ParseGrouping(week) succeeded")
+	}
+}
@@ -1,13 +1,19 @@
 package usage
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 // TokenUsage represents token counts from a Claude session
 type TokenUsage struct {
 	InputTokens              int64 `json:"input_tokens"`
 	OutputTokens             int64 `json:"output_tokens"`
 	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
-	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	// CacheCreation1hInputTokens is the part of CacheCreationInputTokens
+	// written to the 1-hour cache, which costs more than the 5-minute one
+	CacheCreation1hInputTokens int64 `json:"cache_creation_1h_input_tokens"`
+	CacheReadInputTokens       int64 `json:"cache_read_input_tokens"`
 }
 
 // Add adds another TokenUsage to this one
@@ -15,20 +21,142 @@ func (t *TokenUsage) Add(other TokenUsage) {
 	t.InputTokens += other.InputTokens
 	t.OutputTokens += other.OutputTokens
 	t.CacheCreationInputTokens += other.CacheCreationInputTokens
+	t.CacheCreation1hInputTokens += other.CacheCreation1hInputTokens
 	t.CacheReadInputTokens += other.CacheReadInputTokens
 }
 
+// Total returns all tokens, input, output and cached
+func (t *TokenUsage) Total() int64 {
+	return t.TotalInput() + t.OutputTokens
+}
+
 // TotalInput returns the total input tokens (regular + cache creation + cache read)
 func (t *TokenUsage) TotalInput() int64 {
 	return t.InputTokens + t.CacheCreationInputTokens + t.CacheReadInputTokens
 }
 
+// ModelBreakdown splits usage and its cost by model
+type ModelBreakdown struct {
+	Models     map[string]TokenUsage // usage per model ID, for transcripts
+	ModelCosts map[string]float64
+	Unpriced   []string // models without a price, counted as free
+}
+
+func (b *ModelBreakdown) addModel(model string, u TokenUsage, cost float64, priced bool) {
+	if !priced && !containsString(b.Unpriced, model) {
+		b.Unpriced = append(b.Unpriced, model)
+		sort.Strings(b.Unpriced)
+	}
+	if u == (TokenUsage{}) {
+		return
+	}
+	if b.Models == nil {
+		b.Models = make(map[string]TokenUsage)
+		b.ModelCosts = make(map[string]float64)
+	}
+	bucket := b.Models[model]
+	bucket.Add(u)
+	b.Models[model] = bucket
+	b.ModelCosts[model] += cost
+}
+
+// ModelCost is one model's usage and what it cost
+type ModelCost struct {
+	Model  string
+	Usage  TokenUsage
+	Cost   float64
+	Priced bool
+}
+
+// ByModel lists each model's usage and cost, most expensive first
+func (b *ModelBreakdown) ByModel() []ModelCost {
+	costs := make([]ModelCost, 0, len(b.Models))
+	for model, u := range b.Models {
+		costs = append(costs, ModelCost{
+			Model:  model,
+			Usage:  u,
+			Cost:   b.ModelCosts[model],
+			Priced: !containsString(b.Unpriced, model),
+		})
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if costs[i].Cost != costs[j].Cost {
+			return costs[i].Cost > costs[j].Cost
+		}
+		return costs[i].Model < costs[j].Model
+	})
+	return costs
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Sub subtracts another TokenUsage from this one
+func (t *TokenUsage) Sub(other TokenUsage) {
+	t.InputTokens -= other.InputTokens
+	t.OutputTokens -= other.OutputTokens
+	t.CacheCreationInputTokens -= other.CacheCreationInputTokens
+	t.CacheCreation1hInputTokens -= other.CacheCreation1hInputTokens
+	t.CacheReadInputTokens -= other.CacheReadInputTokens
+}
+
+// UsageSplit is the share of a session's usage spent by one side of it
+type UsageSplit struct {
+	Usage TokenUsage
+	Cost  float64
+}
+
 // SessionUsage represents usage data for a Claude session
 type SessionUsage struct {
 	SessionID     string
 	ProjectPath   string
-	TotalUsage    TokenUsage
+	TotalUsage    TokenUsage // including subagents
 	EstimatedCost float64
-	Model         string
-	LastUpdated   time.Time
+	Model         string // last model seen in the main conversation
+	ModelBreakdown
+	Subagents   UsageSplit    // spent by Task subagents
+	Context     ContextWindow // of the main conversation
+	Compaction  *Compaction   // the main conversation's last, if any
+	LastUpdated time.Time
+}
+
+// AddModelUsage adds usage of a model and what it cost
+func (s *SessionUsage) AddModelUsage(model string, u TokenUsage, cost float64, priced bool) {
+	s.TotalUsage.Add(u)
+	s.EstimatedCost += cost
+	s.addModel(model, u, cost, priced)
+}
+
+// AddSubagentUsage adds usage of a model by a Task subagent
+func (s *SessionUsage) AddSubagentUsage(model string, u TokenUsage, cost float64, priced bool) {
+	s.AddModelUsage(model, u, cost, priced)
+	s.Subagents.Usage.Add(u)
+	s.Subagents.Cost += cost
+}
+
+// Parent returns the share of usage spent by the main conversation
+func (s *SessionUsage) Parent() UsageSplit {
+	parent := UsageSplit{Usage: s.TotalUsage, Cost: s.EstimatedCost - s.Subagents.Cost}
+	parent.Usage.Sub(s.Subagents.Usage)
+	return parent
+}
+
+// merge adds another transcript's usage, such as a subagent's, to s
+func (s *SessionUsage) merge(other *SessionUsage) {
+	for _, c := range other.ByModel() {
+		s.addModel(c.Model, c.Usage, c.Cost, c.Priced)
+	}
+	s.TotalUsage.Add(other.TotalUsage)
+	s.EstimatedCost += other.EstimatedCost
+	s.Subagents.Usage.Add(other.Subagents.Usage)
+	s.Subagents.Cost += other.Subagents.Cost
+	if s.Model == "" {
+		s.Model = other.Model
+	}
 }
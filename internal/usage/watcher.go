@@ -3,6 +3,7 @@ package usage
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -15,17 +16,19 @@ type Watcher struct {
 	updateCh      chan string
 	pollInterval  time.Duration
 	claudeBaseDir string
+	ingester      *Ingester
 }
 
 type sessionWatch struct {
-	sessionFile string
-	lastSize    int64
-	lastMod     time.Time
-	usage       *SessionUsage
+	projectDir string
+	sessionID  string
+	lastSize   int64 // of the session and its subagent transcripts
+	lastMod    time.Time
+	usage      *SessionUsage
 }
 
-// NewWatcher creates a new usage watcher
-func NewWatcher(pollInterval time.Duration) *Watcher {
+// NewWatcher creates a new usage watcher that reads files through ingester
+func NewWatcher(pollInterval time.Duration, ingester *Ingester) *Watcher {
 	claudeDir, _ := GetClaudeProjectsDir()
 	return &Watcher{
 		sessions:      make(map[string]*sessionWatch),
@@ -33,6 +36,7 @@ func NewWatcher(pollInterval time.Duration) *Watcher {
 		updateCh:      make(chan string, 100),
 		pollInterval:  pollInterval,
 		claudeBaseDir: claudeDir,
+		ingester:      ingester,
 	}
 }
 
@@ -97,7 +101,8 @@ func (w *Watcher) WatchSession(sessionName, workingDir, sessionID string) {
 
 	if _, exists := w.sessions[sessionName]; !exists {
 		w.sessions[sessionName] = &sessionWatch{
-			sessionFile: sessionFile,
+			projectDir: projectDir,
+			sessionID:  strings.TrimSuffix(filepath.Base(sessionFile), ".jsonl"),
 		}
 	}
 }
@@ -162,29 +167,26 @@ func (w *Watcher) poll() {
 }
 
 func (w *Watcher) updateSession(name string, watch *sessionWatch) {
-	info, err := os.Stat(watch.sessionFile)
+	size, modTime, err := transcriptsState(watch.projectDir, watch.sessionID)
 	if err != nil {
 		return
 	}
 
-	// Check if file changed
-	if info.Size() == watch.lastSize && info.ModTime().Equal(watch.lastMod) {
+	// Check if any transcript changed
+	if size == watch.lastSize && modTime.Equal(watch.lastMod) {
 		return
 	}
 
-	// Parse the file
-	usage, err := ParseSessionFile(watch.sessionFile)
-	if err != nil {
+	// Read what was appended
+	usage, err := w.ingester.projectSessionUsage(watch.projectDir, watch.sessionID)
+	if err != nil || usage == nil {
 		return
 	}
 
-	// Calculate cost
-	usage.EstimatedCost = CalculateCost(usage.TotalUsage, usage.Model)
-
 	w.mu.Lock()
 	if sw, ok := w.sessions[name]; ok {
-		sw.lastSize = info.Size()
-		sw.lastMod = info.ModTime()
+		sw.lastSize = size
+		sw.lastMod = modTime
 		sw.usage = usage
 	}
 	w.mu.Unlock()
@@ -196,6 +198,27 @@ func (w *Watcher) updateSession(name string, watch *sessionWatch) {
 	}
 }
 
+// transcriptsState returns the combined size and latest modification time of
+// a session's transcript and those of the subagents it may have spawned
+func transcriptsState(projectDir, sessionID string) (int64, time.Time, error) {
+	info, err := os.Stat(filepath.Join(projectDir, sessionID+".jsonl"))
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	size, modTime := info.Size(), info.ModTime()
+
+	subagents, _ := findSubagentFiles(projectDir, sessionID)
+	for _, f := range subagents {
+		if info, err := os.Stat(f); err == nil {
+			size += info.Size()
+			if info.ModTime().After(modTime) {
+				modTime = info.ModTime()
+			}
+		}
+	}
+	return size, modTime, nil
+}
+
 // FindActiveSessionFile finds the active JSONL file for a given working directory
 func FindActiveSessionFile(workingDir string) (string, error) {
 	projectDir, err := FindProjectDir(workingDir)
@@ -240,7 +263,6 @@ func GetSessionUsage(workingDir string) (*SessionUsage, error) {
 		return nil, err
 	}
 
-	usage.EstimatedCost = CalculateCost(usage.TotalUsage, usage.Model)
 	return usage, nil
 }
 
@@ -262,7 +284,6 @@ func GetAllSessionsUsage(workingDir string) ([]*SessionUsage, error) {
 		if err != nil {
 			continue
 		}
-		usage.EstimatedCost = CalculateCost(usage.TotalUsage, usage.Model)
 
 		// Get file mod time as last updated
 		if info, err := os.Stat(f); err == nil {
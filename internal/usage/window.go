@@ -0,0 +1,243 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+	"github.com/valentindosimont/ccmanager/internal/store"
+)
+
+// BlockDuration is how long a subscription's usage block lasts. A block
+// starts at the hour of the first message sent after the previous one ended.
+const BlockDuration = 5 * time.Hour
+
+// Week is how long a weekly limit lasts
+const Week = 7 * 24 * time.Hour
+
+// windowHistory is how far back blocks are rebuilt, to find the largest
+// recent block when no block limit is set
+const windowHistory = 4 * Week
+
+// UsageEntry is one API response's usage at the time it was made
+type UsageEntry struct {
+	Time  time.Time
+	Usage TokenUsage
+	Cost  float64
+}
+
+// Block is a 5-hour usage block
+type Block struct {
+	Start        time.Time
+	End          time.Time
+	LastActivity time.Time
+	Usage        TokenUsage
+	Cost         float64
+	Messages     int
+}
+
+// Tokens returns the tokens used in the block
+func (b *Block) Tokens() int64 {
+	return b.Usage.Total()
+}
+
+// Blocks groups entries, sorted by time, into the blocks they were billed in
+func Blocks(entries []UsageEntry) []Block {
+	var blocks []Block
+	for _, e := range entries {
+		if len(blocks) == 0 || !e.Time.Before(blocks[len(blocks)-1].End) {
+			start := e.Time.Truncate(time.Hour)
+			blocks = append(blocks, Block{Start: start, End: start.Add(BlockDuration)})
+		}
+		b := &blocks[len(blocks)-1]
+		b.LastActivity = e.Time
+		b.Usage.Add(e.Usage)
+		b.Cost += e.Cost
+		b.Messages++
+	}
+	return blocks
+}
+
+// PlanLimits are a subscription plan's token limits
+type PlanLimits struct {
+	BlockTokens  int64 // 0 to compare against the largest recent block
+	WeeklyTokens int64 // 0 for none
+
+	// The week starts at WeeklyResetAt into WeeklyResetDay; without
+	// HasWeeklyReset it is the last 7 days
+	HasWeeklyReset bool
+	WeeklyResetDay time.Weekday
+	WeeklyResetAt  time.Duration
+}
+
+// NewPlanLimits validates the configured plan limits
+func NewPlanLimits(cfg config.PlanConfig) (PlanLimits, error) {
+	limits := PlanLimits{BlockTokens: cfg.BlockTokens, WeeklyTokens: cfg.WeeklyTokens}
+	if limits.BlockTokens < 0 || limits.WeeklyTokens < 0 {
+		return limits, fmt.Errorf("plan: limits can't be negative")
+	}
+	if cfg.WeeklyReset == "" {
+		return limits, nil
+	}
+
+	day, clock, ok := strings.Cut(strings.TrimSpace(cfg.WeeklyReset), " ")
+	at, err := time.Parse("15:04", strings.TrimSpace(clock))
+	weekday, known := weekdays[strings.ToLower(day)]
+	if !ok || err != nil || !known {
+		return limits, fmt.Errorf("plan: weekly_reset must look like \"mon 09:00\", got %q", cfg.WeeklyReset)
+	}
+	limits.HasWeeklyReset = true
+	limits.WeeklyResetDay = weekday
+	limits.WeeklyResetAt = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	return limits, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// weekStart returns when the week holding now started
+func (l PlanLimits) weekStart(now time.Time) time.Time {
+	if !l.HasWeeklyReset {
+		return now.Add(-Week)
+	}
+	y, m, d := now.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(l.WeeklyResetAt)
+	start = start.AddDate(0, 0, -int((now.Weekday()-l.WeeklyResetDay+7)%7))
+	if start.After(now) {
+		start = start.AddDate(0, 0, -7)
+	}
+	return start
+}
+
+// WindowStatus is how much of a plan's block and weekly limits is used
+type WindowStatus struct {
+	Block       *Block  // the block in progress, nil between blocks
+	BlockLimit  int64   // configured, or the largest recent block
+	BlockMax    bool    // BlockLimit is the largest recent block
+	BurnRate    float64 // tokens per minute since the block's first message
+	CostPerHour float64
+	Exhausts    time.Time // when the block limit runs out at this rate; zero if it lasts the block
+
+	WeekStart    time.Time
+	WeekEnd      time.Time // zero for a rolling week
+	WeekUsage    TokenUsage
+	WeekCost     float64
+	WeekLimit    int64
+	WeekExhausts time.Time // when the weekly limit runs out at this week's rate; zero if it lasts
+}
+
+// NewWindowStatus works out the block and week in progress at now from
+// entries sorted by time
+func NewWindowStatus(entries []UsageEntry, limits PlanLimits, now time.Time) *WindowStatus {
+	s := &WindowStatus{BlockLimit: limits.BlockTokens, WeekLimit: limits.WeeklyTokens}
+
+	blocks := Blocks(entries)
+	if n := len(blocks); n > 0 && now.Before(blocks[n-1].End) {
+		s.Block = &blocks[n-1]
+		blocks = blocks[:n-1]
+	}
+	if s.BlockLimit == 0 {
+		for i := range blocks {
+			s.BlockLimit = max(s.BlockLimit, blocks[i].Tokens())
+		}
+		s.BlockMax = s.BlockLimit > 0
+	}
+
+	if b := s.Block; b != nil {
+		minutes := max(now.Sub(b.Start).Minutes(), 1)
+		s.BurnRate = float64(b.Tokens()) / minutes
+		s.CostPerHour = b.Cost / minutes * 60
+		s.Exhausts = exhaustion(b.Tokens(), s.BlockLimit, s.BurnRate, now, b.End)
+	}
+
+	s.WeekStart = limits.weekStart(now)
+	if limits.HasWeeklyReset {
+		s.WeekEnd = s.WeekStart.Add(Week)
+	}
+	for _, e := range entries {
+		if !e.Time.Before(s.WeekStart) && !e.Time.After(now) {
+			s.WeekUsage.Add(e.Usage)
+			s.WeekCost += e.Cost
+		}
+	}
+	if !s.WeekEnd.IsZero() {
+		rate := float64(s.WeekUsage.Total()) / max(now.Sub(s.WeekStart).Minutes(), 1)
+		s.WeekExhausts = exhaustion(s.WeekUsage.Total(), s.WeekLimit, rate, now, s.WeekEnd)
+	}
+	return s
+}
+
+// exhaustion returns when used tokens reach limit at rate tokens per minute,
+// or zero if that isn't before end
+func exhaustion(used, limit int64, rate float64, now, end time.Time) time.Time {
+	if limit <= 0 || rate <= 0 {
+		return time.Time{}
+	}
+	if used >= limit {
+		return now
+	}
+	at := now.Add(time.Duration(float64(limit-used) / rate * float64(time.Minute)))
+	if !at.Before(end) {
+		return time.Time{}
+	}
+	return at
+}
+
+// BlockPercent returns how much of the block limit is used, or 0 without one
+func (s *WindowStatus) BlockPercent() float64 {
+	if s.Block == nil || s.BlockLimit <= 0 {
+		return 0
+	}
+	return float64(s.Block.Tokens()) / float64(s.BlockLimit) * 100
+}
+
+// WeekPercent returns how much of the weekly limit is used, or 0 without one
+func (s *WindowStatus) WeekPercent() float64 {
+	if s.WeekLimit <= 0 {
+		return 0
+	}
+	return float64(s.WeekUsage.Total()) / float64(s.WeekLimit) * 100
+}
+
+// entriesFromMessages converts stored messages, sorted by time, to entries
+func entriesFromMessages(msgs []store.UsageEvent) []UsageEntry {
+	entries := make([]UsageEntry, 0, len(msgs))
+	for _, m := range msgs {
+		entries = append(entries, UsageEntry{Time: m.Timestamp, Usage: messageTokens(m), Cost: m.Cost})
+	}
+	return entries
+}
+
+// parseEntries reads the timestamped responses of every transcript made at
+// or after since, for when there is no store
+func parseEntries(since time.Time, prices *PriceTable) ([]UsageEntry, error) {
+	projects, err := ListAllProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []UsageEntry
+	for _, projectPath := range projects {
+		files, _ := FindSessionFiles(projectPath)
+		subagents, _ := FindSubagentFiles(projectPath)
+		for _, file := range append(files, subagents...) {
+			responses, err := parseResponses(file)
+			if err != nil {
+				continue
+			}
+			for i, u := range responses.usage {
+				if responses.times[i].Before(since) {
+					continue
+				}
+				cost, _ := prices.Cost(responses.models[i], responses.times[i], u)
+				entries = append(entries, UsageEntry{Time: responses.times[i], Usage: u, Cost: cost})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
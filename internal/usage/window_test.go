@@ -0,0 +1,202 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/valentindosimont/ccmanager/internal/config"
+)
+
+func entryAt(at string, tokens int64) UsageEntry {
+	t, err := time.ParseInLocation("2006-01-02 15:04", at, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return UsageEntry{Time: t, Usage: TokenUsage{InputTokens: tokens}, Cost: float64(tokens) / 1000}
+}
+
+func utcTime(at string) time.Time {
+	return entryAt(at, 0).Time
+}
+
+func TestBlocks(t *testing.T) {
+	blocks := Blocks([]UsageEntry{
+		entryAt("2026-06-01 09:20", 100),
+		entryAt("2026-06-01 13:59", 100), // still in the 09:00 block
+		entryAt("2026-06-01 14:00", 100), // starts a block at 14:00
+		entryAt("2026-06-01 23:45", 100), // after a gap, starts at 23:00
+	})
+
+	want := []struct {
+		start  string
+		tokens int64
+	}{
+		{"2026-06-01 09:00", 200},
+		{"2026-06-01 14:00", 100},
+		{"2026-06-01 23:00", 100},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(blocks), len(want))
+	}
+	for i, w := range want {
+		if !blocks[i].Start.Equal(utcTime(w.start)) || blocks[i].Tokens() != w.tokens {
+			t.Errorf("block %d = %s with %d tokens, want %s with %d",
+				i, blocks[i].Start.Format("15:04"), blocks[i].Tokens(), w.start, w.tokens)
+		}
+		if blocks[i].End.Sub(blocks[i].Start) != BlockDuration {
+			t.Errorf("block %d lasts %v", i, blocks[i].End.Sub(blocks[i].Start))
+		}
+	}
+}
+
+func TestNewWindowStatus(t *testing.T) {
+	history := []UsageEntry{
+		entryAt("2026-06-01 09:00", 1000), // the largest past block
+		entryAt("2026-06-02 09:00", 400),
+		entryAt("2026-06-03 10:10", 300), // the block in progress
+		entryAt("2026-06-03 11:00", 300),
+	}
+	now := utcTime("2026-06-03 11:00")
+
+	tests := []struct {
+		name         string
+		limits       PlanLimits
+		wantPercent  float64
+		wantMax      bool
+		wantExhausts string
+	}{
+		{
+			name:        "largest past block",
+			wantPercent: 60,
+			wantMax:     true,
+			// 600 tokens in 60 minutes, so the last 400 take 40 more
+			wantExhausts: "2026-06-03 11:40",
+		},
+		{
+			name:        "configured limit that lasts the block",
+			limits:      PlanLimits{BlockTokens: 10_000},
+			wantPercent: 6,
+		},
+		{
+			name:         "configured limit already used",
+			limits:       PlanLimits{BlockTokens: 500},
+			wantPercent:  120,
+			wantExhausts: "2026-06-03 11:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWindowStatus(history, tt.limits, now)
+			if s.Block == nil || !s.Block.End.Equal(utcTime("2026-06-03 15:00")) {
+				t.Fatalf("Block = %+v, want the 10:00 block", s.Block)
+			}
+			if s.BlockPercent() != tt.wantPercent || s.BlockMax != tt.wantMax {
+				t.Errorf("BlockPercent() = %v (max %v), want %v (max %v)", s.BlockPercent(), s.BlockMax, tt.wantPercent, tt.wantMax)
+			}
+			if s.BurnRate != 10 {
+				t.Errorf("BurnRate = %v, want 10", s.BurnRate)
+			}
+			var want time.Time
+			if tt.wantExhausts != "" {
+				want = utcTime(tt.wantExhausts)
+			}
+			if !s.Exhausts.Equal(want) {
+				t.Errorf("Exhausts = %v, want %v", s.Exhausts, want)
+			}
+		})
+	}
+
+	if s := NewWindowStatus(history, PlanLimits{}, utcTime("2026-06-03 15:00")); s.Block != nil {
+		t.Errorf("Block = %+v after it ended, want none", s.Block)
+	}
+}
+
+func TestWindowStatusWeek(t *testing.T) {
+	history := []UsageEntry{
+		entryAt("2026-05-29 12:00", 5000), // Friday, the week before
+		entryAt("2026-06-01 08:00", 1000), // Monday before the reset
+		entryAt("2026-06-01 10:00", 2000),
+		entryAt("2026-06-02 10:00", 2000),
+	}
+	now := utcTime("2026-06-02 10:00") // Tuesday
+
+	limits, err := NewPlanLimits(config.PlanConfig{WeeklyTokens: 10_000, WeeklyReset: "Mon 09:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewWindowStatus(history, limits, now)
+	if !s.WeekStart.Equal(utcTime("2026-06-01 09:00")) || !s.WeekEnd.Equal(utcTime("2026-06-08 09:00")) {
+		t.Errorf("week = %v to %v", s.WeekStart, s.WeekEnd)
+	}
+	if s.WeekUsage.Total() != 4000 || s.WeekPercent() != 40 {
+		t.Errorf("week usage = %d (%v%%), want 4000 (40%%)", s.WeekUsage.Total(), s.WeekPercent())
+	}
+	// 4000 tokens in 25 hours runs out the other 6000 in 37.5 more
+	if want := utcTime("2026-06-03 23:30"); !s.WeekExhausts.Equal(want) {
+		t.Errorf("WeekExhausts = %v, want %v", s.WeekExhausts, want)
+	}
+
+	rolling := NewWindowStatus(history, PlanLimits{}, now)
+	if rolling.WeekUsage.Total() != 10_000 || !rolling.WeekEnd.IsZero() {
+		t.Errorf("rolling week = %d tokens ending %v, want all 10000 and no end", rolling.WeekUsage.Total(), rolling.WeekEnd)
+	}
+}
+
+func TestNewPlanLimitsErrors(t *testing.T) {
+	tests := []config.PlanConfig{
+		{BlockTokens: -1},
+		{WeeklyReset: "monday"},
+		{WeeklyReset: "someday 09:00"},
+		{WeeklyReset: "mon 9am"},
+	}
+	for _, cfg := range tests {
+		t.Run(fmt.Sprintf("%+v", cfg), func(t *testing.T) {
+			if _, err := NewPlanLimits(cfg); err == nil {
+				t.Error("NewPlanLimits() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestIngesterWindowStatus(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := filepath.Join(home, ".claude", "projects", "-repo")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	line := func(at time.Time, input int64) string {
+		return fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4-20250514","usage":{"input_tokens":%d}}}`+"\n",
+			at.UTC().Format(time.RFC3339Nano), input)
+	}
+	appendFile(t, filepath.Join(project, "old.jsonl"), line(now.Add(-2*windowHistory), 999))
+	appendFile(t, filepath.Join(project, "a.jsonl"), line(now.Add(-48*time.Hour), 500)+line(now.Add(-time.Minute), 100))
+	appendFile(t, filepath.Join(project, "b.jsonl"), line(now.Add(-30*time.Second), 200))
+
+	sources := map[string]*Ingester{
+		"parse":  NewIngester(nil),
+		"ingest": newTestIngester(t),
+	}
+	for name, ing := range sources {
+		t.Run(name, func(t *testing.T) {
+			s, err := ing.WindowStatus(PlanLimits{}, now)
+			if err != nil {
+				t.Fatalf("WindowStatus: %v", err)
+			}
+			if s.Block == nil || s.Block.Tokens() != 300 {
+				t.Fatalf("Block = %+v, want 300 tokens", s.Block)
+			}
+			if s.BlockLimit != 500 || !s.BlockMax {
+				t.Errorf("BlockLimit = %d (max %v), want the 500 token block", s.BlockLimit, s.BlockMax)
+			}
+			if s.WeekUsage.Total() != 800 {
+				t.Errorf("week = %d tokens, want 800", s.WeekUsage.Total())
+			}
+		})
+	}
+}